@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+)
+
+const (
+	purposeVerifyEmail   = "verify_email"
+	purposeResetPassword = "reset_password"
+
+	// VerificationTokenTTL is how long an email verification link stays valid
+	VerificationTokenTTL = 24 * time.Hour
+	// ResetTokenTTL is how long a password reset link stays valid
+	ResetTokenTTL = time.Hour
+	// MinPasswordLength is the shortest password accepted
+	MinPasswordLength = 6
+)
+
+var (
+	ErrInvalidActionToken   = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	ErrIncorrectPassword    = errors.New("current password is incorrect")
+	ErrWeakPassword         = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+)
+
+// RequestEmailVerification mails a fresh verification link, invalidating any
+// earlier one
+func (s *Service) RequestEmailVerification(userID string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendVerificationEmail(user)
+}
+
+// VerifyEmail marks the address of the token's user as verified
+func (s *Service) VerifyEmail(token string) (*User, error) {
+	actionToken, err := s.repo.ConsumeActionToken(purposeVerifyEmail, hashToken(token))
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	user, err := s.repo.GetUserByID(actionToken.UserID)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	if !user.EmailVerified {
+		now := time.Now()
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
+		if err := s.repo.UpdateUser(user); err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+
+		s.eventBus.Publish("user.email_verified", map[string]any{
+			"user_id": user.ID,
+			"email":   user.Email,
+		})
+		log.Printf("📧 Email verified: %s", user.Email)
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// RequestPasswordReset mails a reset link if the address belongs to an
+// active user. It reports success either way so callers cannot probe which
+// addresses are registered.
+func (s *Service) RequestPasswordReset(email string) error {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil || !user.IsActive {
+		return nil
+	}
+
+	token, err := s.issueActionToken(user, purposeResetPassword, ResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.appURL, url.QueryEscape(token))
+	s.sendMail(user, "Reset your password", fmt.Sprintf(
+		"Hi %s,\n\nUse the link below to choose a new password. It expires in %s and works once.\n\n%s\n\nIf you did not ask for this, you can ignore this email.",
+		user.Username, ResetTokenTTL, link))
+
+	s.eventBus.Publish("user.password_reset_requested", map[string]any{
+		"user_id": user.ID,
+		"email":   user.Email,
+	})
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and signs the user
+// out everywhere
+func (s *Service) ResetPassword(token, newPassword string) error {
+	if len(newPassword) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+
+	actionToken, err := s.repo.ConsumeActionToken(purposeResetPassword, hashToken(token))
+	if err != nil {
+		return ErrInvalidActionToken
+	}
+
+	user, err := s.repo.GetUserByID(actionToken.UserID)
+	if err != nil {
+		return ErrInvalidActionToken
+	}
+
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+	if err := s.repo.InvalidateUserSessions(user.ID); err != nil {
+		log.Printf("Warning: Failed to revoke sessions for user %s: %v", user.ID, err)
+	}
+	s.clearLoginFailures(user.Email)
+
+	s.eventBus.Publish("user.password_reset", map[string]any{
+		"user_id": user.ID,
+		"email":   user.Email,
+	})
+	s.sendMail(user, "Your password was reset", fmt.Sprintf(
+		"Hi %s,\n\nYour password was reset and all devices were signed out.", user.Username))
+
+	return nil
+}
+
+// ChangePassword replaces the password after confirming the current one.
+// Other devices are signed out; the session family of currentSessionID stays.
+func (s *Service) ChangePassword(userID, currentSessionID, currentPassword, newPassword string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if !user.VerifyPassword(currentPassword) {
+		return ErrIncorrectPassword
+	}
+	if len(newPassword) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	keepFamily := ""
+	if session, err := s.repo.GetSession(currentSessionID); err == nil && session.UserID == userID {
+		keepFamily = session.FamilyID
+	}
+	if err := s.repo.InvalidateOtherSessions(userID, keepFamily); err != nil {
+		log.Printf("Warning: Failed to revoke sessions for user %s: %v", userID, err)
+	}
+
+	s.eventBus.Publish("user.password_changed", map[string]any{
+		"user_id": user.ID,
+		"email":   user.Email,
+	})
+	s.sendMail(user, "Your password was changed", fmt.Sprintf(
+		"Hi %s,\n\nYour password was changed and your other devices were signed out. If this wasn't you, reset your password now.", user.Username))
+
+	return nil
+}
+
+func (s *Service) sendVerificationEmail(user *User) error {
+	token, err := s.issueActionToken(user, purposeVerifyEmail, VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/auth/verify-email?token=%s", s.appURL, url.QueryEscape(token))
+	s.sendMail(user, "Confirm your email address", fmt.Sprintf(
+		"Hi %s,\n\nConfirm your email address by opening the link below. It expires in %s.\n\n%s",
+		user.Username, VerificationTokenTTL, link))
+
+	s.eventBus.Publish("user.email_verification_requested", map[string]any{
+		"user_id": user.ID,
+		"email":   user.Email,
+	})
+	return nil
+}
+
+// issueActionToken stores a new single-use token for the user and returns
+// the plaintext to mail out
+func (s *Service) issueActionToken(user *User, purpose string, ttl time.Duration) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repo.SaveActionToken(&ActionToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *Service) setPassword(user *User, password string) error {
+	if err := user.HashPassword(password); err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.repo.UpdateUser(user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// VerifyEmail confirms an address from the mailed link (GET ?token=) or a
+// JSON body with the token
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	req := VerifyEmailRequest{Token: r.URL.Query().Get("token")}
+	if req.Token == "" && r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.VerifyEmail(req.Token)
+	if err != nil {
+		http.Error(w, err.Error(), accountErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    user,
+		"message": "Email verified",
+	})
+}
+
+// ResendVerification mails a new verification link to the signed-in user
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.RequestEmailVerification(userID); err != nil {
+		http.Error(w, err.Error(), accountErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Verification email sent",
+	})
+}
+
+// ForgotPassword mails a reset link. The response is the same whether or not
+// the address is registered.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(req.Email); err != nil {
+		log.Printf("Password reset request failed: %v", err)
+		http.Error(w, "Failed to request password reset", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "If the address is registered, a reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password using a mailed reset token
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		http.Error(w, "Token and password are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ResetPassword(req.Token, req.Password); err != nil {
+		http.Error(w, err.Error(), accountErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Password reset; sign in with your new password",
+	})
+}
+
+// UnlockAccount lifts a login lockout from the mailed link (GET ?token=) or
+// a JSON body with the token
+func (h *Handler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	req := UnlockRequest{Token: r.URL.Query().Get("token")}
+	if req.Token == "" && r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UnlockAccount(req.Token); err != nil {
+		http.Error(w, err.Error(), accountErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Account unlocked; you can sign in again",
+	})
+}
+
+// ChangePassword replaces the signed-in user's password after checking the
+// current one
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Current and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	sessionID := ""
+	if claims := GetClaims(r); claims != nil {
+		sessionID = claims.SessionID
+	}
+
+	if err := h.service.ChangePassword(userID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		http.Error(w, err.Error(), accountErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Password changed",
+	})
+}
+
+func accountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidActionToken), errors.Is(err, ErrWeakPassword):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrIncorrectPassword):
+		return http.StatusForbidden
+	case errors.Is(err, ErrEmailAlreadyVerified):
+		return http.StatusConflict
+	default:
+		log.Printf("Account request failed: %v", err)
+		return http.StatusInternalServerError
+	}
+}
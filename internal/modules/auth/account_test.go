@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// outbox keeps sent mail so tests can follow the links in it
+type outbox struct {
+	bodies []string
+}
+
+func (o *outbox) Send(to, subject, body string) error {
+	o.bodies = append(o.bodies, body)
+	return nil
+}
+
+// resetToken requests a reset for email and returns the token from the mailed link
+func resetToken(t *testing.T, s *Service, mail *outbox, email string) string {
+	t.Helper()
+	if err := s.RequestPasswordReset(email); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	return linkToken(t, mail.bodies[len(mail.bodies)-1])
+}
+
+// linkToken returns the token query parameter of the link in a mail body
+func linkToken(t *testing.T, body string) string {
+	t.Helper()
+	_, rest, found := strings.Cut(body, "token=")
+	if !found {
+		t.Fatalf("no link in %q", body)
+	}
+	token, err := url.QueryUnescape(strings.Fields(rest)[0])
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	return token
+}
+
+func TestResetPasswordTokenIsSingleUse(t *testing.T) {
+	s, _ := newTestService(t)
+	mail := &outbox{}
+	s.SetMailSender(mail, "https://app.test")
+	login := registerUser(t, s, "ann@example.com")
+
+	token := resetToken(t, s, mail, "ann@example.com")
+	if err := s.ResetPassword(token, "new password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if err := s.ResetPassword(token, "another password"); !errors.Is(err, ErrInvalidActionToken) {
+		t.Errorf("second use err = %v, want ErrInvalidActionToken", err)
+	}
+
+	if _, err := s.Login(LoginRequest{Email: "ann@example.com", Password: "new password"}, testDevice); err != nil {
+		t.Errorf("login with the new password: %v", err)
+	}
+	if _, err := s.Login(LoginRequest{Email: "ann@example.com", Password: "correct horse"}, testDevice); err == nil {
+		t.Error("old password still works")
+	}
+	if _, err := s.RefreshToken(login.RefreshToken, testDevice); err == nil {
+		t.Error("session from before the reset still refreshes")
+	}
+}
+
+func TestResetPasswordOnlyLatestLinkWorks(t *testing.T) {
+	s, _ := newTestService(t)
+	mail := &outbox{}
+	s.SetMailSender(mail, "https://app.test")
+	registerUser(t, s, "ann@example.com")
+
+	first := resetToken(t, s, mail, "ann@example.com")
+	second := resetToken(t, s, mail, "ann@example.com")
+	if err := s.ResetPassword(first, "new password"); !errors.Is(err, ErrInvalidActionToken) {
+		t.Errorf("superseded link err = %v, want ErrInvalidActionToken", err)
+	}
+	if err := s.ResetPassword(second, "new password"); err != nil {
+		t.Errorf("latest link: %v", err)
+	}
+}
+
+func TestResetPasswordRejectsExpiredToken(t *testing.T) {
+	s, _ := newTestService(t)
+	login := registerUser(t, s, "ann@example.com")
+
+	token, err := s.issueActionToken(&login.User, purposeResetPassword, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("issueActionToken: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if err := s.ResetPassword(token, "new password"); !errors.Is(err, ErrInvalidActionToken) {
+		t.Errorf("expired token err = %v, want ErrInvalidActionToken", err)
+	}
+	if _, err := s.Login(LoginRequest{Email: "ann@example.com", Password: "correct horse"}, testDevice); err != nil {
+		t.Errorf("password changed by an expired token: %v", err)
+	}
+}
+
+func TestResetPasswordTokenIsPurposeBound(t *testing.T) {
+	s, _ := newTestService(t)
+	login := registerUser(t, s, "ann@example.com")
+
+	token, err := s.issueActionToken(&login.User, purposeVerifyEmail, VerificationTokenTTL)
+	if err != nil {
+		t.Fatalf("issueActionToken: %v", err)
+	}
+	if err := s.ResetPassword(token, "new password"); !errors.Is(err, ErrInvalidActionToken) {
+		t.Errorf("verification token err = %v, want ErrInvalidActionToken", err)
+	}
+}
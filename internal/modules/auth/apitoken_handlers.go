@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// ListAPITokens lists the signed-in user's personal access tokens
+func (h *Handler) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.service.ListAPITokens(userID)
+	if err != nil {
+		log.Printf("Listing API tokens failed: %v", err)
+		http.Error(w, "Failed to list API tokens", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    tokens,
+	})
+}
+
+// CreateAPIToken issues a personal access token. The plaintext token is in
+// this response only.
+func (h *Handler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.service.CreateAPIToken(userID, req)
+	if err != nil {
+		http.Error(w, err.Error(), apiTokenErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    response,
+		"message": "Copy the token now; it will not be shown again",
+	})
+}
+
+// RevokeAPIToken deletes the token given by ?id=
+func (h *Handler) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokenID := r.URL.Query().Get("id")
+	if tokenID == "" {
+		http.Error(w, "Token ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeAPIToken(userID, tokenID); err != nil {
+		http.Error(w, err.Error(), apiTokenErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "API token revoked",
+	})
+}
+
+func apiTokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidAPITokenRequest), errors.Is(err, ErrTooManyAPITokens):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrAPITokenNotFound):
+		return http.StatusNotFound
+	default:
+		log.Printf("API token request failed: %v", err)
+		return http.StatusInternalServerError
+	}
+}
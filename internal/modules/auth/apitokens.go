@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// APITokenPrefix marks personal access tokens so the middleware can tell
+	// them from JWTs
+	APITokenPrefix = "pat_"
+	// apiTokenTouchInterval limits last-used writes to one per token per minute
+	apiTokenTouchInterval = time.Minute
+	maxAPITokensPerUser   = 50
+)
+
+// APIResources are the API modules a token can be scoped to. Each takes a
+// ":read" or ":write" scope; write implies read.
+var APIResources = []string{"time", "expense", "client", "invoice"}
+
+var (
+	ErrInvalidAPIToken        = errors.New("invalid or expired API token")
+	ErrInvalidAPITokenRequest = errors.New("invalid API token request")
+	ErrAPITokenNotFound       = errors.New("API token not found")
+	ErrTooManyAPITokens       = fmt.Errorf("at most %d API tokens per user", maxAPITokensPerUser)
+)
+
+// CreateAPIToken issues a personal access token. expiresInDays of 0 means the
+// token does not expire.
+func (s *Service) CreateAPIToken(userID string, req CreateAPITokenRequest) (*CreateAPITokenResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidAPITokenRequest)
+	}
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPITokenRequest)
+	}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPITokenRequest, scope)
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		return nil, fmt.Errorf("%w: expires_in_days must not be negative", ErrInvalidAPITokenRequest)
+	}
+
+	existing, err := s.repo.GetUserAPITokens(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API tokens: %w", err)
+	}
+	if len(existing) >= maxAPITokensPerUser {
+		return nil, ErrTooManyAPITokens
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	plaintext := APITokenPrefix + secret
+
+	token := &APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plaintext[:len(APITokenPrefix)+6],
+		TokenHash: hashToken(plaintext),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateAPIToken(token); err != nil {
+		return nil, fmt.Errorf("failed to create API token: %w", err)
+	}
+
+	s.eventBus.Publish("user.api_token_created", map[string]any{
+		"user_id":  userID,
+		"token_id": token.ID,
+		"name":     token.Name,
+		"scopes":   token.Scopes,
+	})
+	log.Printf("🔑 API token created for user %s: %s", userID, token.Name)
+
+	token.TokenHash = ""
+	return &CreateAPITokenResponse{Token: plaintext, APIToken: token}, nil
+}
+
+// ListAPITokens returns the user's unexpired tokens, newest first
+func (s *Service) ListAPITokens(userID string) ([]*APIToken, error) {
+	tokens, err := s.repo.GetUserAPITokens(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API tokens: %w", err)
+	}
+
+	now := time.Now()
+	live := []*APIToken{}
+	for _, token := range tokens {
+		if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+			continue
+		}
+		token.TokenHash = ""
+		live = append(live, token)
+	}
+
+	slices.SortFunc(live, func(a, b *APIToken) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return live, nil
+}
+
+// RevokeAPIToken deletes one of the user's tokens
+func (s *Service) RevokeAPIToken(userID, tokenID string) error {
+	if err := s.repo.DeleteAPIToken(userID, tokenID); err != nil {
+		return ErrAPITokenNotFound
+	}
+
+	s.eventBus.Publish("user.api_token_revoked", map[string]any{
+		"user_id":  userID,
+		"token_id": tokenID,
+	})
+	return nil
+}
+
+// AuthenticateAPIToken resolves a personal access token to its record and
+// active user, recording the use
+func (s *Service) AuthenticateAPIToken(plaintext string) (*APIToken, *User, error) {
+	token, err := s.repo.GetAPITokenByHash(hashToken(plaintext))
+	if err != nil {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	user, err := s.GetUserByID(token.UserID)
+	if err != nil || !user.IsActive {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.repo.TouchAPIToken(token.ID, now); err != nil {
+			log.Printf("Warning: Failed to record API token use: %v", err)
+		}
+		token.LastUsedAt = &now
+	}
+
+	return token, user, nil
+}
+
+// HasScope reports whether the token grants scope; write implies read
+func (t *APIToken) HasScope(scope string) bool {
+	if slices.Contains(t.Scopes, scope) {
+		return true
+	}
+	resource, action, _ := strings.Cut(scope, ":")
+	return action == "read" && slices.Contains(t.Scopes, resource+":write")
+}
+
+// requiredScope maps an API request to the scope it needs: the module from
+// /api/<module>/..., read for safe methods and write otherwise. /api/project/
+// belongs to the client module. It returns "" for paths no scope covers.
+func requiredScope(r *http.Request) string {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/api/")
+	if !ok {
+		return ""
+	}
+	resource, _, _ := strings.Cut(rest, "/")
+	if resource == "project" {
+		resource = "client"
+	}
+	if !slices.Contains(APIResources, resource) {
+		return ""
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return resource + ":read"
+	default:
+		return resource + ":write"
+	}
+}
+
+func validScope(scope string) bool {
+	resource, action, ok := strings.Cut(scope, ":")
+	return ok && slices.Contains(APIResources, resource) && (action == "read" || action == "write")
+}
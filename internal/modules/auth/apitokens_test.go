@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHasScope(t *testing.T) {
+	token := &APIToken{Scopes: []string{"time:write", "invoice:read"}}
+	cases := []struct {
+		scope string
+		want  bool
+	}{
+		{"time:write", true},
+		{"time:read", true},
+		{"invoice:read", true},
+		{"invoice:write", false},
+		{"expense:read", false},
+		{"client:write", false},
+		{"time", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := token.HasScope(tc.scope); got != tc.want {
+			t.Errorf("HasScope(%q) = %v, want %v", tc.scope, got, tc.want)
+		}
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/api/time/current", "time:read"},
+		{http.MethodHead, "/api/expense/list", "expense:read"},
+		{http.MethodOptions, "/api/invoice/list", "invoice:read"},
+		{http.MethodPost, "/api/time/start", "time:write"},
+		{http.MethodPut, "/api/client/update", "client:write"},
+		{http.MethodDelete, "/api/invoice/delete", "invoice:write"},
+		{http.MethodGet, "/api/project/list", "client:read"},
+		{http.MethodPost, "/api/project/milestone/create", "client:write"},
+		{http.MethodGet, "/api/auth/tokens", ""},
+		{http.MethodGet, "/api/workspaces/members", ""},
+		{http.MethodGet, "/api/timeline", ""},
+		{http.MethodGet, "/dashboard", ""},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		if got := requiredScope(r); got != tc.want {
+			t.Errorf("%s %s: scope = %q, want %q", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestRequireAuthEnforcesAPITokenScopes(t *testing.T) {
+	s, _ := newTestService(t)
+	login := registerUser(t, s, "ann@example.com")
+	created, err := s.CreateAPIToken(login.User.ID, CreateAPITokenRequest{Name: "script", Scopes: []string{"time:read"}})
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+
+	handler := NewMiddleware(s).RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	cases := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/api/time/current", http.StatusNoContent},
+		{http.MethodPost, "/api/time/start", http.StatusForbidden},
+		{http.MethodGet, "/api/expense/list", http.StatusForbidden},
+		{http.MethodGet, "/api/auth/tokens", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		r.Header.Set("Authorization", "Bearer "+created.Token)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%s %s: status = %d, want %d", tc.method, tc.path, w.Code, tc.want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/time/current", nil)
+	r.Header.Set("Authorization", "Bearer "+APITokenPrefix+"unknown")
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token: status = %d, want 401", w.Code)
+	}
+}
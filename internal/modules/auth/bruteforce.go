@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	purposeUnlockAccount = "unlock_account"
+
+	// accountFreeAttempts and ipFreeAttempts are the failures allowed before
+	// lockouts start. An IP gets more since it may be shared by many users.
+	accountFreeAttempts = 5
+	ipFreeAttempts      = 20
+	// baseLockout doubles with every failure past the free attempts, up to
+	// maxLockout
+	baseLockout = 30 * time.Second
+	maxLockout  = time.Hour
+	// attemptWindow is how long counters survive without a new failure
+	attemptWindow = 24 * time.Hour
+	// UnlockTokenTTL is how long an emailed unlock link stays valid
+	UnlockTokenTTL = time.Hour
+)
+
+var ErrLoginLocked = errors.New("too many failed login attempts")
+
+// LockedError carries when a locked login may be retried
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%v; retry in %s", ErrLoginLocked, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// checkLoginAllowed fails with a LockedError while the account or the IP is
+// locked out
+func (s *Service) checkLoginAllowed(email, ip string) error {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range attemptKeys(email, ip) {
+		attempts, err := s.repo.GetLoginAttempts(key)
+		if err != nil {
+			return fmt.Errorf("failed to check login attempts: %w", err)
+		}
+		if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+			wait = max(wait, attempts.LockedUntil.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return &LockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt against the account and the IP,
+// locking either once past its free attempts. The account owner is mailed an
+// unlock link when the account gets locked.
+func (s *Service) recordLoginFailure(email, ip string) {
+	now := time.Now()
+	for i, key := range attemptKeys(email, ip) {
+		free := accountFreeAttempts
+		if i == 1 {
+			free = ipFreeAttempts
+		}
+
+		wasLocked := false
+		attempts, err := s.repo.UpdateLoginAttempts(key, attemptWindow, func(a *LoginAttempts) {
+			wasLocked = a.LockedUntil != nil && a.LockedUntil.After(now)
+			a.Failures++
+			a.LastFailure = now
+			if a.Failures >= free {
+				until := now.Add(lockoutFor(a.Failures - free))
+				a.LockedUntil = &until
+			}
+		})
+		if err != nil {
+			log.Printf("Warning: Failed to record login failure: %v", err)
+			continue
+		}
+		if attempts.LockedUntil == nil || wasLocked {
+			continue
+		}
+
+		log.Printf("🚨 Login locked for %s until %s after %d failures", key, attempts.LockedUntil.Format(time.RFC3339), attempts.Failures)
+		s.eventBus.Publish("auth.login.locked", map[string]any{
+			"key":          key,
+			"email":        email,
+			"ip_address":   ip,
+			"failures":     attempts.Failures,
+			"locked_until": attempts.LockedUntil,
+		})
+		if i == 0 && attempts.Failures == free {
+			s.sendUnlockEmail(email)
+		}
+	}
+}
+
+// clearLoginFailures resets the account counter after a successful login.
+// The IP counter is left to expire so one good login cannot reset it.
+func (s *Service) clearLoginFailures(email string) {
+	if err := s.repo.ClearLoginAttempts(accountAttemptKey(email)); err != nil {
+		log.Printf("Warning: Failed to clear login attempts: %v", err)
+	}
+}
+
+// UnlockAccount clears an account lockout using the emailed unlock token
+func (s *Service) UnlockAccount(token string) error {
+	actionToken, err := s.repo.ConsumeActionToken(purposeUnlockAccount, hashToken(token))
+	if err != nil {
+		return ErrInvalidActionToken
+	}
+
+	user, err := s.repo.GetUserByID(actionToken.UserID)
+	if err != nil {
+		return ErrInvalidActionToken
+	}
+
+	s.clearLoginFailures(user.Email)
+	s.eventBus.Publish("auth.login.unlocked", map[string]any{
+		"user_id": user.ID,
+		"email":   user.Email,
+	})
+	return nil
+}
+
+func (s *Service) sendUnlockEmail(email string) {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		return
+	}
+
+	token, err := s.issueActionToken(user, purposeUnlockAccount, UnlockTokenTTL)
+	if err != nil {
+		log.Printf("Warning: Failed to issue unlock token: %v", err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/api/auth/unlock?token=%s", s.appURL, url.QueryEscape(token))
+	s.sendMail(user, "Sign-in attempts blocked", fmt.Sprintf(
+		"Hi %s,\n\nWe blocked sign-ins to your account after several failed attempts. If that was you, unlock it with the link below; otherwise consider changing your password.\n\n%s",
+		user.Username, link))
+}
+
+// lockoutFor is the lockout after the given number of failures past the
+// free attempts: baseLockout, doubling each time, capped at maxLockout
+func lockoutFor(extraFailures int) time.Duration {
+	lockout := baseLockout
+	for range extraFailures {
+		lockout *= 2
+		if lockout >= maxLockout {
+			return maxLockout
+		}
+	}
+	return lockout
+}
+
+// attemptKeys returns the account and IP counter keys
+func attemptKeys(email, ip string) []string {
+	return []string{accountAttemptKey(email), "ip:" + ip}
+}
+
+func accountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLockoutForBackoff(t *testing.T) {
+	cases := []struct {
+		extra int
+		want  time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{5, 16 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{8, time.Hour},
+		{1000, time.Hour},
+	}
+	for _, tc := range cases {
+		if got := lockoutFor(tc.extra); got != tc.want {
+			t.Errorf("lockoutFor(%d) = %s, want %s", tc.extra, got, tc.want)
+		}
+	}
+}
+
+func TestLoginLocksAccountAfterFreeAttempts(t *testing.T) {
+	s, events := newTestService(t)
+	mail := &outbox{}
+	s.SetMailSender(mail, "https://app.test")
+	registerUser(t, s, "ann@example.com")
+	sent := len(mail.bodies)
+
+	wrong := LoginRequest{Email: "ann@example.com", Password: "wrong guess"}
+	for i := 1; i < accountFreeAttempts; i++ {
+		if _, err := s.Login(wrong, testDevice); err == nil || errors.Is(err, ErrLoginLocked) {
+			t.Fatalf("attempt %d: err = %v, want invalid credentials", i, err)
+		}
+	}
+	if _, err := s.Login(wrong, testDevice); err == nil {
+		t.Fatal("last free attempt succeeded")
+	}
+
+	// Even the right password waits out the lockout
+	_, err := s.Login(LoginRequest{Email: "ANN@example.com", Password: "correct horse"}, testDevice)
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("err = %v, want a LockedError", err)
+	}
+	if locked.RetryAfter <= 0 || locked.RetryAfter > baseLockout {
+		t.Errorf("retry after %s, want at most %s", locked.RetryAfter, baseLockout)
+	}
+	if len(events.Published("auth.login.locked")) != 1 {
+		t.Error("lockout was not published once")
+	}
+	if len(mail.bodies) != sent+1 {
+		t.Fatalf("sent %d unlock emails, want 1", len(mail.bodies)-sent)
+	}
+
+	if err := s.UnlockAccount(linkToken(t, mail.bodies[len(mail.bodies)-1])); err != nil {
+		t.Fatalf("UnlockAccount: %v", err)
+	}
+	if _, err := s.Login(LoginRequest{Email: "ann@example.com", Password: "correct horse"}, testDevice); err != nil {
+		t.Errorf("login after unlock: %v", err)
+	}
+}
+
+func TestRecordLoginFailureDoublesLockout(t *testing.T) {
+	s, _ := newTestService(t)
+	for range accountFreeAttempts + 2 {
+		s.recordLoginFailure("ann@example.com", "192.0.2.9")
+	}
+
+	attempts, err := s.repo.GetLoginAttempts(accountAttemptKey("ann@example.com"))
+	if err != nil {
+		t.Fatalf("GetLoginAttempts: %v", err)
+	}
+	if attempts.LockedUntil == nil {
+		t.Fatal("account not locked")
+	}
+	remaining := time.Until(*attempts.LockedUntil)
+	if want := lockoutFor(2); remaining > want || remaining < want-time.Minute {
+		t.Errorf("locked for %s, want about %s", remaining.Round(time.Second), want)
+	}
+}
+
+func TestLoginLocksIPAcrossAccounts(t *testing.T) {
+	s, _ := newTestService(t)
+	registerUser(t, s, "ann@example.com")
+
+	// Spread the guesses so no single account reaches its own limit
+	for i := range ipFreeAttempts {
+		s.recordLoginFailure(fmt.Sprintf("user%d@example.com", i), testDevice.IPAddress)
+	}
+
+	_, err := s.Login(LoginRequest{Email: "ann@example.com", Password: "correct horse"}, testDevice)
+	if !errors.Is(err, ErrLoginLocked) {
+		t.Errorf("login from the locked IP err = %v, want ErrLoginLocked", err)
+	}
+	other := Device{UserAgent: "test", IPAddress: "198.51.100.7"}
+	if _, err := s.Login(LoginRequest{Email: "ann@example.com", Password: "correct horse"}, other); err != nil {
+		t.Errorf("login from another IP: %v", err)
+	}
+}
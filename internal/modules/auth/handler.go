@@ -2,8 +2,12 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -27,12 +31,12 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Password) < 6 {
-		http.Error(w, "Password must be at least 6 characters", http.StatusBadRequest)
+	if len(req.Password) < MinPasswordLength {
+		http.Error(w, ErrWeakPassword.Error(), http.StatusBadRequest)
 		return
 	}
 
-	response, err := h.service.Register(req)
+	response, err := h.service.Register(req, deviceFromRequest(r))
 	if err != nil {
 		log.Printf("Registration failed: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -58,23 +62,28 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.service.Login(req)
+	response, err := h.service.Login(req, deviceFromRequest(r))
 	if err != nil {
 		log.Printf("Login failed: %v", err)
+		if writeLocked(w, err) {
+			return
+		}
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	// Set HTTP-only cookie for web browsers
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
-		Value:    response.Token,
-		Path:     "/",
-		MaxAge:   86400, // 24 hours
-		HttpOnly: true,
-		Secure:   false, // Set to true in production with HTTPS
-		SameSite: http.SameSiteStrictMode,
-	})
+	// Accounts with 2FA get a challenge to complete at /api/auth/login/2fa
+	if response.Challenge != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"success": true,
+			"data":    response.Challenge,
+		})
+		return
+	}
+
+	// Set HTTP-only cookies for web browsers
+	setAuthCookies(w, response)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
@@ -84,29 +93,26 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
-	userID := r.Header.Get("X-User-ID")
+	userID := GetUserID(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	err := h.service.Logout(userID)
+	sessionID := ""
+	if claims := GetClaims(r); claims != nil {
+		sessionID = claims.SessionID
+	}
+
+	err := h.service.Logout(userID, sessionID)
 	if err != nil {
 		log.Printf("Logout failed: %v", err)
 		http.Error(w, "Failed to logout", http.StatusInternalServerError)
 		return
 	}
 
-	// Clear auth cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteStrictMode,
-	})
+	// Clear auth cookies
+	clearAuthCookies(w)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
@@ -137,7 +143,7 @@ func (h *Handler) VerifyToken(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
-	userID := r.Header.Get("X-User-ID")
+	userID := GetUserID(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -156,25 +162,157 @@ func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// RefreshToken rotates the refresh token from the JSON body, or from the
+// refresh cookie for browsers, into a new access and refresh token pair
 func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "Authorization header required", http.StatusUnauthorized)
+	var req RefreshRequest
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		if cookie, err := r.Cookie("refresh_token"); err == nil {
+			req.RefreshToken = cookie.Value
+		}
+	}
+	if req.RefreshToken == "" {
+		http.Error(w, "Refresh token required", http.StatusUnauthorized)
 		return
 	}
 
-	tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-	newToken, err := h.service.RefreshToken(tokenString)
+	response, err := h.service.RefreshToken(req.RefreshToken, deviceFromRequest(r))
 	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		log.Printf("Token refresh failed: %v", err)
+		clearAuthCookies(w)
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	setAuthCookies(w, response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    response,
+	})
+}
+
+// ListSessions lists the devices the user is signed in on
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	currentSessionID := ""
+	if claims := GetClaims(r); claims != nil {
+		currentSessionID = claims.SessionID
+	}
+
+	sessions, err := h.service.ListSessions(userID, currentSessionID)
+	if err != nil {
+		log.Printf("Listing sessions failed: %v", err)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
 		"success": true,
-		"data": map[string]string{
-			"token": newToken,
-		},
+		"data":    sessions,
 	})
 }
+
+// RevokeSession signs out the device given by ?id=
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	familyID := r.URL.Query().Get("id")
+	if familyID == "" {
+		http.Error(w, "Session ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeSession(userID, familyID); err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Revoking session failed: %v", err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Session revoked",
+	})
+}
+
+// deviceFromRequest describes the client a session is opened or refreshed from
+func deviceFromRequest(r *http.Request) Device {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return Device{
+		UserAgent: r.UserAgent(),
+		IPAddress: ip,
+	}
+}
+
+// writeLocked answers a locked-out login with 429 and Retry-After, reporting
+// whether err was a lockout
+func writeLocked(w http.ResponseWriter, err error) bool {
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	http.Error(w, locked.Error(), http.StatusTooManyRequests)
+	return true
+}
+
+// setAuthCookies stores the access and refresh tokens as HTTP-only cookies
+func setAuthCookies(w http.ResponseWriter, response *LoginResponse) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "auth_token",
+		Value:    response.Token,
+		Path:     "/",
+		MaxAge:   int(AccessTokenTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    response.RefreshToken,
+		Path:     "/",
+		MaxAge:   int(RefreshTokenTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func clearAuthCookies(w http.ResponseWriter) {
+	for _, name := range []string{"auth_token", "refresh_token", workspaceCookie} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   false,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+}
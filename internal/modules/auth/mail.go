@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"log"
+	"strings"
+)
+
+// MailSender delivers account emails such as verification and password reset
+// links. Plug in an SMTP or provider-backed sender with Service.SetMailSender.
+type MailSender interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailSender writes emails to the log instead of sending them. It is the
+// default so development setups work without a mail server.
+type LogMailSender struct{}
+
+func NewLogMailSender() *LogMailSender {
+	return &LogMailSender{}
+}
+
+func (m *LogMailSender) Send(to, subject, body string) error {
+	log.Printf("📧 Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// SetMailSender replaces the mail sender. appURL is the public base URL used
+// in links, e.g. https://app.example.com.
+func (s *Service) SetMailSender(sender MailSender, appURL string) {
+	s.mailer = sender
+	s.appURL = strings.TrimRight(appURL, "/")
+}
+
+// sendMail delivers an account email. Delivery failures are logged rather
+// than failing the request that triggered them.
+func (s *Service) sendMail(user *User, subject, body string) {
+	if err := s.mailer.Send(user.Email, subject, body); err != nil {
+		log.Printf("Warning: Failed to send %q to %s: %v", subject, user.Email, err)
+	}
+}
@@ -2,9 +2,12 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
+
+	"datastar-go/internal/shared/types"
 )
 
 type contextKey string
@@ -13,8 +16,15 @@ const (
 	UserIDKey   contextKey = "user_id"
 	UserKey     contextKey = "user"
 	ClaimsKey   contextKey = "claims"
+	APITokenKey contextKey = "api_token"
+	ActorKey    contextKey = "actor"
 )
 
+// WorkspaceHeader selects the workspace an API request acts on. Browsers use
+// the workspace cookie set by SwitchWorkspace instead. Without either the
+// user's personal workspace is used.
+const WorkspaceHeader = "X-Workspace-ID"
+
 type Middleware struct {
 	service *Service
 }
@@ -37,6 +47,11 @@ func (m *Middleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		if strings.HasPrefix(tokenString, APITokenPrefix) {
+			m.serveWithAPIToken(w, r, tokenString, next)
+			return
+		}
+
 		claims, err := m.service.VerifyToken(tokenString)
 		if err != nil {
 			log.Printf("Token verification failed: %v", err)
@@ -56,9 +71,16 @@ func (m *Middleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		actor, err := m.resolveActor(r, user.ID)
+		if err != nil {
+			http.Error(w, "Not a member of this workspace", http.StatusForbidden)
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, UserKey, user)
 		ctx = context.WithValue(ctx, ClaimsKey, claims)
+		ctx = context.WithValue(ctx, ActorKey, actor)
 
 		r.Header.Set("X-User-ID", claims.UserID)
 		r.Header.Set("X-Username", claims.Username)
@@ -81,6 +103,9 @@ func (m *Middleware) OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
 						ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 						ctx = context.WithValue(ctx, UserKey, user)
 						ctx = context.WithValue(ctx, ClaimsKey, claims)
+						if actor, err := m.resolveActor(r, user.ID); err == nil {
+							ctx = context.WithValue(ctx, ActorKey, actor)
+						}
 
 						r.Header.Set("X-User-ID", claims.UserID)
 						r.Header.Set("X-Username", claims.Username)
@@ -96,11 +121,74 @@ func (m *Middleware) OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// serveWithAPIToken authenticates a personal access token and checks that its
+// scopes cover the request
+func (m *Middleware) serveWithAPIToken(w http.ResponseWriter, r *http.Request, tokenString string, next http.HandlerFunc) {
+	token, user, err := m.service.AuthenticateAPIToken(tokenString)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+
+	scope := requiredScope(r)
+	if scope == "" || !token.HasScope(scope) {
+		http.Error(w, "Token lacks the required scope", http.StatusForbidden)
+		return
+	}
+
+	actor, err := m.resolveActor(r, user.ID)
+	if err != nil {
+		http.Error(w, "Not a member of this workspace", http.StatusForbidden)
+		return
+	}
+
+	claims := &AuthClaims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		TokenID:  token.ID,
+	}
+
+	ctx := context.WithValue(r.Context(), UserIDKey, user.ID)
+	ctx = context.WithValue(ctx, UserKey, user)
+	ctx = context.WithValue(ctx, ClaimsKey, claims)
+	ctx = context.WithValue(ctx, APITokenKey, token)
+	ctx = context.WithValue(ctx, ActorKey, actor)
+
+	r.Header.Set("X-User-ID", user.ID)
+	r.Header.Set("X-Username", user.Username)
+	r.Header.Set("X-Email", user.Email)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetUserID returns the authenticated user's ID from the request context, or
+// "" if the request did not pass through the auth middleware. Client-supplied
+// headers are never trusted.
 func GetUserID(r *http.Request) string {
 	if userID, ok := r.Context().Value(UserIDKey).(string); ok {
 		return userID
 	}
-	return r.Header.Get("X-User-ID")
+	return ""
+}
+
+// GetActor returns the authenticated user acting in the workspace the request
+// selected, with their role there. Without the auth middleware it is the zero
+// Actor, whose UserID is "".
+func GetActor(r *http.Request) types.Actor {
+	if actor, ok := r.Context().Value(ActorKey).(types.Actor); ok {
+		return actor
+	}
+	return types.Actor{}
+}
+
+// ErrorStatus returns 403 Forbidden for errors from workspace role checks and
+// status for any other error
+func ErrorStatus(err error, status int) int {
+	if errors.Is(err, types.ErrForbidden) {
+		return http.StatusForbidden
+	}
+	return status
 }
 
 func GetUser(r *http.Request) *User {
@@ -117,6 +205,15 @@ func GetClaims(r *http.Request) *AuthClaims {
 	return nil
 }
 
+// GetAPIToken returns the personal access token the request authenticated
+// with, or nil for JWT-authenticated requests
+func GetAPIToken(r *http.Request) *APIToken {
+	if token, ok := r.Context().Value(APITokenKey).(*APIToken); ok {
+		return token
+	}
+	return nil
+}
+
 // RequireWebAuth handles auth for web pages (supports both cookies and Authorization header)
 func (m *Middleware) RequireWebAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -133,32 +230,31 @@ func (m *Middleware) RequireWebAuth(next http.HandlerFunc) http.HandlerFunc {
 			}
 		}
 		
-		if tokenString == "" {
-			// Redirect to login for web requests, return 401 for API requests
-			if strings.HasPrefix(r.URL.Path, "/api/") {
-				http.Error(w, "Authentication required", http.StatusUnauthorized)
-			} else {
-				http.Redirect(w, r, "/login", http.StatusSeeOther)
+		var claims *AuthClaims
+		var err error
+		if tokenString != "" {
+			claims, err = m.service.VerifyToken(tokenString)
+			if err != nil {
+				log.Printf("Token verification failed: %v", err)
 			}
-			return
 		}
 
-		claims, err := m.service.VerifyToken(tokenString)
-		if err != nil {
-			log.Printf("Token verification failed: %v", err)
-			// Clear invalid cookie
-			http.SetCookie(w, &http.Cookie{
-				Name:     "auth_token",
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-				Secure:   false, // Set to true in production with HTTPS
-				SameSite: http.SameSiteStrictMode,
-			})
-			
+		// Browsers carry a refresh cookie; renew an expired access cookie from it
+		if claims == nil && authHeader == "" {
+			claims = m.refreshWebSession(w, r)
+		}
+
+		if claims == nil {
+			// Clear invalid cookies
+			clearAuthCookies(w)
+
+			// Redirect to login for web requests, return 401 for API requests
 			if strings.HasPrefix(r.URL.Path, "/api/") {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				if tokenString == "" {
+					http.Error(w, "Authentication required", http.StatusUnauthorized)
+				} else {
+					http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				}
 			} else {
 				http.Redirect(w, r, "/login", http.StatusSeeOther)
 			}
@@ -185,9 +281,16 @@ func (m *Middleware) RequireWebAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		actor, err := m.resolveActor(r, user.ID)
+		if err != nil {
+			http.Error(w, "Not a member of this workspace", http.StatusForbidden)
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, UserKey, user)
 		ctx = context.WithValue(ctx, ClaimsKey, claims)
+		ctx = context.WithValue(ctx, ActorKey, actor)
 
 		r.Header.Set("X-User-ID", claims.UserID)
 		r.Header.Set("X-Username", claims.Username)
@@ -195,4 +298,42 @@ func (m *Middleware) RequireWebAuth(next http.HandlerFunc) http.HandlerFunc {
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
+}
+
+// resolveActor finds the workspace the request acts on and the user's role
+// in it. A workspace named in the header must be one the user belongs to; a
+// stale workspace cookie, e.g. after being removed from the workspace, falls
+// back to the personal workspace.
+func (m *Middleware) resolveActor(r *http.Request, userID string) (types.Actor, error) {
+	if workspaceID := r.Header.Get(WorkspaceHeader); workspaceID != "" {
+		return m.service.ResolveActor(userID, workspaceID)
+	}
+	if cookie, err := r.Cookie(workspaceCookie); err == nil && cookie.Value != "" {
+		if actor, err := m.service.ResolveActor(userID, cookie.Value); err == nil {
+			return actor, nil
+		}
+	}
+	return types.PersonalActor(userID), nil
+}
+
+// refreshWebSession rotates the refresh cookie into a new token pair and
+// returns the new access token's claims, or nil if there is nothing to renew
+func (m *Middleware) refreshWebSession(w http.ResponseWriter, r *http.Request) *AuthClaims {
+	cookie, err := r.Cookie("refresh_token")
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+
+	response, err := m.service.RefreshToken(cookie.Value, deviceFromRequest(r))
+	if err != nil {
+		log.Printf("Session refresh failed: %v", err)
+		return nil
+	}
+	setAuthCookies(w, response)
+
+	claims, err := m.service.VerifyToken(response.Token)
+	if err != nil {
+		return nil
+	}
+	return claims
 }
\ No newline at end of file
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"datastar-go/internal/shared/types"
+)
+
+// teamFixture is a shared workspace owned by ann with bob as a member and
+// carol outside it
+type teamFixture struct {
+	s               *Service
+	ann, bob, carol *LoginResponse
+	workspaceID     string
+}
+
+func newTeamFixture(t *testing.T) *teamFixture {
+	t.Helper()
+	s, _ := newTestService(t)
+	f := &teamFixture{
+		s:     s,
+		ann:   registerUser(t, s, "ann@example.com"),
+		bob:   registerUser(t, s, "bob@example.com"),
+		carol: registerUser(t, s, "carol@example.com"),
+	}
+	workspace, err := s.CreateWorkspace(f.ann.User.ID, "Studio")
+	if err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	f.workspaceID = workspace.ID
+	if err := s.repo.SaveMembership(newMembership(workspace.ID, &f.bob.User, types.RoleMember)); err != nil {
+		t.Fatalf("SaveMembership: %v", err)
+	}
+	return f
+}
+
+func TestResolveActor(t *testing.T) {
+	f := newTeamFixture(t)
+	bob, carol := f.bob.User.ID, f.carol.User.ID
+
+	cases := []struct {
+		name        string
+		userID      string
+		workspaceID string
+		want        types.Actor
+		forbidden   bool
+	}{
+		{"no workspace", bob, "", types.PersonalActor(bob), false},
+		{"personal workspace", bob, bob, types.PersonalActor(bob), false},
+		{"member", bob, f.workspaceID, types.Actor{UserID: bob, WorkspaceID: f.workspaceID, Role: types.RoleMember}, false},
+		{"non-member", carol, f.workspaceID, types.Actor{}, true},
+		{"someone's personal workspace", carol, f.ann.User.ID, types.Actor{}, true},
+		{"unknown workspace", bob, "missing", types.Actor{}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actor, err := f.s.ResolveActor(tc.userID, tc.workspaceID)
+			if tc.forbidden {
+				if !errors.Is(err, types.ErrForbidden) {
+					t.Errorf("err = %v, want ErrForbidden", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveActor: %v", err)
+			}
+			if actor != tc.want {
+				t.Errorf("actor = %+v, want %+v", actor, tc.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareResolvesWorkspace(t *testing.T) {
+	f := newTeamFixture(t)
+	m := NewMiddleware(f.s)
+	bob := f.bob.User.ID
+	member := types.Actor{UserID: bob, WorkspaceID: f.workspaceID, Role: types.RoleMember}
+
+	middlewares := []struct {
+		name string
+		wrap func(http.HandlerFunc) http.HandlerFunc
+		// authenticate adds the login's access token the way the middleware reads it
+		authenticate func(*http.Request, *LoginResponse)
+	}{
+		{"RequireAuth", m.RequireAuth, func(r *http.Request, login *LoginResponse) {
+			r.Header.Set("Authorization", "Bearer "+login.Token)
+		}},
+		{"RequireWebAuth", m.RequireWebAuth, func(r *http.Request, login *LoginResponse) {
+			r.AddCookie(&http.Cookie{Name: "auth_token", Value: login.Token})
+		}},
+	}
+	cases := []struct {
+		name       string
+		login      *LoginResponse
+		header     string
+		cookie     string
+		wantStatus int
+		want       types.Actor
+	}{
+		{"no selection", f.bob, "", "", http.StatusOK, types.PersonalActor(bob)},
+		{"member header", f.bob, f.workspaceID, "", http.StatusOK, member},
+		{"member cookie", f.bob, "", f.workspaceID, http.StatusOK, member},
+		{"header beats cookie", f.bob, bob, f.workspaceID, http.StatusOK, types.PersonalActor(bob)},
+		{"non-member header", f.carol, f.workspaceID, "", http.StatusForbidden, types.Actor{}},
+		{"stale cookie", f.carol, "", f.workspaceID, http.StatusOK, types.PersonalActor(f.carol.User.ID)},
+	}
+
+	for _, mw := range middlewares {
+		for _, tc := range cases {
+			t.Run(mw.name+"/"+tc.name, func(t *testing.T) {
+				var got types.Actor
+				handler := mw.wrap(func(w http.ResponseWriter, r *http.Request) {
+					got = GetActor(r)
+				})
+
+				r := httptest.NewRequest(http.MethodGet, "/api/time/current", nil)
+				mw.authenticate(r, tc.login)
+				if tc.header != "" {
+					r.Header.Set(WorkspaceHeader, tc.header)
+				}
+				if tc.cookie != "" {
+					r.AddCookie(&http.Cookie{Name: workspaceCookie, Value: tc.cookie})
+				}
+				w := httptest.NewRecorder()
+				handler(w, r)
+
+				if w.Code != tc.wantStatus {
+					t.Fatalf("status = %d, want %d", w.Code, tc.wantStatus)
+				}
+				if got != tc.want {
+					t.Errorf("actor = %+v, want %+v", got, tc.want)
+				}
+			})
+		}
+	}
+}
+
+func TestMiddlewareFallsBackAfterRemoval(t *testing.T) {
+	f := newTeamFixture(t)
+	bob := f.bob.User.ID
+	if err := f.s.repo.DeleteMembership(f.workspaceID, bob); err != nil {
+		t.Fatalf("DeleteMembership: %v", err)
+	}
+
+	var got types.Actor
+	handler := NewMiddleware(f.s).RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		got = GetActor(r)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/api/time/current", nil)
+	r.Header.Set("Authorization", "Bearer "+f.bob.Token)
+	r.AddCookie(&http.Cookie{Name: workspaceCookie, Value: f.workspaceID})
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	if w.Code != http.StatusOK || got != types.PersonalActor(bob) {
+		t.Errorf("status %d actor %+v, want the personal workspace", w.Code, got)
+	}
+}
@@ -0,0 +1,632 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// OIDCLoginTTL is how long the round trip through an identity provider
+	// may take
+	OIDCLoginTTL = 10 * time.Minute
+	// oidcKeyRefreshInterval limits how often an unknown kid refetches a
+	// provider's keys
+	oidcKeyRefreshInterval = time.Minute
+	oidcMaxResponseBytes   = 1 << 20
+)
+
+var (
+	ErrUnknownOIDCProvider  = errors.New("unknown identity provider")
+	ErrInvalidOIDCLogin     = errors.New("invalid or expired sign-in")
+	ErrInvalidIDToken       = errors.New("invalid ID token")
+	ErrOIDCProvider         = errors.New("identity provider request failed")
+	ErrOIDCEmailNotVerified = errors.New("the identity provider did not confirm a verified email address")
+	ErrOIDCLinkRefused      = errors.New("an account with this email exists but has not verified it; sign in with your password and verify your email first")
+)
+
+// oidcSigningAlgorithms are the ID token algorithms accepted from providers.
+// HS256 is left out: it would make the client secret a verification key.
+var oidcSigningAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// OIDCProviderConfig registers an OpenID Connect identity provider. Its
+// endpoints and keys are discovered from Issuer.
+type OIDCProviderConfig struct {
+	Name     string
+	Issuer   string
+	ClientID string
+	// ClientSecret is empty for public clients, which rely on PKCE alone
+	ClientSecret string
+	Scopes       []string
+}
+
+type oidcProvider struct {
+	config OIDCProviderConfig
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]any
+	keysFetchedAt time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	Email             string `json:"email"`
+	EmailVerified     any    `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// emailVerified reads email_verified, which some providers send as a string
+func (c *idTokenClaims) emailVerified() bool {
+	switch verified := c.EmailVerified.(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
+
+// AddOIDCProvider enables sign-in with an OpenID Connect provider. Discovery
+// waits for the first sign-in, so an unreachable provider does not stop the
+// app from starting.
+func (s *Service) AddOIDCProvider(config OIDCProviderConfig) error {
+	if !oidcProviderName.MatchString(config.Name) {
+		return fmt.Errorf("invalid provider name %q: use lowercase letters, digits and dashes", config.Name)
+	}
+	if config.ClientID == "" {
+		return fmt.Errorf("provider %s: client ID is required", config.Name)
+	}
+
+	issuer, err := url.Parse(config.Issuer)
+	if err != nil || issuer.Host == "" {
+		return fmt.Errorf("provider %s: invalid issuer URL %q", config.Name, config.Issuer)
+	}
+	// Plain HTTP is only trusted for a provider on this machine, such as a
+	// mock provider during development
+	if issuer.Scheme != "https" && !isLoopbackHost(issuer.Hostname()) {
+		return fmt.Errorf("provider %s: issuer must use https", config.Name)
+	}
+
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	} else if !slices.Contains(config.Scopes, "openid") {
+		config.Scopes = append([]string{"openid"}, config.Scopes...)
+	}
+
+	if s.oidc == nil {
+		s.oidc = make(map[string]*oidcProvider)
+	}
+	s.oidc[config.Name] = &oidcProvider{config: config}
+	log.Printf("🔑 Sign-in with %s enabled (%s)", config.Name, config.Issuer)
+	return nil
+}
+
+// OIDCProviders lists the identity providers users can sign in with
+func (s *Service) OIDCProviders() []OIDCProviderInfo {
+	providers := []OIDCProviderInfo{}
+	for name := range s.oidc {
+		providers = append(providers, OIDCProviderInfo{
+			Name:     name,
+			LoginURL: oidcPath(name, "login"),
+		})
+	}
+	slices.SortFunc(providers, func(a, b OIDCProviderInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return providers
+}
+
+// BeginOIDCLogin starts an authorization code flow with PKCE. It returns the
+// provider URL to send the browser to and the state that must come back
+// with it.
+func (s *Service) BeginOIDCLogin(providerName, returnTo string) (string, string, error) {
+	provider, ok := s.oidc[providerName]
+	if !ok {
+		return "", "", ErrUnknownOIDCProvider
+	}
+	discovery, err := provider.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	login := &OIDCLoginState{
+		Provider:     providerName,
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ReturnTo:     safeReturnTo(returnTo),
+		ExpiresAt:    time.Now().Add(OIDCLoginTTL),
+	}
+	if err := s.repo.SaveOIDCLoginState(login); err != nil {
+		return "", "", fmt.Errorf("failed to save sign-in state: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.config.ClientID},
+		"redirect_uri":          {s.oidcRedirectURL(providerName)},
+		"scope":                 {strings.Join(provider.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// CompleteOIDCLogin redeems the authorization code the provider returned,
+// validates the ID token and signs in the linked user, linking or creating
+// one by verified email on first sign-in. Accounts with 2FA get a challenge
+// as with Login. It also returns the local path the sign-in started from.
+func (s *Service) CompleteOIDCLogin(providerName, state, code string, device Device) (*LoginResponse, string, error) {
+	provider, ok := s.oidc[providerName]
+	if !ok {
+		return nil, "", ErrUnknownOIDCProvider
+	}
+
+	login, err := s.repo.ConsumeOIDCLoginState(hashToken(state))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load sign-in state: %w", err)
+	}
+	if login == nil || login.Provider != providerName || code == "" {
+		return nil, "", ErrInvalidOIDCLogin
+	}
+
+	rawIDToken, err := provider.exchangeCode(code, login.CodeVerifier, s.oidcRedirectURL(providerName))
+	if err != nil {
+		return nil, "", err
+	}
+	claims, err := provider.verifyIDToken(rawIDToken, login.Nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user, err := s.oidcUser(providerName, claims)
+	if err != nil {
+		return nil, "", err
+	}
+	if !user.IsActive {
+		return nil, "", ErrAccountDeactivated
+	}
+
+	challenge, err := s.twoFactorChallenge(user)
+	if err != nil {
+		return nil, "", err
+	}
+	if challenge != nil {
+		return &LoginResponse{Challenge: challenge}, login.ReturnTo, nil
+	}
+
+	response, err := s.startSession(user, device)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.eventBus.Publish("user.logged_in", map[string]any{
+		"user_id":  user.ID,
+		"email":    user.Email,
+		"provider": providerName,
+	})
+
+	return response, login.ReturnTo, nil
+}
+
+// oidcUser returns the user linked to the token's subject. On a first
+// sign-in the identity is linked to the user with the same email, or to a
+// new user, but only when the provider has verified the email. An existing
+// account must have verified it too, or whoever registered it first could
+// take over the provider's user.
+func (s *Service) oidcUser(providerName string, claims *idTokenClaims) (*User, error) {
+	identity, err := s.repo.GetOIDCIdentity(providerName, claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	if identity != nil {
+		user, err := s.repo.GetUserByID(identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("linked user not found: %w", err)
+		}
+		identity.Email = claims.Email
+		identity.LastLoginAt = time.Now()
+		if err := s.repo.SaveOIDCIdentity(identity); err != nil {
+			log.Printf("Warning: Failed to update %s identity of user %s: %v", providerName, user.ID, err)
+		}
+		return user, nil
+	}
+
+	if claims.Email == "" || !claims.emailVerified() {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.repo.GetUserByEmail(claims.Email)
+	if err == nil {
+		if !user.EmailVerified {
+			return nil, ErrOIDCLinkRefused
+		}
+	} else {
+		user, err = s.createOIDCUser(providerName, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	identity = &OIDCIdentity{
+		Provider:    providerName,
+		Subject:     claims.Subject,
+		UserID:      user.ID,
+		Email:       claims.Email,
+		LinkedAt:    now,
+		LastLoginAt: now,
+	}
+	if err := s.repo.SaveOIDCIdentity(identity); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	log.Printf("🔗 Linked %s identity %s to user %s", providerName, claims.Subject, user.ID)
+	s.eventBus.Publish("user.identity_linked", map[string]any{
+		"user_id":  user.ID,
+		"email":    user.Email,
+		"provider": providerName,
+	})
+	return user, nil
+}
+
+// createOIDCUser registers a user for a provider's account. The user has no
+// password until they set one through password reset.
+func (s *Service) createOIDCUser(providerName string, claims *idTokenClaims) (*User, error) {
+	username := claims.PreferredUsername
+	if username == "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if len(username) < 3 {
+		username += "-" + uuid.New().String()[:4]
+	}
+	if existing, err := s.repo.GetUserByUsername(username); err == nil && existing != nil {
+		username += "-" + uuid.New().String()[:4]
+	}
+
+	now := time.Now()
+	user := &User{
+		Email:           claims.Email,
+		Username:        username,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.repo.CreateUser(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	log.Printf("🔐 Registered user %s through %s", user.Email, providerName)
+	s.eventBus.Publish("user.registered", map[string]any{
+		"user_id":  user.ID,
+		"email":    user.Email,
+		"username": user.Username,
+		"provider": providerName,
+	})
+	return user, nil
+}
+
+func (s *Service) oidcRedirectURL(providerName string) string {
+	return s.appURL + oidcPath(providerName, "callback")
+}
+
+func oidcPath(providerName, action string) string {
+	return "/api/auth/oidc/" + providerName + "/" + action
+}
+
+// discover fetches the provider's metadata once and caches it
+func (p *oidcProvider) discover() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	endpoint := strings.TrimRight(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := oidcGetJSON(endpoint, &discovery); err != nil {
+		return nil, fmt.Errorf("%w: discovery: %v", ErrOIDCProvider, err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != strings.TrimRight(p.config.Issuer, "/") {
+		return nil, fmt.Errorf("%w: discovery returned issuer %q", ErrOIDCProvider, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document is missing endpoints", ErrOIDCProvider)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// exchangeCode redeems an authorization code with its PKCE verifier for an
+// ID token
+func (p *oidcProvider) exchangeCode(code, verifier, redirectURL string) (string, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: token request: %v", ErrOIDCProvider, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseBytes)).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: token response: %s", ErrOIDCProvider, resp.Status)
+	}
+	if body.Error != "" {
+		// The provider refused the code: reused, expired or not ours
+		return "", fmt.Errorf("%w: %s %s", ErrInvalidOIDCLogin, body.Error, body.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		return "", fmt.Errorf("%w: token response without an ID token (%s)", ErrOIDCProvider, resp.Status)
+	}
+	return body.IDToken, nil
+}
+
+// verifyIDToken checks the ID token's signature against the provider's keys
+// and its issuer, audience, lifetime and nonce
+func (p *oidcProvider) verifyIDToken(rawIDToken, nonce string) (*idTokenClaims, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &idTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.verificationKey(kid)
+	},
+		jwt.WithValidMethods(oidcSigningAlgorithms),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: issued to %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+	return claims, nil
+}
+
+// verificationKey returns the provider key named kid, refetching the key set
+// when the provider may have rotated its keys. A token without a kid is
+// accepted only while the provider publishes a single key.
+func (p *oidcProvider) verificationKey(kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := p.lookupKey(kid)
+	if key == nil && time.Since(p.keysFetchedAt) >= oidcKeyRefreshInterval {
+		if err := p.fetchKeys(); err != nil {
+			return nil, err
+		}
+		key = p.lookupKey(kid)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSigningKey, kid)
+	}
+	return key, nil
+}
+
+func (p *oidcProvider) lookupKey(kid string) any {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+// fetchKeys loads the provider's JWKS. Callers hold p.mu and have run
+// discover.
+func (p *oidcProvider) fetchKeys() error {
+	p.keysFetchedAt = time.Now()
+
+	var set JWKSet
+	if err := oidcGetJSON(p.discovery.JWKSURI, &set); err != nil {
+		return fmt.Errorf("%w: keys: %v", ErrOIDCProvider, err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parsePublicJWK(jwk)
+		if err != nil {
+			log.Printf("Warning: Skipping %s key %s: %v", p.config.Name, jwk.KeyID, err)
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	p.keys = keys
+	return nil
+}
+
+// parsePublicJWK decodes an RSA, EC or Ed25519 public key
+func parsePublicJWK(jwk JWK) (any, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid EC coordinates")
+		}
+		return ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+}
+
+func oidcGetJSON(endpoint string, v any) error {
+	resp, err := oidcHTTPClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseBytes)).Decode(v)
+}
+
+// safeReturnTo keeps only local paths, so a sign-in link cannot send the
+// browser to another site afterwards
+func safeReturnTo(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	parsed, err := url.Parse(path)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" {
+		return "/"
+	}
+	return path
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
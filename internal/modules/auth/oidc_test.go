@@ -0,0 +1,428 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	mockClientID = "business-manager"
+	mockKeyID    = "mock-key"
+)
+
+// mockProvider is an OpenID Connect provider serving discovery, a JWKS, an
+// authorization endpoint that approves every request and a token endpoint
+// that enforces PKCE
+type mockProvider struct {
+	server *httptest.Server
+	key    ed25519.PrivateKey
+	// signingKey signs ID tokens; it is key unless a test swaps it out
+	signingKey ed25519.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]mockGrant
+	// user is the account that signs in
+	user jwt.MapClaims
+	// tamper, when set, changes the ID token claims before signing
+	tamper func(jwt.MapClaims)
+}
+
+type mockGrant struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p := &mockProvider{
+		key:        key,
+		signingKey: key,
+		grants:     map[string]mockGrant{},
+		user: jwt.MapClaims{
+			"sub":            "subject-1",
+			"email":          "sso@example.com",
+			"email_verified": true,
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                p.server.URL,
+			AuthorizationEndpoint: p.server.URL + "/authorize",
+			TokenEndpoint:         p.server.URL + "/token",
+			JWKSURI:               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JWKSet{Keys: []JWK{{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			KeyID:     mockKeyID,
+			Use:       "sig",
+			Algorithm: AlgorithmEdDSA,
+			X:         base64.RawURLEncoding.EncodeToString(p.key.Public().(ed25519.PublicKey)),
+		}}})
+	})
+	mux.HandleFunc("GET /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != mockClientID || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	p.mu.Lock()
+	p.grants[code] = mockGrant{
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	p.mu.Unlock()
+
+	callback := url.Values{"code": {code}, "state": {query.Get("state")}}
+	http.Redirect(w, r, query.Get("redirect_uri")+"?"+callback.Encode(), http.StatusFound)
+}
+
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	refuse := func(reason string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": reason})
+	}
+
+	p.mu.Lock()
+	grant, ok := p.grants[r.FormValue("code")]
+	delete(p.grants, r.FormValue("code"))
+	claims := jwt.MapClaims{}
+	for name, value := range p.user {
+		claims[name] = value
+	}
+	tamper := p.tamper
+	p.mu.Unlock()
+
+	if !ok {
+		refuse("unknown or used code")
+		return
+	}
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		refuse("PKCE verification failed")
+		return
+	}
+	if r.FormValue("redirect_uri") != grant.redirectURI || r.FormValue("client_id") != mockClientID {
+		refuse("client mismatch")
+		return
+	}
+
+	now := time.Now()
+	claims["iss"] = p.server.URL
+	claims["aud"] = mockClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(5 * time.Minute).Unix()
+	claims["nonce"] = grant.nonce
+	if tamper != nil {
+		tamper(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = mockKeyID
+	idToken, err := token.SignedString(p.signingKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+// newOIDCService returns a service with the mock provider registered as "mock"
+func newOIDCService(t *testing.T, p *mockProvider) *Service {
+	t.Helper()
+	s, _ := newTestService(t)
+	if err := s.AddOIDCProvider(OIDCProviderConfig{Name: "mock", Issuer: p.server.URL, ClientID: mockClientID}); err != nil {
+		t.Fatalf("AddOIDCProvider: %v", err)
+	}
+	return s
+}
+
+// approve follows an authorization URL to the mock provider and returns the
+// code and state it redirects back with
+func approve(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize redirect: %v", err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+// signIn runs a whole sign-in against the mock provider
+func signIn(t *testing.T, s *Service) (*LoginResponse, error) {
+	t.Helper()
+	authURL, state, err := s.BeginOIDCLogin("mock", "/invoices")
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin: %v", err)
+	}
+	code, returnedState := approve(t, authURL)
+	if returnedState != state {
+		t.Fatalf("provider returned state %q, want %q", returnedState, state)
+	}
+	response, returnTo, err := s.CompleteOIDCLogin("mock", state, code, testDevice)
+	if err == nil && returnTo != "/invoices" {
+		t.Errorf("return to %q, want /invoices", returnTo)
+	}
+	return response, err
+}
+
+func TestOIDCLoginCreatesAndReusesUser(t *testing.T) {
+	p := newMockProvider(t)
+	s := newOIDCService(t, p)
+
+	first, err := signIn(t, s)
+	if err != nil {
+		t.Fatalf("first sign-in: %v", err)
+	}
+	if first.User.Email != "sso@example.com" || !first.User.EmailVerified {
+		t.Errorf("created user %+v", first.User)
+	}
+	if _, err := s.VerifyToken(first.Token); err != nil {
+		t.Errorf("access token: %v", err)
+	}
+
+	// The subject stays linked even if the provider's email changes
+	p.user["email"] = "renamed@example.com"
+	second, err := signIn(t, s)
+	if err != nil {
+		t.Fatalf("second sign-in: %v", err)
+	}
+	if second.User.ID != first.User.ID {
+		t.Errorf("second sign-in user %s, want %s", second.User.ID, first.User.ID)
+	}
+}
+
+func TestOIDCLoginEnforcesPKCE(t *testing.T) {
+	p := newMockProvider(t)
+	s := newOIDCService(t, p)
+
+	// A code issued to one sign-in cannot be redeemed by another, whose
+	// verifier does not match the code's challenge
+	authURL, _, err := s.BeginOIDCLogin("mock", "/")
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin: %v", err)
+	}
+	stolen, _ := approve(t, authURL)
+	_, attackerState, err := s.BeginOIDCLogin("mock", "/")
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin: %v", err)
+	}
+
+	if _, _, err := s.CompleteOIDCLogin("mock", attackerState, stolen, testDevice); !errors.Is(err, ErrInvalidOIDCLogin) {
+		t.Errorf("err = %v, want ErrInvalidOIDCLogin", err)
+	}
+}
+
+func TestOIDCLoginRejectsStateMismatch(t *testing.T) {
+	p := newMockProvider(t)
+	s := newOIDCService(t, p)
+	if err := s.AddOIDCProvider(OIDCProviderConfig{Name: "other", Issuer: p.server.URL, ClientID: mockClientID}); err != nil {
+		t.Fatalf("AddOIDCProvider: %v", err)
+	}
+
+	authURL, state, err := s.BeginOIDCLogin("mock", "/")
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin: %v", err)
+	}
+	code, _ := approve(t, authURL)
+
+	if _, _, err := s.CompleteOIDCLogin("mock", "forged", code, testDevice); !errors.Is(err, ErrInvalidOIDCLogin) {
+		t.Errorf("unknown state: err = %v, want ErrInvalidOIDCLogin", err)
+	}
+	if _, _, err := s.CompleteOIDCLogin("other", state, code, testDevice); !errors.Is(err, ErrInvalidOIDCLogin) {
+		t.Errorf("state of another provider: err = %v, want ErrInvalidOIDCLogin", err)
+	}
+	// The state is spent by the failed attempt above
+	if _, _, err := s.CompleteOIDCLogin("mock", state, code, testDevice); !errors.Is(err, ErrInvalidOIDCLogin) {
+		t.Errorf("reused state: err = %v, want ErrInvalidOIDCLogin", err)
+	}
+}
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	p := newMockProvider(t)
+	s := newOIDCService(t, p)
+	h := NewHandler(s)
+
+	authURL, state, err := s.BeginOIDCLogin("mock", "/")
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin: %v", err)
+	}
+	code, _ := approve(t, authURL)
+
+	callback := func(cookie string) int {
+		query := url.Values{"code": {code}, "state": {state}}
+		r := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/callback?"+query.Encode(), nil)
+		r.SetPathValue("provider", "mock")
+		if cookie != "" {
+			r.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		h.OIDCCallback(w, r)
+		return w.Code
+	}
+
+	// A sign-in started in another browser is not completed in this one
+	if status := callback(""); status != http.StatusUnauthorized {
+		t.Errorf("without cookie: status = %d, want 401", status)
+	}
+	if status := callback("another-state"); status != http.StatusUnauthorized {
+		t.Errorf("mismatched cookie: status = %d, want 401", status)
+	}
+	if status := callback(state); status != http.StatusOK {
+		t.Errorf("matching cookie: status = %d, want 200", status)
+	}
+}
+
+func TestOIDCLoginValidatesIDToken(t *testing.T) {
+	_, foreignKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		tamper func(jwt.MapClaims)
+		key    ed25519.PrivateKey
+		ok     bool
+	}{
+		{"nonce mismatch", func(c jwt.MapClaims) { c["nonce"] = "replayed" }, nil, false},
+		{"missing nonce", func(c jwt.MapClaims) { delete(c, "nonce") }, nil, false},
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, nil, false},
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "another-app" }, nil, false},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-5 * time.Minute).Unix() }, nil, false},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }, nil, false},
+		{"issued in the future", func(c jwt.MapClaims) { c["iat"] = time.Now().Add(10 * time.Minute).Unix() }, nil, false},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }, nil, false},
+		{"several audiences without azp", func(c jwt.MapClaims) { c["aud"] = []string{mockClientID, "another-app"} }, nil, false},
+		{"several audiences for another azp", func(c jwt.MapClaims) {
+			c["aud"] = []string{mockClientID, "another-app"}
+			c["azp"] = "another-app"
+		}, nil, false},
+		{"several audiences for us", func(c jwt.MapClaims) {
+			c["aud"] = []string{mockClientID, "another-app"}
+			c["azp"] = mockClientID
+		}, nil, true},
+		{"signed with another key", nil, foreignKey, false},
+		{"untouched", nil, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newMockProvider(t)
+			p.tamper = tc.tamper
+			if tc.key != nil {
+				p.signingKey = tc.key
+			}
+			s := newOIDCService(t, p)
+
+			_, err := signIn(t, s)
+			if tc.ok && err != nil {
+				t.Errorf("sign-in: %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("err = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginLinksOnlyVerifiedEmails(t *testing.T) {
+	t.Run("unverified at the provider", func(t *testing.T) {
+		p := newMockProvider(t)
+		p.user["email_verified"] = false
+		s := newOIDCService(t, p)
+
+		if _, err := signIn(t, s); !errors.Is(err, ErrOIDCEmailNotVerified) {
+			t.Errorf("err = %v, want ErrOIDCEmailNotVerified", err)
+		}
+		if _, err := s.repo.GetUserByEmail("sso@example.com"); err == nil {
+			t.Error("user created for an unverified email")
+		}
+	})
+
+	t.Run("verified as a string", func(t *testing.T) {
+		p := newMockProvider(t)
+		p.user["email_verified"] = "true"
+		s := newOIDCService(t, p)
+
+		if _, err := signIn(t, s); err != nil {
+			t.Errorf("sign-in: %v", err)
+		}
+	})
+
+	t.Run("unverified local account", func(t *testing.T) {
+		p := newMockProvider(t)
+		s := newOIDCService(t, p)
+		registerUser(t, s, "sso@example.com")
+
+		if _, err := signIn(t, s); !errors.Is(err, ErrOIDCLinkRefused) {
+			t.Errorf("err = %v, want ErrOIDCLinkRefused", err)
+		}
+		if identity, _ := s.repo.GetOIDCIdentity("mock", "subject-1"); identity != nil {
+			t.Error("identity linked to an unverified account")
+		}
+	})
+
+	t.Run("verified local account", func(t *testing.T) {
+		p := newMockProvider(t)
+		s := newOIDCService(t, p)
+		local := registerUser(t, s, "sso@example.com")
+		user, err := s.repo.GetUserByID(local.User.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		user.EmailVerified = true
+		if err := s.repo.UpdateUser(user); err != nil {
+			t.Fatalf("UpdateUser: %v", err)
+		}
+
+		response, err := signIn(t, s)
+		if err != nil {
+			t.Fatalf("sign-in: %v", err)
+		}
+		if response.User.ID != local.User.ID {
+			t.Errorf("signed in as %s, want the existing user %s", response.User.ID, local.User.ID)
+		}
+	})
+}
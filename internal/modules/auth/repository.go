@@ -3,6 +3,7 @@ package auth
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
@@ -17,6 +18,17 @@ func NewRepository(db *badger.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// userRecord is the stored form of a user. User hides the password hash from
+// JSON responses, so the record carries it separately.
+type userRecord struct {
+	User
+	PasswordHash string `json:"password_hash"`
+}
+
+func marshalUser(user *User) ([]byte, error) {
+	return json.Marshal(userRecord{User: *user, PasswordHash: user.Password})
+}
+
 func (r *Repository) CreateUser(user *User) error {
 	user.ID = uuid.New().String()
 	user.CreatedAt = time.Now()
@@ -38,7 +50,7 @@ func (r *Repository) CreateUser(user *User) error {
 			return fmt.Errorf("user with username %s already exists", user.Username)
 		}
 
-		data, err := json.Marshal(user)
+		data, err := marshalUser(user)
 		if err != nil {
 			return err
 		}
@@ -56,7 +68,7 @@ func (r *Repository) CreateUser(user *User) error {
 }
 
 func (r *Repository) GetUserByID(id string) (*User, error) {
-	var user User
+	var record userRecord
 	err := r.db.View(func(txn *badger.Txn) error {
 		key := fmt.Sprintf("user:%s", id)
 		item, err := txn.Get([]byte(key))
@@ -65,9 +77,11 @@ func (r *Repository) GetUserByID(id string) (*User, error) {
 		}
 
 		return item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &user)
+			return json.Unmarshal(val, &record)
 		})
 	})
+	user := record.User
+	user.Password = record.PasswordHash
 	return &user, err
 }
 
@@ -118,7 +132,7 @@ func (r *Repository) UpdateUser(user *User) error {
 
 	return r.db.Update(func(txn *badger.Txn) error {
 		key := fmt.Sprintf("user:%s", user.ID)
-		data, err := json.Marshal(user)
+		data, err := marshalUser(user)
 		if err != nil {
 			return err
 		}
@@ -126,102 +140,969 @@ func (r *Repository) UpdateUser(user *User) error {
 	})
 }
 
+// CreateSession stores a session with its refresh token and user indexes. The
+// entries expire with the session.
 func (r *Repository) CreateSession(session *Session) error {
 	session.ID = uuid.New().String()
 	session.CreatedAt = time.Now()
 	session.IsActive = true
 
 	return r.db.Update(func(txn *badger.Txn) error {
-		key := fmt.Sprintf("session:%s", session.ID)
-		userSessionKey := fmt.Sprintf("user_session:%s", session.UserID)
-		
-		data, err := json.Marshal(session)
+		return r.putSession(txn, session, true)
+	})
+}
+
+func (r *Repository) GetSession(sessionID string) (*Session, error) {
+	var session *Session
+	err := r.db.View(func(txn *badger.Txn) error {
+		var err error
+		session, err = r.getSession(txn, sessionID)
+		return err
+	})
+	return session, err
+}
+
+// GetSessionByToken looks a session up by the hash of its refresh token
+func (r *Repository) GetSessionByToken(tokenHash string) (*Session, error) {
+	var session *Session
+	err := r.db.View(func(txn *badger.Txn) error {
+		key := fmt.Sprintf("session_token:%s", tokenHash)
+		item, err := txn.Get([]byte(key))
 		if err != nil {
 			return err
 		}
 
-		if err := txn.Set([]byte(key), data); err != nil {
+		var sessionID string
+		err = item.Value(func(val []byte) error {
+			sessionID = string(val)
+			return nil
+		})
+		if err != nil {
 			return err
 		}
 
-		return txn.Set([]byte(userSessionKey), []byte(session.ID))
+		session, err = r.getSession(txn, sessionID)
+		return err
 	})
+	return session, err
 }
 
-func (r *Repository) GetSessionByToken(token string) (*Session, error) {
-	var session Session
+// GetUserSessions returns every stored session of a user, rotated and revoked
+// ones included
+func (r *Repository) GetUserSessions(userID string) ([]*Session, error) {
+	var sessions []*Session
 	err := r.db.View(func(txn *badger.Txn) error {
-		key := fmt.Sprintf("session_token:%s", token)
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(fmt.Sprintf("user_sessions:%s:", userID))
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			sessionID := strings.TrimPrefix(string(it.Item().Key()), string(prefix))
+			session, err := r.getSession(txn, sessionID)
+			if err == badger.ErrKeyNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, session)
+		}
+		return nil
+	})
+	return sessions, err
+}
+
+// RotateSession marks the current session as replaced and stores next in the
+// same transaction. It fails with ErrRefreshTokenReused if the session was
+// already rotated, so two refreshes racing on one token cannot both succeed.
+func (r *Repository) RotateSession(currentID string, next *Session) error {
+	now := time.Now()
+	next.ID = uuid.New().String()
+	next.CreatedAt = now
+	next.IsActive = true
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		current, err := r.getSession(txn, currentID)
+		if err != nil {
+			return err
+		}
+		if current.ReplacedBy != "" || current.RevokedAt != nil {
+			return ErrRefreshTokenReused
+		}
+
+		current.IsActive = false
+		current.RotatedAt = &now
+		current.ReplacedBy = next.ID
+		if err := r.putSession(txn, current, false); err != nil {
+			return err
+		}
+
+		return r.putSession(txn, next, true)
+	})
+}
+
+// InvalidateSession revokes a single session
+func (r *Repository) InvalidateSession(sessionID string) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		session, err := r.getSession(txn, sessionID)
+		if err != nil {
+			return err
+		}
+		return r.revokeSession(txn, session, time.Now())
+	})
+}
+
+// InvalidateSessionFamily revokes every session descended from the same login
+func (r *Repository) InvalidateSessionFamily(userID, familyID string) error {
+	return r.invalidateSessions(userID, func(session *Session) bool {
+		return session.FamilyID == familyID
+	})
+}
+
+// InvalidateUserSessions revokes all of a user's sessions
+func (r *Repository) InvalidateUserSessions(userID string) error {
+	return r.invalidateSessions(userID, func(*Session) bool { return true })
+}
+
+// InvalidateOtherSessions revokes all of a user's sessions outside keepFamilyID
+func (r *Repository) InvalidateOtherSessions(userID, keepFamilyID string) error {
+	return r.invalidateSessions(userID, func(session *Session) bool {
+		return session.FamilyID != keepFamilyID
+	})
+}
+
+func (r *Repository) invalidateSessions(userID string, match func(*Session) bool) error {
+	sessions, err := r.GetUserSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return r.db.Update(func(txn *badger.Txn) error {
+		for _, session := range sessions {
+			if session.RevokedAt != nil || !match(session) {
+				continue
+			}
+			if err := r.revokeSession(txn, session, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// revokeSession marks the session revoked and puts its access token on the
+// revocation list
+func (r *Repository) revokeSession(txn *badger.Txn, session *Session, at time.Time) error {
+	if session.RevokedAt != nil {
+		return nil
+	}
+	session.IsActive = false
+	session.RevokedAt = &at
+	if err := r.putSession(txn, session, false); err != nil {
+		return err
+	}
+	return r.revokeToken(txn, session.AccessTokenID, session.AccessExpiresAt)
+}
+
+// RevokeToken adds an access token's jti to the revocation list until the
+// token would have expired anyway
+func (r *Repository) RevokeToken(tokenID string, expiresAt time.Time) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		return r.revokeToken(txn, tokenID, expiresAt)
+	})
+}
+
+func (r *Repository) IsTokenRevoked(tokenID string) (bool, error) {
+	err := r.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(fmt.Sprintf("revoked_token:%s", tokenID)))
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *Repository) revokeToken(txn *badger.Txn, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+	key := fmt.Sprintf("revoked_token:%s", tokenID)
+	return txn.SetEntry(badger.NewEntry([]byte(key), []byte(expiresAt.Format(time.RFC3339))).WithTTL(ttl))
+}
+
+func (r *Repository) getSession(txn *badger.Txn, sessionID string) (*Session, error) {
+	item, err := txn.Get([]byte(fmt.Sprintf("session:%s", sessionID)))
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// putSession writes the session record, and its indexes when withIndexes is
+// set, all expiring at the session's expiry
+func (r *Repository) putSession(txn *badger.Txn, session *Session, withIndexes bool) error {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("session:%s", session.ID)
+	if err := txn.SetEntry(badger.NewEntry([]byte(key), data).WithTTL(ttl)); err != nil {
+		return err
+	}
+	if !withIndexes {
+		return nil
+	}
+
+	tokenKey := fmt.Sprintf("session_token:%s", session.TokenHash)
+	if err := txn.SetEntry(badger.NewEntry([]byte(tokenKey), []byte(session.ID)).WithTTL(ttl)); err != nil {
+		return err
+	}
+
+	userSessionKey := fmt.Sprintf("user_sessions:%s:%s", session.UserID, session.ID)
+	return txn.SetEntry(badger.NewEntry([]byte(userSessionKey), []byte(session.ID)).WithTTL(ttl))
+}
+
+// SaveActionToken stores a single-use token, replacing any outstanding token
+// of the same purpose for the user so only the latest link works
+func (r *Repository) SaveActionToken(token *ActionToken) error {
+	token.CreatedAt = time.Now()
+	ttl := time.Until(token.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("action token already expired")
+	}
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		userKey := fmt.Sprintf("user_action_token:%s:%s", token.Purpose, token.UserID)
+		item, err := txn.Get([]byte(userKey))
+		if err == nil {
+			previous, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if err := txn.Delete([]byte(fmt.Sprintf("action_token:%s:%s", token.Purpose, previous))); err != nil {
+				return err
+			}
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		data, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("action_token:%s:%s", token.Purpose, token.TokenHash)
+		if err := txn.SetEntry(badger.NewEntry([]byte(key), data).WithTTL(ttl)); err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry([]byte(userKey), []byte(token.TokenHash)).WithTTL(ttl))
+	})
+}
+
+// ConsumeActionToken returns the token stored under the hash and deletes it,
+// so each token can be used once. It returns badger.ErrKeyNotFound for
+// unknown, used or expired tokens.
+func (r *Repository) ConsumeActionToken(purpose, tokenHash string) (*ActionToken, error) {
+	var token ActionToken
+	err := r.db.Update(func(txn *badger.Txn) error {
+		key := fmt.Sprintf("action_token:%s:%s", purpose, tokenHash)
 		item, err := txn.Get([]byte(key))
 		if err != nil {
 			return err
 		}
 
-		var sessionID string
 		err = item.Value(func(val []byte) error {
-			sessionID = string(val)
-			return nil
+			return json.Unmarshal(val, &token)
 		})
 		if err != nil {
 			return err
 		}
 
-		sessionKey := fmt.Sprintf("session:%s", sessionID)
-		sessionItem, err := txn.Get([]byte(sessionKey))
+		if err := txn.Delete([]byte(key)); err != nil {
+			return err
+		}
+		return txn.Delete([]byte(fmt.Sprintf("user_action_token:%s:%s", purpose, token.UserID)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, badger.ErrKeyNotFound
+	}
+	return &token, nil
+}
+
+// FailActionToken records a wrong answer against a token, deleting it once
+// maxAttempts is reached. It reports whether the token is still usable.
+func (r *Repository) FailActionToken(purpose, tokenHash string, maxAttempts int) (bool, error) {
+	usable := false
+	err := r.db.Update(func(txn *badger.Txn) error {
+		key := fmt.Sprintf("action_token:%s:%s", purpose, tokenHash)
+		item, err := txn.Get([]byte(key))
 		if err != nil {
 			return err
 		}
 
-		return sessionItem.Value(func(val []byte) error {
-			return json.Unmarshal(val, &session)
+		var token ActionToken
+		err = item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &token)
 		})
+		if err != nil {
+			return err
+		}
+
+		token.Attempts++
+		if token.Attempts >= maxAttempts {
+			if err := txn.Delete([]byte(key)); err != nil {
+				return err
+			}
+			return txn.Delete([]byte(fmt.Sprintf("user_action_token:%s:%s", purpose, token.UserID)))
+		}
+
+		data, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+		usable = true
+		return txn.SetEntry(badger.NewEntry([]byte(key), data).WithTTL(time.Until(token.ExpiresAt)))
 	})
-	return &session, err
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return usable, err
 }
 
-func (r *Repository) InvalidateSession(sessionID string) error {
+// GetActionToken returns a stored token without using it up
+func (r *Repository) GetActionToken(purpose, tokenHash string) (*ActionToken, error) {
+	var token ActionToken
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(fmt.Sprintf("action_token:%s:%s", purpose, tokenHash)))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &token)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, badger.ErrKeyNotFound
+	}
+	return &token, nil
+}
+
+// GetTwoFactorState returns the user's 2FA record, or an empty one if the
+// user never enrolled
+func (r *Repository) GetTwoFactorState(userID string) (*TwoFactorState, error) {
+	state := &TwoFactorState{UserID: userID}
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(fmt.Sprintf("user_2fa:%s", userID)))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, state)
+		})
+	})
+	if err == badger.ErrKeyNotFound {
+		return state, nil
+	}
+	return state, err
+}
+
+func (r *Repository) SaveTwoFactorState(state *TwoFactorState) error {
 	return r.db.Update(func(txn *badger.Txn) error {
-		key := fmt.Sprintf("session:%s", sessionID)
-		item, err := txn.Get([]byte(key))
+		data, err := json.Marshal(state)
 		if err != nil {
 			return err
 		}
+		return txn.Set([]byte(fmt.Sprintf("user_2fa:%s", state.UserID)), data)
+	})
+}
 
-		var session Session
-		err = item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &session)
+// CreateAPIToken stores a personal access token under its hash, expiring
+// with the token if it has an expiry
+func (r *Repository) CreateAPIToken(token *APIToken) error {
+	token.ID = uuid.New().String()
+	token.CreatedAt = time.Now()
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		return r.putAPIToken(txn, token, true)
+	})
+}
+
+// GetAPITokenByHash looks a token up by the hash of its plaintext
+func (r *Repository) GetAPITokenByHash(tokenHash string) (*APIToken, error) {
+	var token *APIToken
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(fmt.Sprintf("api_token_hash:%s", tokenHash)))
+		if err != nil {
+			return err
+		}
+		tokenID, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		token, err = r.getAPIToken(txn, string(tokenID))
+		return err
+	})
+	return token, err
+}
+
+func (r *Repository) GetUserAPITokens(userID string) ([]*APIToken, error) {
+	var tokens []*APIToken
+	err := r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(fmt.Sprintf("user_api_tokens:%s:", userID))
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			tokenID := strings.TrimPrefix(string(it.Item().Key()), string(prefix))
+			token, err := r.getAPIToken(txn, tokenID)
+			if err == badger.ErrKeyNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			tokens = append(tokens, token)
+		}
+		return nil
+	})
+	return tokens, err
+}
+
+// TouchAPIToken records when a token was last used
+func (r *Repository) TouchAPIToken(tokenID string, usedAt time.Time) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		token, err := r.getAPIToken(txn, tokenID)
+		if err != nil {
+			return err
+		}
+		token.LastUsedAt = &usedAt
+		return r.putAPIToken(txn, token, false)
+	})
+}
+
+// DeleteAPIToken removes a user's token and its indexes
+func (r *Repository) DeleteAPIToken(userID, tokenID string) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		token, err := r.getAPIToken(txn, tokenID)
+		if err != nil {
+			return err
+		}
+		if token.UserID != userID {
+			return badger.ErrKeyNotFound
+		}
+
+		keys := []string{
+			fmt.Sprintf("api_token:%s", token.ID),
+			fmt.Sprintf("api_token_hash:%s", token.TokenHash),
+			fmt.Sprintf("user_api_tokens:%s:%s", token.UserID, token.ID),
+		}
+		for _, key := range keys {
+			if err := txn.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Repository) getAPIToken(txn *badger.Txn, tokenID string) (*APIToken, error) {
+	item, err := txn.Get([]byte(fmt.Sprintf("api_token:%s", tokenID)))
+	if err != nil {
+		return nil, err
+	}
+
+	var token APIToken
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *Repository) putAPIToken(txn *badger.Txn, token *APIToken, withIndexes bool) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	entry := func(key string, value []byte) *badger.Entry {
+		e := badger.NewEntry([]byte(key), value)
+		if token.ExpiresAt != nil {
+			e = e.WithTTL(time.Until(*token.ExpiresAt))
+		}
+		return e
+	}
+
+	if err := txn.SetEntry(entry(fmt.Sprintf("api_token:%s", token.ID), data)); err != nil {
+		return err
+	}
+	if !withIndexes {
+		return nil
+	}
+	if err := txn.SetEntry(entry(fmt.Sprintf("api_token_hash:%s", token.TokenHash), []byte(token.ID))); err != nil {
+		return err
+	}
+	return txn.SetEntry(entry(fmt.Sprintf("user_api_tokens:%s:%s", token.UserID, token.ID), []byte(token.ID)))
+}
+
+// GetLoginAttempts returns the failure counter stored under key, empty if none
+func (r *Repository) GetLoginAttempts(key string) (*LoginAttempts, error) {
+	attempts := &LoginAttempts{}
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(fmt.Sprintf("login_attempts:%s", key)))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, attempts)
 		})
+	})
+	if err == badger.ErrKeyNotFound {
+		return attempts, nil
+	}
+	return attempts, err
+}
+
+// UpdateLoginAttempts applies update to the counter under key in one
+// transaction and keeps it for ttl after the last change
+func (r *Repository) UpdateLoginAttempts(key string, ttl time.Duration, update func(*LoginAttempts)) (*LoginAttempts, error) {
+	attempts := &LoginAttempts{}
+	err := r.db.Update(func(txn *badger.Txn) error {
+		storeKey := []byte(fmt.Sprintf("login_attempts:%s", key))
+		item, err := txn.Get(storeKey)
+		if err == nil {
+			err = item.Value(func(val []byte) error {
+				return json.Unmarshal(val, attempts)
+			})
+		}
+		if err != nil && err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		update(attempts)
+
+		data, err := json.Marshal(attempts)
+		if err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(storeKey, data).WithTTL(ttl))
+	})
+	return attempts, err
+}
+
+func (r *Repository) ClearLoginAttempts(key string) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(fmt.Sprintf("login_attempts:%s", key)))
+	})
+}
+
+// CreateWorkspace stores a workspace together with its owner's membership
+func (r *Repository) CreateWorkspace(workspace *Workspace, owner *Membership) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(workspace)
 		if err != nil {
 			return err
 		}
+		if err := txn.Set([]byte(fmt.Sprintf("workspace:%s", workspace.ID)), data); err != nil {
+			return err
+		}
+		return r.putMembership(txn, owner)
+	})
+}
 
-		session.IsActive = false
-		data, err := json.Marshal(session)
+// GetWorkspace returns a workspace, or nil if it doesn't exist
+func (r *Repository) GetWorkspace(workspaceID string) (*Workspace, error) {
+	var workspace Workspace
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(fmt.Sprintf("workspace:%s", workspaceID)))
 		if err != nil {
 			return err
 		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &workspace)
+		})
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
 
-		return txn.Set([]byte(key), data)
+// GetMembership returns a user's membership of a workspace, or nil if the
+// user is not a member
+func (r *Repository) GetMembership(workspaceID, userID string) (*Membership, error) {
+	var membership *Membership
+	err := r.db.View(func(txn *badger.Txn) error {
+		var err error
+		membership, err = r.getMembership(txn, workspaceID, userID)
+		return err
 	})
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	return membership, err
 }
 
-func (r *Repository) InvalidateUserSessions(userID string) error {
+func (r *Repository) SaveMembership(membership *Membership) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		return r.putMembership(txn, membership)
+	})
+}
+
+func (r *Repository) DeleteMembership(workspaceID, userID string) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Delete([]byte(fmt.Sprintf("workspace_member:%s:%s", workspaceID, userID))); err != nil {
+			return err
+		}
+		return txn.Delete([]byte(fmt.Sprintf("user_workspace:%s:%s", userID, workspaceID)))
+	})
+}
+
+// GetWorkspaceMembers returns every membership of a workspace
+func (r *Repository) GetWorkspaceMembers(workspaceID string) ([]*Membership, error) {
+	var members []*Membership
+	err := r.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(fmt.Sprintf("workspace_member:%s:", workspaceID))
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var membership Membership
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &membership)
+			})
+			if err != nil {
+				return err
+			}
+			members = append(members, &membership)
+		}
+		return nil
+	})
+	return members, err
+}
+
+// GetUserMemberships returns the memberships of every workspace the user
+// belongs to
+func (r *Repository) GetUserMemberships(userID string) ([]*Membership, error) {
+	var memberships []*Membership
+	err := r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(fmt.Sprintf("user_workspace:%s:", userID))
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			workspaceID := strings.TrimPrefix(string(it.Item().Key()), string(prefix))
+			membership, err := r.getMembership(txn, workspaceID, userID)
+			if err == badger.ErrKeyNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			memberships = append(memberships, membership)
+		}
+		return nil
+	})
+	return memberships, err
+}
+
+func (r *Repository) getMembership(txn *badger.Txn, workspaceID, userID string) (*Membership, error) {
+	item, err := txn.Get([]byte(fmt.Sprintf("workspace_member:%s:%s", workspaceID, userID)))
+	if err != nil {
+		return nil, err
+	}
+
+	var membership Membership
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &membership)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (r *Repository) putMembership(txn *badger.Txn, membership *Membership) error {
+	data, err := json.Marshal(membership)
+	if err != nil {
+		return err
+	}
+	if err := txn.Set([]byte(fmt.Sprintf("workspace_member:%s:%s", membership.WorkspaceID, membership.UserID)), data); err != nil {
+		return err
+	}
+	return txn.Set([]byte(fmt.Sprintf("user_workspace:%s:%s", membership.UserID, membership.WorkspaceID)), []byte(membership.WorkspaceID))
+}
+
+// CreateInvitation stores an invitation under its token hash until it expires
+func (r *Repository) CreateInvitation(invitation *Invitation) error {
+	invitation.ID = uuid.New().String()
+	invitation.CreatedAt = time.Now()
+
+	data, err := json.Marshal(invitation)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(invitation.ExpiresAt)
 	return r.db.Update(func(txn *badger.Txn) error {
-		userSessionKey := fmt.Sprintf("user_session:%s", userID)
-		item, err := txn.Get([]byte(userSessionKey))
+		entries := map[string][]byte{
+			fmt.Sprintf("workspace_invite:%s", invitation.ID):                             data,
+			fmt.Sprintf("workspace_invite_hash:%s", invitation.TokenHash):                 []byte(invitation.ID),
+			fmt.Sprintf("workspace_invites:%s:%s", invitation.WorkspaceID, invitation.ID): []byte(invitation.ID),
+		}
+		for key, value := range entries {
+			if err := txn.SetEntry(badger.NewEntry([]byte(key), value).WithTTL(ttl)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetInvitationByHash looks an invitation up by the hash of its token
+func (r *Repository) GetInvitationByHash(tokenHash string) (*Invitation, error) {
+	var invitation *Invitation
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(fmt.Sprintf("workspace_invite_hash:%s", tokenHash)))
+		if err != nil {
+			return err
+		}
+		invitationID, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		invitation, err = r.getInvitation(txn, string(invitationID))
+		return err
+	})
+	return invitation, err
+}
+
+// GetWorkspaceInvitations returns a workspace's pending invitations
+func (r *Repository) GetWorkspaceInvitations(workspaceID string) ([]*Invitation, error) {
+	var invitations []*Invitation
+	err := r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(fmt.Sprintf("workspace_invites:%s:", workspaceID))
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			invitationID := strings.TrimPrefix(string(it.Item().Key()), string(prefix))
+			invitation, err := r.getInvitation(txn, invitationID)
+			if err == badger.ErrKeyNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			invitations = append(invitations, invitation)
+		}
+		return nil
+	})
+	return invitations, err
+}
+
+// DeleteInvitation removes a workspace's invitation and its indexes
+func (r *Repository) DeleteInvitation(workspaceID, invitationID string) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		invitation, err := r.getInvitation(txn, invitationID)
+		if err != nil {
+			return err
+		}
+		if invitation.WorkspaceID != workspaceID {
+			return badger.ErrKeyNotFound
+		}
+
+		keys := []string{
+			fmt.Sprintf("workspace_invite:%s", invitation.ID),
+			fmt.Sprintf("workspace_invite_hash:%s", invitation.TokenHash),
+			fmt.Sprintf("workspace_invites:%s:%s", invitation.WorkspaceID, invitation.ID),
+		}
+		for _, key := range keys {
+			if err := txn.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Repository) getInvitation(txn *badger.Txn, invitationID string) (*Invitation, error) {
+	item, err := txn.Get([]byte(fmt.Sprintf("workspace_invite:%s", invitationID)))
+	if err != nil {
+		return nil, err
+	}
+
+	var invitation Invitation
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &invitation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// SaveSigningKey stores a JWT signing key. A superseded key expires from the
+// store once tokens it signed can no longer be valid.
+func (r *Repository) SaveSigningKey(key *SigningKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry([]byte(fmt.Sprintf("jwt_signing_key:%s", key.ID)), data)
+		if key.ExpiresAt != nil {
+			ttl := time.Until(*key.ExpiresAt)
+			if ttl <= 0 {
+				return txn.Delete(entry.Key)
+			}
+			entry = entry.WithTTL(ttl)
+		}
+		return txn.SetEntry(entry)
+	})
+}
+
+// GetSigningKeys returns every stored JWT signing key that has not expired
+func (r *Repository) GetSigningKeys() ([]*SigningKey, error) {
+	var keys []*SigningKey
+	err := r.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("jwt_signing_key:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var key SigningKey
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &key)
+			})
+			if err != nil {
+				return err
+			}
+			keys = append(keys, &key)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// SaveOIDCLoginState stores a pending identity provider sign-in until it
+// expires
+func (r *Repository) SaveOIDCLoginState(state *OIDCLoginState) error {
+	ttl := time.Until(state.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("sign-in state already expired")
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		key := fmt.Sprintf("oidc_state:%s", state.StateHash)
+		return txn.SetEntry(badger.NewEntry([]byte(key), data).WithTTL(ttl))
+	})
+}
+
+// ConsumeOIDCLoginState returns the sign-in stored under the hash and deletes
+// it, so each state is used once. It returns nil for unknown, used or expired
+// states.
+func (r *Repository) ConsumeOIDCLoginState(stateHash string) (*OIDCLoginState, error) {
+	var state OIDCLoginState
+	err := r.db.Update(func(txn *badger.Txn) error {
+		key := []byte(fmt.Sprintf("oidc_state:%s", stateHash))
+		item, err := txn.Get(key)
 		if err != nil {
 			return err
 		}
 
-		var sessionID string
 		err = item.Value(func(val []byte) error {
-			sessionID = string(val)
-			return nil
+			return json.Unmarshal(val, &state)
 		})
 		if err != nil {
 			return err
 		}
+		return txn.Delete(key)
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(state.ExpiresAt) {
+		return nil, nil
+	}
+	return &state, nil
+}
 
-		return r.InvalidateSession(sessionID)
+// GetOIDCIdentity returns the identity linked for a provider's subject, or
+// nil if none is
+func (r *Repository) GetOIDCIdentity(provider, subject string) (*OIDCIdentity, error) {
+	var identity OIDCIdentity
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(fmt.Sprintf("oidc_identity:%s:%s", provider, subject)))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &identity)
+		})
 	})
-}
\ No newline at end of file
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *Repository) SaveOIDCIdentity(identity *OIDCIdentity) error {
+	data, err := json.Marshal(identity)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		key := fmt.Sprintf("oidc_identity:%s:%s", identity.Provider, identity.Subject)
+		return txn.Set([]byte(key), data)
+	})
+}
@@ -12,6 +12,23 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/verify", h.VerifyToken)
 	mux.HandleFunc("/profile", h.GetProfile)
 	mux.HandleFunc("/refresh", h.RefreshToken)
+	mux.HandleFunc("GET /sessions", h.ListSessions)
+	mux.HandleFunc("DELETE /sessions", h.RevokeSession)
+	mux.HandleFunc("/verify-email", h.VerifyEmail)
+	mux.HandleFunc("POST /verify-email/resend", h.ResendVerification)
+	mux.HandleFunc("POST /password/forgot", h.ForgotPassword)
+	mux.HandleFunc("POST /password/reset", h.ResetPassword)
+	mux.HandleFunc("POST /password/change", h.ChangePassword)
+	mux.HandleFunc("POST /login/2fa", h.CompleteTwoFactorLogin)
+	mux.HandleFunc("/unlock", h.UnlockAccount)
+	mux.HandleFunc("GET /2fa", h.TwoFactorStatus)
+	mux.HandleFunc("POST /2fa/enroll", h.EnrollTOTP)
+	mux.HandleFunc("POST /2fa/confirm", h.ConfirmTOTP)
+	mux.HandleFunc("POST /2fa/disable", h.DisableTwoFactor)
+	mux.HandleFunc("POST /2fa/recovery-codes", h.RegenerateRecoveryCodes)
+	mux.HandleFunc("GET /tokens", h.ListAPITokens)
+	mux.HandleFunc("POST /tokens", h.CreateAPIToken)
+	mux.HandleFunc("DELETE /tokens", h.RevokeAPIToken)
 
 	log.Println("Auth API routes configured")
 }
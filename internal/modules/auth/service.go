@@ -2,33 +2,57 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// AccessTokenTTL bounds how long a JWT stays usable without a refresh
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a refresh token stays valid if unused
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrAccountDeactivated  = errors.New("account is deactivated")
 )
 
 type Service struct {
-	repo      *Repository
-	jwtSecret []byte
-	eventBus  EventPublisher
+	repo     *Repository
+	keys     *keyRing
+	eventBus EventPublisher
+	mailer   MailSender
+	appURL   string
+	oidc     map[string]*oidcProvider
 }
 
 type EventPublisher interface {
 	Publish(event string, data any) error
 }
 
-func NewService(repo *Repository, jwtSecret string, eventBus EventPublisher) *Service {
+// NewService creates the auth service. legacySecret, when set, still verifies
+// HS256 tokens signed before signing keys were stored; call LoadSigningKeys
+// before issuing tokens.
+func NewService(repo *Repository, legacySecret string, eventBus EventPublisher) *Service {
 	return &Service{
-		repo:      repo,
-		jwtSecret: []byte(jwtSecret),
-		eventBus:  eventBus,
+		repo:     repo,
+		keys:     &keyRing{legacy: []byte(legacySecret)},
+		eventBus: eventBus,
+		mailer:   NewLogMailSender(),
 	}
 }
 
-func (s *Service) Register(req RegisterRequest) (*LoginResponse, error) {
+func (s *Service) Register(req RegisterRequest, device Device) (*LoginResponse, error) {
 	log.Printf("🔐 Registering new user: %s", req.Email)
 
 	user := &User{
@@ -44,9 +68,9 @@ func (s *Service) Register(req RegisterRequest) (*LoginResponse, error) {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	token, err := s.GenerateJWT(user)
+	response, err := s.startSession(user, device)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
 	s.eventBus.Publish("user.registered", map[string]any{
@@ -55,100 +79,151 @@ func (s *Service) Register(req RegisterRequest) (*LoginResponse, error) {
 		"username": user.Username,
 	})
 
-	user.Password = ""
-	return &LoginResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	if err := s.sendVerificationEmail(user); err != nil {
+		log.Printf("Warning: Failed to start email verification for %s: %v", user.Email, err)
+	}
+
+	return response, nil
 }
 
-func (s *Service) Login(req LoginRequest) (*LoginResponse, error) {
+func (s *Service) Login(req LoginRequest, device Device) (*LoginResponse, error) {
 	log.Printf("🔐 User login attempt: %s", req.Email)
 
+	if err := s.checkLoginAllowed(req.Email, device.IPAddress); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.GetUserByEmail(req.Email)
 	if err != nil {
+		s.recordLoginFailure(req.Email, device.IPAddress)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	// Check the password first so a deactivated account only reveals
+	// itself to someone who knows it, and guesses still count
+	if !user.VerifyPassword(req.Password) {
+		s.recordLoginFailure(req.Email, device.IPAddress)
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
 	if !user.IsActive {
-		return nil, fmt.Errorf("account is deactivated")
+		return nil, ErrAccountDeactivated
 	}
 
-	if !user.VerifyPassword(req.Password) {
-		return nil, fmt.Errorf("invalid credentials")
+	challenge, err := s.twoFactorChallenge(user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return &LoginResponse{Challenge: challenge}, nil
 	}
 
-	token, err := s.GenerateJWT(user)
+	response, err := s.startSession(user, device)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
+	s.clearLoginFailures(user.Email)
 
 	s.eventBus.Publish("user.logged_in", map[string]any{
 		"user_id": user.ID,
 		"email":   user.Email,
 	})
 
-	user.Password = ""
-	return &LoginResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	return response, nil
 }
 
+// VerifyToken validates an access token and checks its jti against the
+// revocation list. All auth middleware goes through here.
 func (s *Service) VerifyToken(tokenString string) (*AuthClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.MapClaims{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.jwtSecret, nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		return s.keys.verification(kid, token.Method.Alg())
+	}, jwt.WithValidMethods([]string{AlgorithmHS256, AlgorithmEdDSA, AlgorithmRS256}))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*jwt.MapClaims); ok && token.Valid {
-		userID, _ := (*claims)["user_id"].(string)
-		username, _ := (*claims)["username"].(string)
-		email, _ := (*claims)["email"].(string)
-		exp, _ := (*claims)["exp"].(float64)
-		iat, _ := (*claims)["iat"].(float64)
+	claims, ok := token.Claims.(*jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	userID, _ := (*claims)["user_id"].(string)
+	username, _ := (*claims)["username"].(string)
+	email, _ := (*claims)["email"].(string)
+	sessionID, _ := (*claims)["sid"].(string)
+	tokenID, _ := (*claims)["jti"].(string)
+	exp, _ := (*claims)["exp"].(float64)
+	iat, _ := (*claims)["iat"].(float64)
 
-		return &AuthClaims{
-			UserID:    userID,
-			Username:  username,
-			Email:     email,
-			ExpiresAt: int64(exp),
-			IssuedAt:  int64(iat),
-		}, nil
+	if sessionID == "" || tokenID == "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+	revoked, err := s.repo.IsTokenRevoked(tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, fmt.Errorf("token revoked")
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	return &AuthClaims{
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		SessionID: sessionID,
+		TokenID:   tokenID,
+		ExpiresAt: int64(exp),
+		IssuedAt:  int64(iat),
+	}, nil
 }
 
-func (s *Service) GenerateJWT(user *User) (string, error) {
+// GenerateJWT signs the access token issued with a session, identified by
+// the session's AccessTokenID, with the current signing key
+func (s *Service) GenerateJWT(user *User, session *Session) (string, error) {
+	key := s.keys.signing(time.Now())
+	if key == nil {
+		return "", fmt.Errorf("no signing key loaded")
+	}
+
 	claims := jwt.MapClaims{
 		"user_id":  user.ID,
 		"username": user.Username,
 		"email":    user.Email,
-		"exp":      time.Now().Add(24 * time.Hour).Unix(),
-		"iat":      time.Now().Unix(),
+		"sid":      session.ID,
+		"jti":      session.AccessTokenID,
+		"exp":      session.AccessExpiresAt.Unix(),
+		"iat":      session.CreatedAt.Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
 }
 
-func (s *Service) Logout(userID string) error {
+// Logout revokes the session family the access token belongs to, or every
+// session of the user if sessionID is empty
+func (s *Service) Logout(userID, sessionID string) error {
 	log.Printf("🔐 User logout: %s", userID)
 
-	err := s.repo.InvalidateUserSessions(userID)
-	if err != nil {
-		log.Printf("Warning: Failed to invalidate sessions for user %s: %v", userID, err)
+	if sessionID == "" {
+		if err := s.repo.InvalidateUserSessions(userID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+	} else {
+		session, err := s.repo.GetSession(sessionID)
+		if err != nil || session.UserID != userID {
+			return ErrSessionNotFound
+		}
+		if err := s.repo.InvalidateSessionFamily(userID, session.FamilyID); err != nil {
+			return fmt.Errorf("failed to revoke session: %w", err)
+		}
 	}
 
 	s.eventBus.Publish("user.logged_out", map[string]any{
-		"user_id": userID,
+		"user_id":    userID,
+		"session_id": sessionID,
 	})
 
 	return nil
@@ -163,29 +238,118 @@ func (s *Service) GetUserByID(id string) (*User, error) {
 	return user, nil
 }
 
-func (s *Service) RefreshToken(tokenString string) (string, error) {
-	claims, err := s.VerifyToken(tokenString)
+// RefreshToken exchanges a refresh token for a new access and refresh token
+// pair. The presented token is spent; presenting it again is treated as theft
+// and revokes every session of its family.
+func (s *Service) RefreshToken(refreshToken string, device Device) (*LoginResponse, error) {
+	session, err := s.repo.GetSessionByToken(hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if session.ReplacedBy != "" {
+		s.revokeFamily(session)
+		return nil, ErrRefreshTokenReused
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.repo.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+
+	nextToken, err := generateToken()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	next := newSession(user.ID, session.FamilyID, nextToken, device)
 
-	if time.Now().Unix() > claims.ExpiresAt-3600 {
-		user, err := s.repo.GetUserByID(claims.UserID)
-		if err != nil {
-			return "", err
+	if err := s.repo.RotateSession(session.ID, next); err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			s.revokeFamily(session)
+			return nil, err
 		}
+		return nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	return s.tokenResponse(user, next, nextToken)
+}
+
+// startSession opens a new session family for a fresh login
+func (s *Service) startSession(user *User, device Device) (*LoginResponse, error) {
+	refreshToken, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
 
-		return s.GenerateJWT(user)
+	session := newSession(user.ID, uuid.New().String(), refreshToken, device)
+	if err := s.repo.CreateSession(session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
-	return tokenString, nil
+	return s.tokenResponse(user, session, refreshToken)
 }
 
-func GenerateSecretKey() string {
-	key := make([]byte, 32)
-	_, err := rand.Read(key)
+func (s *Service) tokenResponse(user *User, session *Session, refreshToken string) (*LoginResponse, error) {
+	token, err := s.GenerateJWT(user, session)
 	if err != nil {
-		panic("Failed to generate secret key")
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	user.Password = ""
+	return &LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(AccessTokenTTL.Seconds()),
+		User:         *user,
+	}, nil
+}
+
+func (s *Service) revokeFamily(session *Session) {
+	log.Printf("🚨 Refresh token reuse for user %s, revoking session family %s", session.UserID, session.FamilyID)
+
+	if err := s.repo.InvalidateSessionFamily(session.UserID, session.FamilyID); err != nil {
+		log.Printf("Warning: Failed to revoke session family %s: %v", session.FamilyID, err)
+	}
+
+	s.eventBus.Publish("user.refresh_token_reused", map[string]any{
+		"user_id":   session.UserID,
+		"family_id": session.FamilyID,
+	})
+}
+
+// newSession prepares a session and the access token issued with it
+func newSession(userID, familyID, refreshToken string, device Device) *Session {
+	now := time.Now()
+	return &Session{
+		UserID:          userID,
+		FamilyID:        familyID,
+		TokenHash:       hashToken(refreshToken),
+		ExpiresAt:       now.Add(RefreshTokenTTL),
+		UserAgent:       device.UserAgent,
+		IPAddress:       device.IPAddress,
+		AccessTokenID:   uuid.New().String(),
+		AccessExpiresAt: now.Add(AccessTokenTTL),
+	}
+}
+
+// generateToken returns 32 random bytes, URL-safe encoded, for refresh and
+// emailed tokens
+func generateToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
 	}
-	return base64.StdEncoding.EncodeToString(key)
-}
\ No newline at end of file
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// hashToken is the stored form of a refresh token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"datastar-go/internal/shared/testutil"
+)
+
+var testDevice = Device{UserAgent: "test", IPAddress: "192.0.2.1"}
+
+func newTestService(t *testing.T) (*Service, *testutil.Publisher) {
+	t.Helper()
+	events := &testutil.Publisher{}
+	s := NewService(NewRepository(testutil.MemoryDB(t)), "", events)
+	if err := s.LoadSigningKeys(KeyConfig{}); err != nil {
+		t.Fatalf("LoadSigningKeys: %v", err)
+	}
+	return s, events
+}
+
+func registerUser(t *testing.T, s *Service, email string) *LoginResponse {
+	t.Helper()
+	username, _, _ := strings.Cut(email, "@")
+	response, err := s.Register(RegisterRequest{Email: email, Username: username, Password: "correct horse"}, testDevice)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return response
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	s, events := newTestService(t)
+	login := registerUser(t, s, "ann@example.com")
+
+	rotated, err := s.RefreshToken(login.RefreshToken, testDevice)
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if rotated.RefreshToken == login.RefreshToken {
+		t.Fatal("refresh returned the spent token")
+	}
+
+	if _, err := s.RefreshToken(login.RefreshToken, testDevice); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replayed refresh err = %v, want ErrRefreshTokenReused", err)
+	}
+	if len(events.Published("user.refresh_token_reused")) != 1 {
+		t.Error("reuse was not published")
+	}
+
+	// The thief and the victim lose the whole family
+	if _, err := s.RefreshToken(rotated.RefreshToken, testDevice); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh after reuse err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := s.VerifyToken(rotated.Token); err == nil {
+		t.Error("access token of the revoked family still verifies")
+	}
+}
+
+func TestRotateSessionRejectsSpentSession(t *testing.T) {
+	s, _ := newTestService(t)
+	login := registerUser(t, s, "ann@example.com")
+
+	session, err := s.repo.GetSessionByToken(hashToken(login.RefreshToken))
+	if err != nil {
+		t.Fatalf("GetSessionByToken: %v", err)
+	}
+	first := newSession(session.UserID, session.FamilyID, "next-1", testDevice)
+	if err := s.repo.RotateSession(session.ID, first); err != nil {
+		t.Fatalf("RotateSession: %v", err)
+	}
+
+	// A concurrent refresh with the same token loses the race
+	second := newSession(session.UserID, session.FamilyID, "next-2", testDevice)
+	if err := s.repo.RotateSession(session.ID, second); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("second RotateSession err = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := s.repo.GetSessionByToken(hashToken("next-2")); err == nil {
+		t.Error("losing rotation stored its session")
+	}
+}
+
+func TestLoginVerifiesPasswordBeforeDeactivation(t *testing.T) {
+	s, _ := newTestService(t)
+	registerUser(t, s, "ann@example.com")
+
+	user, err := s.repo.GetUserByEmail("ann@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	user.IsActive = false
+	if err := s.repo.UpdateUser(user); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	_, err = s.Login(LoginRequest{Email: "ann@example.com", Password: "wrong guess"}, testDevice)
+	if err == nil || errors.Is(err, ErrAccountDeactivated) {
+		t.Fatalf("wrong password err = %v, want invalid credentials", err)
+	}
+	attempts, err := s.repo.GetLoginAttempts(accountAttemptKey("ann@example.com"))
+	if err != nil {
+		t.Fatalf("GetLoginAttempts: %v", err)
+	}
+	if attempts.Failures != 1 {
+		t.Errorf("failures = %d, want the guess counted", attempts.Failures)
+	}
+
+	if _, err := s.Login(LoginRequest{Email: "ann@example.com", Password: "correct horse"}, testDevice); !errors.Is(err, ErrAccountDeactivated) {
+		t.Errorf("right password err = %v, want ErrAccountDeactivated", err)
+	}
+}
+
+func TestVerifyTokenRejectsRevokedJTI(t *testing.T) {
+	s, _ := newTestService(t)
+	ann := registerUser(t, s, "ann@example.com")
+	bob := registerUser(t, s, "bob@example.com")
+
+	claims, err := s.VerifyToken(ann.Token)
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if claims.TokenID == "" || claims.SessionID == "" {
+		t.Fatalf("claims without jti or sid: %+v", claims)
+	}
+
+	if err := s.repo.RevokeToken(claims.TokenID, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if _, err := s.VerifyToken(ann.Token); err == nil {
+		t.Error("revoked token still verifies")
+	}
+	if _, err := s.VerifyToken(bob.Token); err != nil {
+		t.Errorf("another user's token: %v", err)
+	}
+
+	// Logging out revokes the session's access token
+	bobClaims, err := s.VerifyToken(bob.Token)
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if err := s.Logout(bobClaims.UserID, bobClaims.SessionID); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := s.VerifyToken(bob.Token); err == nil {
+		t.Error("token still verifies after logout")
+	}
+}
+
+func TestVerifyTokenRequiresJTI(t *testing.T) {
+	s, _ := newTestService(t)
+	login := registerUser(t, s, "ann@example.com")
+
+	session := newSession(login.User.ID, "family", "refresh", testDevice)
+	session.ID = "session-1"
+	session.AccessTokenID = ""
+	token, err := s.GenerateJWT(&login.User, session)
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	if _, err := s.VerifyToken(token); err == nil {
+		t.Error("token without a jti verifies")
+	}
+}
+
+func TestRevokeTokenIgnoresExpiredTokens(t *testing.T) {
+	s, _ := newTestService(t)
+	if err := s.repo.RevokeToken("expired", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if revoked, err := s.repo.IsTokenRevoked("expired"); err != nil || revoked {
+		t.Errorf("expired token stored on the revocation list: %v, %v", revoked, err)
+	}
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// ListSessions returns the user's signed-in devices, most recently used
+// first. currentSessionID is the sid of the caller's access token and marks
+// the device making the request.
+func (s *Service) ListSessions(userID, currentSessionID string) ([]*SessionInfo, error) {
+	sessions, err := s.repo.GetUserSessions(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	currentFamily := ""
+	signedInAt := map[string]time.Time{}
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			currentFamily = session.FamilyID
+		}
+		if first, ok := signedInAt[session.FamilyID]; !ok || session.CreatedAt.Before(first) {
+			signedInAt[session.FamilyID] = session.CreatedAt
+		}
+	}
+
+	now := time.Now()
+	infos := []*SessionInfo{}
+	for _, session := range sessions {
+		if session.ReplacedBy != "" || session.RevokedAt != nil || now.After(session.ExpiresAt) {
+			continue
+		}
+		infos = append(infos, &SessionInfo{
+			ID:         session.FamilyID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			SignedInAt: signedInAt[session.FamilyID],
+			LastUsedAt: session.CreatedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.FamilyID == currentFamily,
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].LastUsedAt.After(infos[j].LastUsedAt)
+	})
+	return infos, nil
+}
+
+// RevokeSession signs a device out: its refresh token stops working and its
+// outstanding access token is put on the revocation list
+func (s *Service) RevokeSession(userID, familyID string) error {
+	sessions, err := s.repo.GetUserSessions(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	found := false
+	for _, session := range sessions {
+		if session.FamilyID == familyID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrSessionNotFound
+	}
+
+	if err := s.repo.InvalidateSessionFamily(userID, familyID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	s.eventBus.Publish("user.session_revoked", map[string]any{
+		"user_id":   userID,
+		"family_id": familyID,
+	})
+	log.Printf("🔐 Session revoked for user %s: %s", userID, familyID)
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JWKS publishes the public keys that verify access tokens so other services
+// can check them. The set is served bare, as JWKS clients expect.
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.service.JWKS())
+}
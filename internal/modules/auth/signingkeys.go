@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Access token signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+const (
+	// DefaultKeyRotation is how long a signing key signs tokens before the
+	// next one takes over
+	DefaultKeyRotation = 30 * 24 * time.Hour
+	// DefaultKeyOverlap is how long the next key is published before it
+	// signs, and how long a superseded key keeps verifying
+	DefaultKeyOverlap = 24 * time.Hour
+
+	rsaKeyBits = 2048
+)
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+// KeyConfig controls how access tokens are signed and how often the signing
+// key rotates. Zero values take the defaults.
+type KeyConfig struct {
+	Algorithm        string
+	RotationInterval time.Duration
+	Overlap          time.Duration
+}
+
+func (c *KeyConfig) normalize() error {
+	if c.Algorithm == "" {
+		c.Algorithm = AlgorithmHS256
+	}
+	switch c.Algorithm {
+	case AlgorithmHS256, AlgorithmEdDSA, AlgorithmRS256:
+	default:
+		return fmt.Errorf("unsupported JWT algorithm %q, use %s, %s or %s", c.Algorithm, AlgorithmHS256, AlgorithmEdDSA, AlgorithmRS256)
+	}
+
+	if c.RotationInterval == 0 {
+		c.RotationInterval = DefaultKeyRotation
+	}
+	if c.Overlap == 0 {
+		c.Overlap = DefaultKeyOverlap
+	}
+	if c.Overlap < AccessTokenTTL {
+		return fmt.Errorf("key overlap must be at least the %s access token lifetime", AccessTokenTTL)
+	}
+	if c.RotationInterval <= c.Overlap {
+		return fmt.Errorf("key rotation interval must be longer than the %s overlap", c.Overlap)
+	}
+	return nil
+}
+
+// keyRing holds the parsed signing keys, oldest first. The key that signs is
+// the newest one whose NotBefore has passed; newer keys are only published,
+// older ones only verify until they expire.
+type keyRing struct {
+	mu       sync.RWMutex
+	rotateMu sync.Mutex
+	config   KeyConfig
+	keys     []*loadedKey
+	// legacy verifies HS256 tokens issued without a kid by JWT_SECRET before
+	// signing keys were stored
+	legacy []byte
+}
+
+type loadedKey struct {
+	*SigningKey
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+func (k *loadedKey) expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// signing returns the key that signs tokens at now
+func (r *keyRing) signing(now time.Time) *loadedKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var current *loadedKey
+	for _, key := range r.keys {
+		if !key.NotBefore.After(now) && !key.expired(now) {
+			current = key
+		}
+	}
+	return current
+}
+
+// verification returns the key that checks a token signed with kid and alg
+func (r *keyRing) verification(kid, alg string) (any, error) {
+	if kid == "" {
+		if len(r.legacy) > 0 && alg == AlgorithmHS256 {
+			return r.legacy, nil
+		}
+		return nil, ErrUnknownSigningKey
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.ID != kid || key.expired(time.Now()) {
+			continue
+		}
+		if key.Algorithm != alg {
+			return nil, fmt.Errorf("unexpected signing method: %v", alg)
+		}
+		return key.verifyKey, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownSigningKey, kid)
+}
+
+func (r *keyRing) set(keys []*loadedKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].NotBefore.Before(keys[j].NotBefore)
+	})
+
+	r.mu.Lock()
+	r.keys = keys
+	r.mu.Unlock()
+}
+
+// LoadSigningKeys loads the stored signing keys, creating or rotating them
+// as the configuration requires. It must run before tokens are issued.
+func (s *Service) LoadSigningKeys(config KeyConfig) error {
+	if err := config.normalize(); err != nil {
+		return err
+	}
+	s.keys.config = config
+
+	if err := s.rotateSigningKeys(time.Now()); err != nil {
+		return err
+	}
+
+	current := s.keys.signing(time.Now())
+	log.Printf("🔑 Signing access tokens with %s key %s (rotates every %s)", current.Algorithm, current.ID, config.RotationInterval)
+	return nil
+}
+
+// StartKeyRotation checks for due signing key rotations until ctx is
+// cancelled, often enough to publish the next key a full overlap before it
+// signs
+func (s *Service) StartKeyRotation(ctx context.Context) {
+	interval := min(time.Hour, s.keys.config.Overlap/2)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := s.rotateSigningKeys(time.Now()); err != nil {
+				log.Printf("Signing key rotation failed: %v", err)
+			}
+		}
+	}()
+}
+
+// JWKS returns the public keys that verify access tokens, including the next
+// key once it is published. HS256 keys are secret and never listed.
+func (s *Service) JWKS() JWKSet {
+	s.keys.mu.RLock()
+	defer s.keys.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	now := time.Now()
+	for _, key := range s.keys.keys {
+		if key.expired(now) {
+			continue
+		}
+
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+		switch public := key.verifyKey.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// rotateSigningKeys brings the stored keys in line with the configuration:
+// it creates the first key, replaces a key of another algorithm at once, and
+// publishes the next key an overlap before the current one is due to retire
+func (s *Service) rotateSigningKeys(now time.Time) error {
+	s.keys.rotateMu.Lock()
+	defer s.keys.rotateMu.Unlock()
+
+	config := s.keys.config
+	stored, err := s.repo.GetSigningKeys()
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	var live []*SigningKey
+	var current, next *SigningKey
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].NotBefore.Before(stored[j].NotBefore)
+	})
+	for _, key := range stored {
+		if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+			continue
+		}
+		if key.NotBefore.After(now) {
+			if key.Algorithm != config.Algorithm {
+				// Published for an algorithm no longer in use; withdraw it
+				if err := s.expireSigningKey(key, now); err != nil {
+					return err
+				}
+				continue
+			}
+			next = key
+		} else {
+			current = key
+		}
+		live = append(live, key)
+	}
+
+	var created *SigningKey
+	switch {
+	case current == nil:
+		created, err = s.createSigningKey(config.Algorithm, now)
+	case current.Algorithm != config.Algorithm:
+		if created, err = s.createSigningKey(config.Algorithm, now); err == nil {
+			err = s.expireSigningKey(current, now.Add(config.Overlap))
+		}
+	case next == nil && !now.Before(current.NotBefore.Add(config.RotationInterval-config.Overlap)):
+		start := current.NotBefore.Add(config.RotationInterval)
+		if start.Before(now) {
+			start = now
+		}
+		if created, err = s.createSigningKey(config.Algorithm, start); err == nil {
+			err = s.expireSigningKey(current, start.Add(config.Overlap))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if created != nil {
+		live = append(live, created)
+	}
+
+	loaded := make([]*loadedKey, 0, len(live))
+	for _, key := range live {
+		parsed, err := parseSigningKey(key)
+		if err != nil {
+			return fmt.Errorf("failed to parse signing key %s: %w", key.ID, err)
+		}
+		loaded = append(loaded, parsed)
+	}
+	s.keys.set(loaded)
+	return nil
+}
+
+// createSigningKey generates and stores a key that signs from notBefore on
+func (s *Service) createSigningKey(algorithm string, notBefore time.Time) (*SigningKey, error) {
+	key := &SigningKey{
+		ID:        uuid.New().String(),
+		Algorithm: algorithm,
+		CreatedAt: time.Now(),
+		NotBefore: notBefore,
+	}
+
+	switch algorithm {
+	case AlgorithmHS256:
+		key.Secret = make([]byte, 32)
+		if _, err := rand.Read(key.Secret); err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		if key.PrivateKey, err = x509.MarshalPKCS8PrivateKey(private); err != nil {
+			return nil, fmt.Errorf("failed to encode signing key: %w", err)
+		}
+	case AlgorithmRS256:
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		if key.PrivateKey, err = x509.MarshalPKCS8PrivateKey(private); err != nil {
+			return nil, fmt.Errorf("failed to encode signing key: %w", err)
+		}
+	}
+
+	if err := s.repo.SaveSigningKey(key); err != nil {
+		return nil, fmt.Errorf("failed to store signing key: %w", err)
+	}
+
+	s.eventBus.Publish("auth.signing_key.created", map[string]any{
+		"key_id":     key.ID,
+		"algorithm":  key.Algorithm,
+		"not_before": key.NotBefore,
+	})
+	log.Printf("🔑 New %s signing key %s, signs from %s", key.Algorithm, key.ID, key.NotBefore.Format(time.RFC3339))
+	return key, nil
+}
+
+// expireSigningKey stops a key from verifying tokens after expiresAt
+func (s *Service) expireSigningKey(key *SigningKey, expiresAt time.Time) error {
+	key.ExpiresAt = &expiresAt
+	if err := s.repo.SaveSigningKey(key); err != nil {
+		return fmt.Errorf("failed to retire signing key: %w", err)
+	}
+	return nil
+}
+
+func parseSigningKey(key *SigningKey) (*loadedKey, error) {
+	loaded := &loadedKey{SigningKey: key}
+
+	if key.Algorithm == AlgorithmHS256 {
+		loaded.method = jwt.SigningMethodHS256
+		loaded.signKey = key.Secret
+		loaded.verifyKey = key.Secret
+		return loaded, nil
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := private.(type) {
+	case ed25519.PrivateKey:
+		if key.Algorithm != AlgorithmEdDSA {
+			break
+		}
+		loaded.method = jwt.SigningMethodEdDSA
+		loaded.signKey = private
+		loaded.verifyKey = private.Public()
+		return loaded, nil
+	case *rsa.PrivateKey:
+		if key.Algorithm != AlgorithmRS256 {
+			break
+		}
+		loaded.method = jwt.SigningMethodRS256
+		loaded.signKey = private
+		loaded.verifyKey = &private.PublicKey
+		return loaded, nil
+	}
+	return nil, fmt.Errorf("key does not match algorithm %s", key.Algorithm)
+}
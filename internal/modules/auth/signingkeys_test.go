@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"datastar-go/internal/shared/testutil"
+)
+
+func TestKeyRingVerification(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	ring := &keyRing{
+		legacy: []byte("legacy secret"),
+		keys: []*loadedKey{
+			{SigningKey: &SigningKey{ID: "old", Algorithm: AlgorithmHS256, NotBefore: now.Add(-2 * time.Hour), ExpiresAt: &expired}, verifyKey: []byte("old")},
+			{SigningKey: &SigningKey{ID: "current", Algorithm: AlgorithmHS256, NotBefore: now.Add(-time.Hour)}, verifyKey: []byte("current")},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		kid     string
+		alg     string
+		want    string
+		unknown bool
+	}{
+		{"current key", "current", AlgorithmHS256, "current", false},
+		{"legacy token", "", AlgorithmHS256, "legacy secret", false},
+		{"legacy kid with another algorithm", "", AlgorithmRS256, "", true},
+		{"expired key", "old", AlgorithmHS256, "", true},
+		{"unknown kid", "missing", AlgorithmHS256, "", true},
+		{"algorithm mismatch", "current", AlgorithmEdDSA, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := ring.verification(tc.kid, tc.alg)
+			if tc.want != "" {
+				if err != nil {
+					t.Fatalf("verification: %v", err)
+				}
+				if string(key.([]byte)) != tc.want {
+					t.Errorf("key = %q, want %q", key, tc.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("verification succeeded")
+			}
+			if errors.Is(err, ErrUnknownSigningKey) != tc.unknown {
+				t.Errorf("err = %v, unknown key = %v", err, tc.unknown)
+			}
+		})
+	}
+
+	// Without a legacy secret a token without a kid is never accepted
+	ring.legacy = nil
+	if _, err := ring.verification("", AlgorithmHS256); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("no legacy secret: err = %v, want ErrUnknownSigningKey", err)
+	}
+}
+
+func TestRotateSigningKeysOverlap(t *testing.T) {
+	s, _ := newTestService(t)
+	config := KeyConfig{Algorithm: AlgorithmEdDSA, RotationInterval: 10 * 24 * time.Hour, Overlap: 24 * time.Hour}
+	if err := s.LoadSigningKeys(config); err != nil {
+		t.Fatalf("LoadSigningKeys: %v", err)
+	}
+	login := registerUser(t, s, "ann@example.com")
+	first := s.keys.signing(time.Now())
+	start := first.NotBefore
+
+	// Before the overlap window nothing new is published
+	if err := s.rotateSigningKeys(start.Add(config.RotationInterval - config.Overlap - time.Minute)); err != nil {
+		t.Fatalf("rotateSigningKeys: %v", err)
+	}
+	if len(s.JWKS().Keys) != 1 {
+		t.Fatalf("published %d keys before the overlap, want 1", len(s.JWKS().Keys))
+	}
+
+	// Inside it the next key is published but the current one keeps signing
+	published := start.Add(config.RotationInterval - config.Overlap)
+	for range 2 {
+		if err := s.rotateSigningKeys(published); err != nil {
+			t.Fatalf("rotateSigningKeys: %v", err)
+		}
+	}
+	if len(s.JWKS().Keys) != 2 {
+		t.Fatalf("published %d keys in the overlap, want 2", len(s.JWKS().Keys))
+	}
+	if s.keys.signing(published).ID != first.ID {
+		t.Error("next key signs before its time")
+	}
+	handover := start.Add(config.RotationInterval)
+	next := s.keys.signing(handover)
+	if next.ID == first.ID {
+		t.Fatal("next key does not sign at the handover")
+	}
+
+	// The superseded key verifies for one overlap past the handover
+	old := s.keys.signing(handover.Add(-time.Second))
+	if old.ID != first.ID || old.ExpiresAt == nil || !old.ExpiresAt.Equal(handover.Add(config.Overlap)) {
+		t.Fatalf("old key expires at %v, want %v", old.ExpiresAt, handover.Add(config.Overlap))
+	}
+	if old.expired(handover.Add(config.Overlap-time.Second)) || !old.expired(handover.Add(config.Overlap)) {
+		t.Error("old key does not retire exactly one overlap after the handover")
+	}
+	if _, err := s.VerifyToken(login.Token); err != nil {
+		t.Errorf("token signed with the old key: %v", err)
+	}
+
+	// Rotating at the handover keeps both keys and adds none
+	if err := s.rotateSigningKeys(handover); err != nil {
+		t.Fatalf("rotateSigningKeys: %v", err)
+	}
+	if len(s.keys.keys) != 2 || s.keys.signing(handover).ID != next.ID {
+		t.Errorf("handover left %d keys", len(s.keys.keys))
+	}
+}
+
+func TestRotateSigningKeysAlgorithmChange(t *testing.T) {
+	db := testutil.MemoryDB(t)
+	s := NewService(NewRepository(db), "", &testutil.Publisher{})
+	if err := s.LoadSigningKeys(KeyConfig{Algorithm: AlgorithmEdDSA}); err != nil {
+		t.Fatalf("LoadSigningKeys: %v", err)
+	}
+	login := registerUser(t, s, "ann@example.com")
+
+	// A restart with another algorithm signs with a new key at once
+	restarted := NewService(NewRepository(db), "", &testutil.Publisher{})
+	if err := restarted.LoadSigningKeys(KeyConfig{Algorithm: AlgorithmRS256}); err != nil {
+		t.Fatalf("LoadSigningKeys: %v", err)
+	}
+	if alg := restarted.keys.signing(time.Now()).Algorithm; alg != AlgorithmRS256 {
+		t.Errorf("signing with %s, want %s", alg, AlgorithmRS256)
+	}
+	if _, err := restarted.VerifyToken(login.Token); err != nil {
+		t.Errorf("token signed before the change: %v", err)
+	}
+	if len(restarted.JWKS().Keys) != 2 {
+		t.Errorf("published %d keys, want the old and the new", len(restarted.JWKS().Keys))
+	}
+}
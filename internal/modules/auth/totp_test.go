@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateTOTPWindow(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret: %v", err)
+	}
+	now := time.Unix(1_700_000_010, 0)
+	current := now.Unix() / totpPeriod
+
+	cases := []struct {
+		name   string
+		offset int64
+		ok     bool
+	}{
+		{"two periods ago", -2, false},
+		{"previous period", -1, true},
+		{"current period", 0, true},
+		{"next period", 1, true},
+		{"two periods ahead", 2, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := totpCode(secret, current+tc.offset)
+			if err != nil {
+				t.Fatalf("totpCode: %v", err)
+			}
+			step, ok := validateTOTP(secret, code, now, 0)
+			if ok != tc.ok {
+				t.Fatalf("ok = %v, want %v", ok, tc.ok)
+			}
+			if ok && step != current+tc.offset {
+				t.Errorf("step = %d, want %d", step, current+tc.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsReplay(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret: %v", err)
+	}
+	now := time.Unix(1_700_000_010, 0)
+	current := now.Unix() / totpPeriod
+
+	code, err := totpCode(secret, current)
+	if err != nil {
+		t.Fatalf("totpCode: %v", err)
+	}
+	step, ok := validateTOTP(secret, code, now, 0)
+	if !ok {
+		t.Fatal("fresh code rejected")
+	}
+	if _, ok := validateTOTP(secret, code, now, step); ok {
+		t.Error("code accepted twice")
+	}
+
+	// Once a later code is used, an earlier one still inside the window is spent too
+	previous, err := totpCode(secret, current-1)
+	if err != nil {
+		t.Fatalf("totpCode: %v", err)
+	}
+	if _, ok := validateTOTP(secret, previous, now, step); ok {
+		t.Error("older code accepted after a newer one")
+	}
+
+	next, err := totpCode(secret, current+1)
+	if err != nil {
+		t.Fatalf("totpCode: %v", err)
+	}
+	if _, ok := validateTOTP(secret, next, now, step); !ok {
+		t.Error("next code rejected")
+	}
+}
+
+func TestValidateTOTPFormat(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret: %v", err)
+	}
+	now := time.Unix(1_700_000_010, 0)
+	code, err := totpCode(secret, now.Unix()/totpPeriod)
+	if err != nil {
+		t.Fatalf("totpCode: %v", err)
+	}
+
+	if _, ok := validateTOTP(secret, code[:3]+" "+code[3:], now, 0); !ok {
+		t.Error("code with a space rejected")
+	}
+	if _, ok := validateTOTP(secret, code[:5], now, 0); ok {
+		t.Error("short code accepted")
+	}
+	if _, ok := validateTOTP(secret, "", now, 0); ok {
+		t.Error("empty code accepted")
+	}
+}
@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	purposeLoginChallenge = "login_challenge"
+
+	// ChallengeTokenTTL is how long the second login step may take
+	ChallengeTokenTTL = 5 * time.Minute
+	// maxChallengeAttempts is how many wrong codes a challenge survives
+	maxChallengeAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+var (
+	ErrTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	ErrNoPendingEnrollment = errors.New("start enrollment first")
+	ErrInvalidCode         = errors.New("invalid authentication code")
+)
+
+// TwoFactorStatus reports whether the user has 2FA on and how many recovery
+// codes are left
+func (s *Service) TwoFactorStatus(userID string) (*TwoFactorStatus, error) {
+	state, err := s.repo.GetTwoFactorState(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get 2FA state: %w", err)
+	}
+	return &TwoFactorStatus{
+		Enabled:                state.Secret != "",
+		EnabledAt:              state.EnabledAt,
+		RecoveryCodesRemaining: len(state.RecoveryCodes),
+	}, nil
+}
+
+// BeginTOTPEnrollment creates a pending secret for an authenticator app. 2FA
+// is only switched on once ConfirmTOTPEnrollment sees a valid code from it.
+func (s *Service) BeginTOTPEnrollment(userID string) (*TOTPEnrollment, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	state, err := s.repo.GetTwoFactorState(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get 2FA state: %w", err)
+	}
+	if state.Secret != "" {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	state.PendingSecret = secret
+	if err := s.repo.SaveTwoFactorState(state); err != nil {
+		return nil, fmt.Errorf("failed to save 2FA state: %w", err)
+	}
+
+	uri := totpURI(secret, user.Email)
+	qr, err := totpQRCode(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{Secret: secret, URI: uri, QRCode: qr}, nil
+}
+
+// ConfirmTOTPEnrollment enables 2FA with the pending secret and returns the
+// recovery codes. They are only ever shown here.
+func (s *Service) ConfirmTOTPEnrollment(userID, code string) ([]string, error) {
+	state, err := s.repo.GetTwoFactorState(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get 2FA state: %w", err)
+	}
+	if state.Secret != "" {
+		return nil, ErrTwoFactorEnabled
+	}
+	if state.PendingSecret == "" {
+		return nil, ErrNoPendingEnrollment
+	}
+
+	step, ok := validateTOTP(state.PendingSecret, code, time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state.Secret = state.PendingSecret
+	state.PendingSecret = ""
+	state.RecoveryCodes = hashes
+	state.LastUsedStep = step
+	state.EnabledAt = &now
+	if err := s.repo.SaveTwoFactorState(state); err != nil {
+		return nil, fmt.Errorf("failed to save 2FA state: %w", err)
+	}
+	if err := s.setTwoFactorFlag(userID, true); err != nil {
+		return nil, err
+	}
+
+	s.eventBus.Publish("user.2fa_enabled", map[string]any{
+		"user_id": userID,
+	})
+	log.Printf("🔐 2FA enabled for user %s", userID)
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a current
+// code or recovery code
+func (s *Service) DisableTwoFactor(userID, password, code string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if !user.VerifyPassword(password) {
+		return ErrIncorrectPassword
+	}
+
+	state, err := s.repo.GetTwoFactorState(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get 2FA state: %w", err)
+	}
+	if state.Secret == "" {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := s.checkSecondFactor(state, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.SaveTwoFactorState(&TwoFactorState{UserID: userID}); err != nil {
+		return fmt.Errorf("failed to save 2FA state: %w", err)
+	}
+	if err := s.setTwoFactorFlag(userID, false); err != nil {
+		return err
+	}
+
+	s.eventBus.Publish("user.2fa_disabled", map[string]any{
+		"user_id": userID,
+	})
+	s.sendMail(user, "Two-factor authentication turned off", fmt.Sprintf(
+		"Hi %s,\n\nTwo-factor authentication was turned off for your account. If this wasn't you, reset your password now.", user.Username))
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a code
+func (s *Service) RegenerateRecoveryCodes(userID, code string) ([]string, error) {
+	state, err := s.repo.GetTwoFactorState(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get 2FA state: %w", err)
+	}
+	if state.Secret == "" {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.checkSecondFactor(state, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	state.RecoveryCodes = hashes
+	if err := s.repo.SaveTwoFactorState(state); err != nil {
+		return nil, fmt.Errorf("failed to save 2FA state: %w", err)
+	}
+
+	s.eventBus.Publish("user.recovery_codes_regenerated", map[string]any{
+		"user_id": userID,
+	})
+	return codes, nil
+}
+
+// CompleteTwoFactorLogin exchanges a login challenge and a TOTP or recovery
+// code for tokens. A challenge allows a few wrong codes before it is dropped.
+func (s *Service) CompleteTwoFactorLogin(challengeToken, code string, device Device) (*LoginResponse, error) {
+	challengeHash := hashToken(challengeToken)
+	challenge, err := s.repo.GetActionToken(purposeLoginChallenge, challengeHash)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	user, err := s.repo.GetUserByID(challenge.UserID)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+	if err := s.checkLoginAllowed(user.Email, device.IPAddress); err != nil {
+		return nil, err
+	}
+
+	state, err := s.repo.GetTwoFactorState(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get 2FA state: %w", err)
+	}
+	if err := s.checkSecondFactor(state, code); err != nil {
+		if _, failErr := s.repo.FailActionToken(purposeLoginChallenge, challengeHash, maxChallengeAttempts); failErr != nil {
+			log.Printf("Warning: Failed to record 2FA attempt: %v", failErr)
+		}
+		s.recordLoginFailure(user.Email, device.IPAddress)
+		s.eventBus.Publish("user.2fa_failed", map[string]any{
+			"user_id": user.ID,
+		})
+		return nil, err
+	}
+
+	if _, err := s.repo.ConsumeActionToken(purposeLoginChallenge, challengeHash); err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	response, err := s.startSession(user, device)
+	if err != nil {
+		return nil, err
+	}
+	s.clearLoginFailures(user.Email)
+
+	s.eventBus.Publish("user.logged_in", map[string]any{
+		"user_id":    user.ID,
+		"email":      user.Email,
+		"two_factor": true,
+	})
+
+	return response, nil
+}
+
+// twoFactorChallenge starts the second login step if the user has 2FA on,
+// returning nil otherwise
+func (s *Service) twoFactorChallenge(user *User) (*TwoFactorChallenge, error) {
+	if !user.TwoFactorEnabled {
+		return nil, nil
+	}
+
+	token, err := s.issueActionToken(user, purposeLoginChallenge, ChallengeTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int64(ChallengeTokenTTL.Seconds()),
+	}, nil
+}
+
+// checkSecondFactor accepts a current TOTP code or an unused recovery code,
+// saving the state so neither can be used twice
+func (s *Service) checkSecondFactor(state *TwoFactorState, code string) error {
+	if step, ok := validateTOTP(state.Secret, code, time.Now(), state.LastUsedStep); ok {
+		state.LastUsedStep = step
+		if err := s.repo.SaveTwoFactorState(state); err != nil {
+			return fmt.Errorf("failed to save 2FA state: %w", err)
+		}
+		return nil
+	}
+
+	hash := hashToken(normalizeRecoveryCode(code))
+	for i, stored := range state.RecoveryCodes {
+		if stored != hash {
+			continue
+		}
+		state.RecoveryCodes = append(state.RecoveryCodes[:i], state.RecoveryCodes[i+1:]...)
+		if err := s.repo.SaveTwoFactorState(state); err != nil {
+			return fmt.Errorf("failed to save 2FA state: %w", err)
+		}
+		s.eventBus.Publish("user.recovery_code_used", map[string]any{
+			"user_id":   state.UserID,
+			"remaining": len(state.RecoveryCodes),
+		})
+		return nil
+	}
+
+	return ErrInvalidCode
+}
+
+func (s *Service) setTwoFactorFlag(userID string, enabled bool) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	user.TwoFactorEnabled = enabled
+	if err := s.repo.UpdateUser(user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// newRecoveryCodes returns fresh recovery codes and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// CompleteTwoFactorLogin is the second login step for accounts with 2FA
+func (h *Handler) CompleteTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, "Challenge token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.service.CompleteTwoFactorLogin(req.ChallengeToken, req.Code, deviceFromRequest(r))
+	if err != nil {
+		log.Printf("2FA login failed: %v", err)
+		if writeLocked(w, err) {
+			return
+		}
+		http.Error(w, "Invalid or expired code", http.StatusUnauthorized)
+		return
+	}
+
+	setAuthCookies(w, response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    response,
+	})
+}
+
+// TwoFactorStatus reports the signed-in user's 2FA status
+func (h *Handler) TwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.service.TwoFactorStatus(userID)
+	if err != nil {
+		http.Error(w, err.Error(), twoFactorErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// EnrollTOTP starts authenticator app setup, returning the secret, otpauth
+// URI and QR code
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.service.BeginTOTPEnrollment(userID)
+	if err != nil {
+		http.Error(w, err.Error(), twoFactorErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    enrollment,
+	})
+}
+
+// ConfirmTOTP enables 2FA with a first code and returns the recovery codes
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.service.ConfirmTOTPEnrollment(userID, req.Code)
+	if err != nil {
+		http.Error(w, err.Error(), twoFactorErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    map[string]any{"recovery_codes": codes},
+		"message": "Two-factor authentication enabled. Store the recovery codes somewhere safe.",
+	})
+}
+
+// DisableTwoFactor turns 2FA off given the password and a code
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DisableTwoFactor(userID, req.Password, req.Code); err != nil {
+		http.Error(w, err.Error(), twoFactorErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes given a code
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		http.Error(w, err.Error(), twoFactorErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    map[string]any{"recovery_codes": codes},
+	})
+}
+
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidCode):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrIncorrectPassword):
+		return http.StatusForbidden
+	case errors.Is(err, ErrTwoFactorEnabled), errors.Is(err, ErrTwoFactorNotEnabled), errors.Is(err, ErrNoPendingEnrollment):
+		return http.StatusConflict
+	default:
+		log.Printf("2FA request failed: %v", err)
+		return http.StatusInternalServerError
+	}
+}
@@ -7,22 +7,102 @@ import (
 )
 
 type User struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	IsActive  bool      `json:"is_active"`
+	ID               string     `json:"id"`
+	Email            string     `json:"email"`
+	Username         string     `json:"username"`
+	Password         string     `json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	IsActive         bool       `json:"is_active"`
+	EmailVerified    bool       `json:"email_verified"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+}
+
+// TwoFactorState holds a user's TOTP secret and hashed recovery codes. It is
+// kept apart from User so the secrets never reach a JSON response.
+type TwoFactorState struct {
+	UserID        string     `json:"user_id"`
+	Secret        string     `json:"secret,omitempty"`
+	PendingSecret string     `json:"pending_secret,omitempty"`
+	RecoveryCodes []string   `json:"recovery_codes,omitempty"`
+	LastUsedStep  int64      `json:"last_used_step"`
+	EnabledAt     *time.Time `json:"enabled_at,omitempty"`
+}
+
+// TOTPEnrollment is shown once while setting up an authenticator app
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode string `json:"qr_code"`
+}
+
+// TwoFactorStatus summarizes a user's second factor without its secrets
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
 }
 
+// TwoFactorChallenge is returned by Login instead of tokens when the account
+// has 2FA enabled; the challenge token is exchanged with a code for tokens
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// Session backs one refresh token. Every refresh rotates the session into a
+// new one of the same family; only the hash of the refresh token is stored.
 type Session struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
-	IsActive  bool      `json:"is_active"`
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	FamilyID   string     `json:"family_id"`
+	TokenHash  string     `json:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	IsActive   bool       `json:"is_active"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	ReplacedBy string     `json:"replaced_by,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	IPAddress  string     `json:"ip_address,omitempty"`
+	// AccessTokenID is the jti of the access token issued with the session,
+	// added to the revocation list when the session is revoked
+	AccessTokenID   string    `json:"access_token_id"`
+	AccessExpiresAt time.Time `json:"access_expires_at"`
+}
+
+// Device identifies where a session was signed in from
+type Device struct {
+	UserAgent string
+	IPAddress string
+}
+
+// SessionInfo describes one signed-in device: a session family as seen by
+// its owner
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
 }
 
 type LoginRequest struct {
@@ -36,17 +116,217 @@ type RegisterRequest struct {
 	Password string `json:"password" validate:"required,min=6"`
 }
 
-type LoginResponse struct {
+// ActionToken is a single-use token mailed to a user to prove control of
+// their email address. Only the hash of the token is stored.
+type ActionToken struct {
+	UserID    string    `json:"user_id"`
+	Purpose   string    `json:"purpose"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Attempts  int       `json:"attempts,omitempty"`
+}
+
+// LoginAttempts counts recent failed logins for an account or an IP
+type LoginAttempts struct {
+	Failures    int        `json:"failures"`
+	LastFailure time.Time  `json:"last_failure"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+type UnlockRequest struct {
+	Token string `json:"token"`
+}
+
+type VerifyEmailRequest struct {
 	Token string `json:"token"`
-	User  User   `json:"user"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// APIToken is a personal access token for scripts and integrations. Only the
+// hash of the token is stored; the plaintext is shown once at creation.
+type APIToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `json:"token_hash,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// CreateAPITokenResponse carries the plaintext token, returned only once
+type CreateAPITokenResponse struct {
+	Token    string    `json:"token"`
+	APIToken *APIToken `json:"api_token"`
+}
+
+// Workspace owns the clients, projects, time, expenses and invoices its
+// members share. Every user has a personal workspace whose ID is their user ID.
+type Workspace struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   string    `json:"owner_id"`
+	Personal  bool      `json:"personal"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WorkspaceSummary is a workspace with the caller's role in it
+type WorkspaceSummary struct {
+	Workspace
+	Role string `json:"role"`
+}
+
+// Membership gives a user a role in a workspace
+type Membership struct {
+	WorkspaceID string    `json:"workspace_id"`
+	UserID      string    `json:"user_id"`
+	Email       string    `json:"email"`
+	Username    string    `json:"username"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joined_at"`
+
+	// TwoFactorEnabled is filled in from the user when an owner or admin
+	// lists the members; it is never stored
+	TwoFactorEnabled *bool `json:"two_factor_enabled,omitempty"`
+}
+
+// Invitation asks the owner of an email address to join a workspace. Only the
+// hash of the emailed token is stored.
+type Invitation struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	InvitedBy   string    `json:"invited_by"`
+	TokenHash   string    `json:"token_hash,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type SwitchWorkspaceRequest struct {
+	WorkspaceID string `json:"workspace_id"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UpdateMemberRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+type LoginResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	User         User   `json:"user"`
+	// Challenge is set instead of the tokens when a second factor is needed
+	Challenge *TwoFactorChallenge `json:"-"`
+}
+
+// SigningKey signs and verifies access tokens. HS256 keys hold a shared
+// secret; EdDSA and RS256 keys hold a PKCS #8 private key whose public half
+// is published in the JWKS. Key material never leaves the auth module.
+type SigningKey struct {
+	ID         string     `json:"id"`
+	Algorithm  string     `json:"algorithm"`
+	Secret     []byte     `json:"secret,omitempty"`
+	PrivateKey []byte     `json:"private_key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	NotBefore  time.Time  `json:"not_before"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// JWK is the public half of a signing key in RFC 7517 form
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// OIDCIdentity links an account at an OpenID Connect provider, identified by
+// its subject, to a local user
+type OIDCIdentity struct {
+	Provider    string    `json:"provider"`
+	Subject     string    `json:"subject"`
+	UserID      string    `json:"user_id"`
+	Email       string    `json:"email"`
+	LinkedAt    time.Time `json:"linked_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// OIDCLoginState remembers a sign-in sent to an identity provider until it
+// redirects back. It is stored under the hash of the state parameter.
+type OIDCLoginState struct {
+	Provider     string    `json:"provider"`
+	StateHash    string    `json:"state_hash"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	ReturnTo     string    `json:"return_to"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// OIDCProviderInfo is an identity provider users can sign in with
+type OIDCProviderInfo struct {
+	Name     string `json:"name"`
+	LoginURL string `json:"login_url"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type AuthClaims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	IssuedAt int64  `json:"iat"`
-	ExpiresAt int64 `json:"exp"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid"`
+	TokenID   string `json:"jti"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
 }
 
 func (u *User) HashPassword(password string) error {
@@ -61,4 +341,4 @@ func (u *User) HashPassword(password string) error {
 func (u *User) VerifyPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
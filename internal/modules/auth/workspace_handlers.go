@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"datastar-go/internal/shared/types"
+)
+
+// workspaceCookie remembers the workspace a browser session works in
+const workspaceCookie = "workspace_id"
+
+// ListWorkspaces lists the workspaces the signed-in user belongs to
+func (h *Handler) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaces, err := h.service.ListWorkspaces(actor.UserID)
+	if err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    workspaces,
+		"current": actor.WorkspaceID,
+	})
+}
+
+// CreateWorkspace creates a shared workspace owned by the signed-in user
+func (h *Handler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	workspace, err := h.service.CreateWorkspace(actor.UserID, req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    workspace,
+	})
+}
+
+// SwitchWorkspace makes the browser session work in another workspace the
+// user belongs to. API clients send the X-Workspace-ID header instead.
+func (h *Handler) SwitchWorkspace(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req SwitchWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	next, err := h.service.ResolveActor(actor.UserID, req.WorkspaceID)
+	if err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     workspaceCookie,
+		Value:    next.WorkspaceID,
+		Path:     "/",
+		MaxAge:   int(RefreshTokenTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    next,
+	})
+}
+
+// ListMembers lists the members of the current workspace
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	members, err := h.service.ListMembers(actor)
+	if err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    members,
+	})
+}
+
+// UpdateMember changes a member's role in the current workspace
+func (h *Handler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.service.UpdateMemberRole(actor, req.UserID, req.Role)
+	if err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    member,
+	})
+}
+
+// RemoveMember removes the member given by ?user_id= from the current
+// workspace; members pass their own ID to leave
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	memberID := r.URL.Query().Get("user_id")
+	if memberID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RemoveMember(actor, memberID); err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Member removed",
+	})
+}
+
+// ListInvitations lists the pending invitations of the current workspace
+func (h *Handler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	invitations, err := h.service.ListInvitations(actor)
+	if err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    invitations,
+	})
+}
+
+// InviteMember emails an invitation to join the current workspace
+func (h *Handler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req InviteMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.service.InviteMember(actor, req)
+	if err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    invitation,
+		"message": "Invitation sent to " + invitation.Email,
+	})
+}
+
+// RevokeInvitation withdraws the invitation given by ?id=
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	invitationID := r.URL.Query().Get("id")
+	if invitationID == "" {
+		http.Error(w, "Invitation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeInvitation(actor, invitationID); err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Invitation revoked",
+	})
+}
+
+// AcceptInvitation joins the signed-in user to a workspace. The emailed link
+// opens it with GET ?token=; API clients POST the token.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	actor := GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if r.Method == http.MethodPost {
+		var req AcceptInvitationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		token = req.Token
+	}
+	if token == "" {
+		http.Error(w, "Invitation token is required", http.StatusBadRequest)
+		return
+	}
+
+	workspace, err := h.service.AcceptInvitation(actor.UserID, token)
+	if err != nil {
+		http.Error(w, err.Error(), workspaceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    workspace,
+		"message": "Joined " + workspace.Name,
+	})
+}
+
+func workspaceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidWorkspaceRequest), errors.Is(err, ErrInvalidInvitation):
+		return http.StatusBadRequest
+	case errors.Is(err, types.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrWorkspaceNotFound), errors.Is(err, ErrMemberNotFound), errors.Is(err, ErrInvitationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAlreadyMember):
+		return http.StatusConflict
+	default:
+		log.Printf("Workspace request failed: %v", err)
+		return http.StatusInternalServerError
+	}
+}
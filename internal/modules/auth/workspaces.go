@@ -0,0 +1,439 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+
+	"github.com/google/uuid"
+)
+
+// InvitationTTL is how long an emailed workspace invitation can be accepted
+const InvitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidWorkspaceRequest = errors.New("invalid workspace request")
+	ErrWorkspaceNotFound       = errors.New("workspace not found")
+	ErrMemberNotFound          = errors.New("member not found")
+	ErrAlreadyMember           = errors.New("already a member of this workspace")
+	ErrInvalidInvitation       = errors.New("invalid or expired invitation")
+	ErrInvitationNotFound      = errors.New("invitation not found")
+)
+
+// ResolveActor returns the user acting in workspaceID with their role there.
+// An empty workspaceID selects the user's personal workspace, which they
+// always own.
+func (s *Service) ResolveActor(userID, workspaceID string) (types.Actor, error) {
+	if workspaceID == "" || workspaceID == userID {
+		return types.PersonalActor(userID), nil
+	}
+
+	membership, err := s.repo.GetMembership(workspaceID, userID)
+	if err != nil {
+		return types.Actor{}, fmt.Errorf("failed to get membership: %w", err)
+	}
+	if membership == nil {
+		return types.Actor{}, fmt.Errorf("%w: not a member of workspace %s", types.ErrForbidden, workspaceID)
+	}
+	return types.Actor{UserID: userID, WorkspaceID: workspaceID, Role: membership.Role}, nil
+}
+
+// ListWorkspaces returns the workspaces the user belongs to, personal first
+func (s *Service) ListWorkspaces(userID string) ([]*WorkspaceSummary, error) {
+	if _, err := s.personalWorkspace(userID); err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.repo.GetUserMemberships(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memberships: %w", err)
+	}
+
+	summaries := []*WorkspaceSummary{}
+	for _, membership := range memberships {
+		workspace, err := s.repo.GetWorkspace(membership.WorkspaceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workspace: %w", err)
+		}
+		if workspace == nil {
+			continue
+		}
+		summaries = append(summaries, &WorkspaceSummary{Workspace: *workspace, Role: membership.Role})
+	}
+
+	slices.SortFunc(summaries, func(a, b *WorkspaceSummary) int {
+		if a.Personal != b.Personal {
+			if a.Personal {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return summaries, nil
+}
+
+// CreateWorkspace creates a shared workspace owned by the user
+func (s *Service) CreateWorkspace(userID, name string) (*WorkspaceSummary, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidWorkspaceRequest)
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	now := time.Now()
+	workspace := &Workspace{
+		ID:        uuid.New().String(),
+		Name:      name,
+		OwnerID:   user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateWorkspace(workspace, newMembership(workspace.ID, user, types.RoleOwner)); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	s.eventBus.Publish("workspace.created", map[string]any{
+		"workspace_id": workspace.ID,
+		"user_id":      user.ID,
+		"name":         workspace.Name,
+	})
+	log.Printf("🏢 Workspace created by %s: %s", user.Username, workspace.Name)
+
+	return &WorkspaceSummary{Workspace: *workspace, Role: types.RoleOwner}, nil
+}
+
+// ListMembers returns the members of the actor's workspace. Owners and admins
+// also see whether each member has two-factor authentication turned on.
+func (s *Service) ListMembers(actor types.Actor) ([]*Membership, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	if _, err := s.actorWorkspace(actor); err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.GetWorkspaceMembers(actor.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	slices.SortFunc(members, func(a, b *Membership) int {
+		return a.JoinedAt.Compare(b.JoinedAt)
+	})
+
+	if actor.CanManage() {
+		for _, member := range members {
+			user, err := s.repo.GetUserByID(member.UserID)
+			if err != nil {
+				log.Printf("Warning: Failed to load member %s: %v", member.UserID, err)
+				continue
+			}
+			enabled := user.TwoFactorEnabled
+			member.TwoFactorEnabled = &enabled
+		}
+	}
+	return members, nil
+}
+
+// InviteMember emails an invitation to join the actor's workspace. Nobody is
+// invited as owner; only the owner invites admins.
+func (s *Service) InviteMember(actor types.Actor, req InviteMemberRequest) (*Invitation, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("%w: a valid email is required", ErrInvalidWorkspaceRequest)
+	}
+	if err := s.checkAssignableRole(actor, req.Role); err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.actorWorkspace(actor)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.GetWorkspaceMembers(workspace.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	for _, member := range members {
+		if strings.EqualFold(member.Email, email) {
+			return nil, ErrAlreadyMember
+		}
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &Invitation{
+		WorkspaceID: workspace.ID,
+		Email:       email,
+		Role:        req.Role,
+		InvitedBy:   actor.UserID,
+		TokenHash:   hashToken(token),
+		ExpiresAt:   time.Now().Add(InvitationTTL),
+	}
+	if err := s.repo.CreateInvitation(invitation); err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/api/workspaces/invitations/accept?token=%s", s.appURL, token)
+	body := fmt.Sprintf(
+		"Hi,\n\nYou were invited to join %q as %s. Sign in or register with this email address, then open the link below. It expires in %s.\n\n%s",
+		workspace.Name, req.Role, InvitationTTL, link)
+	if err := s.mailer.Send(email, "You're invited to "+workspace.Name, body); err != nil {
+		log.Printf("Warning: Failed to send invitation to %s: %v", email, err)
+	}
+
+	s.eventBus.Publish("workspace.member_invited", map[string]any{
+		"workspace_id":  workspace.ID,
+		"user_id":       actor.UserID,
+		"invitation_id": invitation.ID,
+		"email":         email,
+		"role":          req.Role,
+	})
+
+	invitation.TokenHash = ""
+	return invitation, nil
+}
+
+// ListInvitations returns the pending invitations of the actor's workspace
+func (s *Service) ListInvitations(actor types.Actor) ([]*Invitation, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.repo.GetWorkspaceInvitations(actor.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+
+	pending := []*Invitation{}
+	for _, invitation := range invitations {
+		invitation.TokenHash = ""
+		pending = append(pending, invitation)
+	}
+	slices.SortFunc(pending, func(a, b *Invitation) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return pending, nil
+}
+
+// RevokeInvitation withdraws a pending invitation
+func (s *Service) RevokeInvitation(actor types.Actor, invitationID string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteInvitation(actor.WorkspaceID, invitationID); err != nil {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation adds the user to the workspace they were invited to. The
+// invitation must have been sent to the user's email address.
+func (s *Service) AcceptInvitation(userID, token string) (*WorkspaceSummary, error) {
+	invitation, err := s.repo.GetInvitationByHash(hashToken(token))
+	if err != nil || time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvalidInvitation
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, fmt.Errorf("%w: it was sent to a different email address", ErrInvalidInvitation)
+	}
+
+	workspace, err := s.repo.GetWorkspace(invitation.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	if workspace == nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	existing, err := s.repo.GetMembership(workspace.ID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrAlreadyMember
+	}
+
+	if err := s.repo.SaveMembership(newMembership(workspace.ID, user, invitation.Role)); err != nil {
+		return nil, fmt.Errorf("failed to add member: %w", err)
+	}
+	if err := s.repo.DeleteInvitation(workspace.ID, invitation.ID); err != nil {
+		log.Printf("Warning: Failed to delete accepted invitation %s: %v", invitation.ID, err)
+	}
+
+	s.eventBus.Publish("workspace.member_joined", map[string]any{
+		"workspace_id": workspace.ID,
+		"user_id":      user.ID,
+		"role":         invitation.Role,
+	})
+	log.Printf("🤝 %s joined workspace %s as %s", user.Username, workspace.Name, invitation.Role)
+
+	return &WorkspaceSummary{Workspace: *workspace, Role: invitation.Role}, nil
+}
+
+// UpdateMemberRole changes a member's role. The owner's role is fixed, and
+// only the owner grants or takes away the admin role.
+func (s *Service) UpdateMemberRole(actor types.Actor, memberID, role string) (*Membership, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	if err := s.checkAssignableRole(actor, role); err != nil {
+		return nil, err
+	}
+
+	member, err := s.repo.GetMembership(actor.WorkspaceID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	if member == nil {
+		return nil, ErrMemberNotFound
+	}
+	if member.Role == types.RoleOwner {
+		return nil, fmt.Errorf("%w: the owner's role cannot be changed", types.ErrForbidden)
+	}
+	if member.Role == types.RoleAdmin && actor.Role != types.RoleOwner {
+		return nil, fmt.Errorf("%w: only the owner can change an admin's role", types.ErrForbidden)
+	}
+
+	member.Role = role
+	if err := s.repo.SaveMembership(member); err != nil {
+		return nil, fmt.Errorf("failed to update member: %w", err)
+	}
+
+	s.eventBus.Publish("workspace.member_role_changed", map[string]any{
+		"workspace_id": actor.WorkspaceID,
+		"user_id":      member.UserID,
+		"changed_by":   actor.UserID,
+		"role":         role,
+	})
+	return member, nil
+}
+
+// RemoveMember takes a member out of the actor's workspace. Members may leave
+// on their own; the owner cannot be removed.
+func (s *Service) RemoveMember(actor types.Actor, memberID string) error {
+	if err := actor.RequireRead(); err != nil {
+		return err
+	}
+	if memberID != actor.UserID {
+		if err := actor.RequireManage(); err != nil {
+			return err
+		}
+	}
+
+	member, err := s.repo.GetMembership(actor.WorkspaceID, memberID)
+	if err != nil {
+		return fmt.Errorf("failed to get membership: %w", err)
+	}
+	if member == nil {
+		return ErrMemberNotFound
+	}
+	if member.Role == types.RoleOwner {
+		return fmt.Errorf("%w: the owner cannot be removed", types.ErrForbidden)
+	}
+	if member.Role == types.RoleAdmin && memberID != actor.UserID && actor.Role != types.RoleOwner {
+		return fmt.Errorf("%w: only the owner can remove an admin", types.ErrForbidden)
+	}
+
+	if err := s.repo.DeleteMembership(actor.WorkspaceID, memberID); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+
+	s.eventBus.Publish("workspace.member_removed", map[string]any{
+		"workspace_id": actor.WorkspaceID,
+		"user_id":      memberID,
+		"removed_by":   actor.UserID,
+	})
+	return nil
+}
+
+// checkAssignableRole fails unless the actor may give someone role
+func (s *Service) checkAssignableRole(actor types.Actor, role string) error {
+	if !types.IsRole(role) || role == types.RoleOwner {
+		return fmt.Errorf("%w: role must be %s, %s or %s", ErrInvalidWorkspaceRequest, types.RoleAdmin, types.RoleMember, types.RoleAccountant)
+	}
+	if role == types.RoleAdmin && actor.Role != types.RoleOwner {
+		return fmt.Errorf("%w: only the owner can make admins", types.ErrForbidden)
+	}
+	return nil
+}
+
+// actorWorkspace returns the actor's workspace, creating it first if it is
+// the actor's personal workspace
+func (s *Service) actorWorkspace(actor types.Actor) (*Workspace, error) {
+	if actor.WorkspaceID == actor.UserID {
+		return s.personalWorkspace(actor.UserID)
+	}
+
+	workspace, err := s.repo.GetWorkspace(actor.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	if workspace == nil {
+		return nil, ErrWorkspaceNotFound
+	}
+	return workspace, nil
+}
+
+// personalWorkspace returns the user's personal workspace. It is created on
+// first use, so accounts from before workspaces get one too.
+func (s *Service) personalWorkspace(userID string) (*Workspace, error) {
+	workspace, err := s.repo.GetWorkspace(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	if workspace != nil {
+		return workspace, nil
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	now := time.Now()
+	workspace = &Workspace{
+		ID:        user.ID,
+		Name:      user.Username,
+		OwnerID:   user.ID,
+		Personal:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateWorkspace(workspace, newMembership(user.ID, user, types.RoleOwner)); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+func newMembership(workspaceID string, user *User, role string) *Membership {
+	return &Membership{
+		WorkspaceID: workspaceID,
+		UserID:      user.ID,
+		Email:       user.Email,
+		Username:    user.Username,
+		Role:        role,
+		JoinedAt:    time.Now(),
+	}
+}
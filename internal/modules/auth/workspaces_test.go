@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"testing"
+
+	"datastar-go/internal/shared/types"
+)
+
+func TestListMembersShowsTwoFactorToManagers(t *testing.T) {
+	s, _ := newTestService(t)
+	ann := registerUser(t, s, "ann@example.com")
+	bob := registerUser(t, s, "bob@example.com")
+	if err := s.setTwoFactorFlag(bob.User.ID, true); err != nil {
+		t.Fatalf("setTwoFactorFlag: %v", err)
+	}
+
+	workspace, err := s.CreateWorkspace(ann.User.ID, "Studio")
+	if err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	for _, role := range []string{types.RoleAdmin, types.RoleMember} {
+		user := registerUser(t, s, role+"@example.com")
+		if err := s.repo.SaveMembership(newMembership(workspace.ID, &user.User, role)); err != nil {
+			t.Fatalf("SaveMembership: %v", err)
+		}
+	}
+	if err := s.repo.SaveMembership(newMembership(workspace.ID, &bob.User, types.RoleAccountant)); err != nil {
+		t.Fatalf("SaveMembership: %v", err)
+	}
+
+	members, err := s.ListMembers(types.Actor{UserID: "x", WorkspaceID: workspace.ID, Role: types.RoleMember})
+	if err != nil {
+		t.Fatalf("ListMembers as member: %v", err)
+	}
+	for _, member := range members {
+		if member.TwoFactorEnabled != nil {
+			t.Errorf("member sees 2FA status of %s", member.Email)
+		}
+	}
+
+	for _, role := range []string{types.RoleOwner, types.RoleAdmin} {
+		members, err := s.ListMembers(types.Actor{UserID: "x", WorkspaceID: workspace.ID, Role: role})
+		if err != nil {
+			t.Fatalf("ListMembers as %s: %v", role, err)
+		}
+		if len(members) != 4 {
+			t.Fatalf("%s got %d members, want 4", role, len(members))
+		}
+		for _, member := range members {
+			if member.TwoFactorEnabled == nil {
+				t.Errorf("%s cannot see 2FA status of %s", role, member.Email)
+				continue
+			}
+			if want := member.UserID == bob.User.ID; *member.TwoFactorEnabled != want {
+				t.Errorf("%s: two_factor_enabled = %v, want %v", member.Email, *member.TwoFactorEnabled, want)
+			}
+		}
+	}
+}
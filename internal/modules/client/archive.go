@@ -0,0 +1,393 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+var (
+	ErrClientArchived = errors.New("client is archived")
+	ErrHasInvoices    = errors.New("invoices exist")
+	ErrUnbilledTime   = errors.New("unbilled time exists")
+)
+
+// InvoiceSource tells whether invoices reference a client or project
+type InvoiceSource interface {
+	GetInvoicesByClient(actor types.Actor, clientID string) ([]*types.Invoice, error)
+}
+
+// SetInvoiceSource wires the invoice lookup used before deleting clients and
+// projects. The invoice service depends on this service, so it is set after
+// construction.
+func (s *Service) SetInvoiceSource(invoices InvoiceSource) {
+	s.invoiceSource = invoices
+}
+
+// ArchiveClient hides a client and its projects from default listings. The
+// projects are stamped with the client's archive time so unarchiving restores
+// exactly those.
+func (s *Service) ArchiveClient(actor types.Actor, clientID string) (*types.Client, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+
+	client, err := s.GetClient(actor, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.ArchivedAt != nil {
+		return client, nil
+	}
+
+	now := time.Now()
+	client.ArchivedAt = &now
+	client.IsActive = false
+	client.UpdatedAt = now
+
+	projects, err := s.projectRepo.GetByClientID(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	var projectIDs []string
+	for _, project := range projects {
+		if project.IsArchived() {
+			continue
+		}
+		project.ArchivedAt = &now
+		project.UpdatedAt = now
+		if err := s.projectRepo.Update(project); err != nil {
+			return nil, fmt.Errorf("failed to archive project: %w", err)
+		}
+		projectIDs = append(projectIDs, project.ID)
+		s.publishProjectEvent("project_archived", "client.project.archived", project)
+	}
+
+	if err := s.clientRepo.Update(client); err != nil {
+		return nil, fmt.Errorf("failed to archive client: %w", err)
+	}
+
+	event := types.NewEvent("client_archived", "client_service", map[string]any{
+		"client_id":   client.ID,
+		"user_id":     client.UserID,
+		"project_ids": projectIDs,
+	})
+
+	s.eventBus.Publish("client.archived", event)
+	log.Printf("🗄️ Client archived: %s (%d projects)", client.Name, len(projectIDs))
+
+	return client, nil
+}
+
+// UnarchiveClient restores a client and the projects archived along with it
+func (s *Service) UnarchiveClient(actor types.Actor, clientID string) (*types.Client, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+
+	client, err := s.GetClient(actor, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.ArchivedAt == nil {
+		return client, nil
+	}
+	archivedAt := *client.ArchivedAt
+
+	projects, err := s.projectRepo.GetByClientID(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	now := time.Now()
+	var projectIDs []string
+	for _, project := range projects {
+		if project.DeletedAt != nil || project.ArchivedAt == nil || !project.ArchivedAt.Equal(archivedAt) {
+			continue
+		}
+		project.ArchivedAt = nil
+		project.UpdatedAt = now
+		if err := s.projectRepo.Update(project); err != nil {
+			return nil, fmt.Errorf("failed to unarchive project: %w", err)
+		}
+		projectIDs = append(projectIDs, project.ID)
+		s.publishProjectEvent("project_unarchived", "client.project.unarchived", project)
+	}
+
+	client.ArchivedAt = nil
+	client.IsActive = true
+	client.UpdatedAt = now
+
+	if err := s.clientRepo.Update(client); err != nil {
+		return nil, fmt.Errorf("failed to unarchive client: %w", err)
+	}
+
+	event := types.NewEvent("client_unarchived", "client_service", map[string]any{
+		"client_id":   client.ID,
+		"user_id":     client.UserID,
+		"project_ids": projectIDs,
+	})
+
+	s.eventBus.Publish("client.unarchived", event)
+	return client, nil
+}
+
+// DeleteClient soft-deletes a client and its projects. It refuses while the
+// client has invoices or any of its projects has unbilled time, since deleting
+// would orphan billing records.
+func (s *Service) DeleteClient(actor types.Actor, clientID string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+
+	client, err := s.GetClient(actor, clientID)
+	if err != nil {
+		return err
+	}
+
+	invoices, err := s.clientInvoices(actor, clientID, "")
+	if err != nil {
+		return err
+	}
+	if len(invoices) > 0 {
+		return fmt.Errorf("%w: %s has %d invoice(s); archive the client instead", ErrHasInvoices, client.Name, len(invoices))
+	}
+
+	projects, err := s.projectRepo.GetByClientID(clientID)
+	if err != nil {
+		return fmt.Errorf("failed to get projects: %w", err)
+	}
+	for _, project := range projects {
+		if project.DeletedAt != nil {
+			continue
+		}
+		if err := s.checkUnbilledTime(actor, project); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, project := range projects {
+		if project.DeletedAt != nil {
+			continue
+		}
+		project.DeletedAt = &now
+		project.UpdatedAt = now
+		if err := s.projectRepo.Update(project); err != nil {
+			return fmt.Errorf("failed to delete project: %w", err)
+		}
+		s.publishProjectEvent("project_deleted", "client.project.deleted", project)
+	}
+
+	client.DeletedAt = &now
+	client.IsActive = false
+	client.UpdatedAt = now
+
+	if err := s.clientRepo.Update(client); err != nil {
+		return fmt.Errorf("failed to delete client: %w", err)
+	}
+
+	event := types.NewEvent("client_deleted", "client_service", map[string]any{
+		"client_id": client.ID,
+		"user_id":   client.UserID,
+	})
+
+	s.eventBus.Publish("client.deleted", event)
+	log.Printf("🗑️ Client deleted: %s", client.Name)
+
+	return nil
+}
+
+// ArchiveProject hides a project from default listings
+func (s *Service) ArchiveProject(actor types.Actor, projectID string) (*types.Project, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+
+	project, err := s.getLiveProject(actor, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.ArchivedAt != nil {
+		return project, nil
+	}
+
+	now := time.Now()
+	project.ArchivedAt = &now
+	project.UpdatedAt = now
+
+	if err := s.projectRepo.Update(project); err != nil {
+		return nil, fmt.Errorf("failed to archive project: %w", err)
+	}
+
+	s.publishProjectEvent("project_archived", "client.project.archived", project)
+	return project, nil
+}
+
+// UnarchiveProject restores a project. Projects of an archived client stay
+// archived until the client is restored.
+func (s *Service) UnarchiveProject(actor types.Actor, projectID string) (*types.Project, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+
+	project, err := s.getLiveProject(actor, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.ArchivedAt == nil {
+		return project, nil
+	}
+
+	client, err := s.clientRepo.GetByID(actor.WorkspaceID, project.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	if client != nil && client.IsArchived() {
+		return nil, fmt.Errorf("%w: unarchive %s first", ErrClientArchived, client.Name)
+	}
+
+	project.ArchivedAt = nil
+	project.UpdatedAt = time.Now()
+
+	if err := s.projectRepo.Update(project); err != nil {
+		return nil, fmt.Errorf("failed to unarchive project: %w", err)
+	}
+
+	s.publishProjectEvent("project_unarchived", "client.project.unarchived", project)
+	return project, nil
+}
+
+// DeleteProject soft-deletes a project that has no invoices and no unbilled time
+func (s *Service) DeleteProject(actor types.Actor, projectID string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+
+	project, err := s.getLiveProject(actor, projectID)
+	if err != nil {
+		return err
+	}
+
+	invoices, err := s.clientInvoices(actor, project.ClientID, project.ID)
+	if err != nil {
+		return err
+	}
+	if len(invoices) > 0 {
+		return fmt.Errorf("%w: %s has %d invoice(s); archive the project instead", ErrHasInvoices, project.Name, len(invoices))
+	}
+	if err := s.checkUnbilledTime(actor, project); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	project.DeletedAt = &now
+	project.UpdatedAt = now
+
+	if err := s.projectRepo.Update(project); err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	s.publishProjectEvent("project_deleted", "client.project.deleted", project)
+	log.Printf("🗑️ Project deleted: %s", project.Name)
+
+	return nil
+}
+
+// getLiveProject returns a workspace's project unless it is missing or
+// soft-deleted
+func (s *Service) getLiveProject(actor types.Actor, projectID string) (*types.Project, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+
+	project, err := s.projectRepo.GetByID(actor.WorkspaceID, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	if project == nil || project.DeletedAt != nil {
+		return nil, ErrProjectNotFound
+	}
+	return project, nil
+}
+
+// clientInvoices returns the client's invoices, only those of projectID if set
+func (s *Service) clientInvoices(actor types.Actor, clientID, projectID string) ([]*types.Invoice, error) {
+	if s.invoiceSource == nil {
+		return nil, fmt.Errorf("invoice source not configured")
+	}
+
+	invoices, err := s.invoiceSource.GetInvoicesByClient(actor, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+	if projectID == "" {
+		return invoices, nil
+	}
+
+	var matched []*types.Invoice
+	for _, invoice := range invoices {
+		if invoice.ProjectID == projectID {
+			matched = append(matched, invoice)
+		}
+	}
+	return matched, nil
+}
+
+// checkUnbilledTime fails if the project has running or unbilled time entries
+func (s *Service) checkUnbilledTime(actor types.Actor, project *types.Project) error {
+	entries, err := s.timeSource.GetProjectTimeEntries(actor, project.ID, time.Time{}, time.Now().AddDate(100, 0, 0))
+	if err != nil {
+		return fmt.Errorf("failed to get time entries: %w", err)
+	}
+
+	unbilled := 0
+	for _, entry := range entries {
+		if entry.IsRunning || !entry.IsBilled {
+			unbilled++
+		}
+	}
+	if unbilled > 0 {
+		return fmt.Errorf("%w: %s has %d unbilled time entries", ErrUnbilledTime, project.Name, unbilled)
+	}
+	return nil
+}
+
+func (s *Service) publishProjectEvent(eventType, subject string, project *types.Project) {
+	event := types.NewEvent(eventType, "client_service", map[string]any{
+		"project_id": project.ID,
+		"client_id":  project.ClientID,
+		"user_id":    project.UserID,
+	})
+
+	s.eventBus.Publish(subject, event)
+}
+
+// activeClients drops archived and deleted clients unless includeArchived is
+// set; deleted clients are always dropped
+func activeClients(clients []*types.Client, includeArchived bool) []*types.Client {
+	filtered := []*types.Client{}
+	for _, client := range clients {
+		if client.DeletedAt != nil || (client.ArchivedAt != nil && !includeArchived) {
+			continue
+		}
+		filtered = append(filtered, client)
+	}
+	return filtered
+}
+
+// activeProjects is activeClients for projects
+func activeProjects(projects []*types.Project, includeArchived bool) []*types.Project {
+	filtered := []*types.Project{}
+	for _, project := range projects {
+		if project.DeletedAt != nil || (project.ArchivedAt != nil && !includeArchived) {
+			continue
+		}
+		filtered = append(filtered, project)
+	}
+	return filtered
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+var ErrInvalidBilling = errors.New("invalid billing model")
+
+// SetProjectBilling sets how a project is charged. A nil billing reverts the
+// project to plain hourly billing.
+func (s *Service) SetProjectBilling(actor types.Actor, projectID string, billing *types.ProjectBilling) (*types.Project, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+
+	project, err := s.getLiveProject(actor, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if billing != nil {
+		if err := validateBilling(billing); err != nil {
+			return nil, err
+		}
+		if billing.Model == types.BillingModelHourly {
+			billing = nil
+		}
+	}
+
+	project.Billing = billing
+	project.UpdatedAt = time.Now()
+
+	if err := s.projectRepo.Update(project); err != nil {
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+
+	event := types.NewEvent("project_billing_updated", "client_service", map[string]any{
+		"project_id": project.ID,
+		"client_id":  project.ClientID,
+		"user_id":    project.UserID,
+		"billing":    billing,
+	})
+
+	s.eventBus.Publish("client.project.billing_updated", event)
+	return project, nil
+}
+
+// validateBilling checks that the amounts the model needs are set
+func validateBilling(billing *types.ProjectBilling) error {
+	if billing.FixedFee < 0 || billing.Cap < 0 || billing.RetainerFee < 0 || billing.RetainerHours < 0 {
+		return fmt.Errorf("%w: amounts can't be negative", ErrInvalidBilling)
+	}
+
+	switch billing.Model {
+	case "", types.BillingModelHourly:
+		billing.Model = types.BillingModelHourly
+	case types.BillingModelFixedFee:
+		if billing.FixedFee == 0 {
+			return fmt.Errorf("%w: fixed_fee is required", ErrInvalidBilling)
+		}
+		if billing.PercentComplete < 0 || billing.PercentComplete > 100 {
+			return fmt.Errorf("%w: percent_complete must be between 0 and 100", ErrInvalidBilling)
+		}
+	case types.BillingModelCappedHourly:
+		if billing.Cap == 0 {
+			return fmt.Errorf("%w: cap is required", ErrInvalidBilling)
+		}
+	case types.BillingModelRetainer:
+		if billing.RetainerFee == 0 {
+			return fmt.Errorf("%w: retainer_fee is required", ErrInvalidBilling)
+		}
+	default:
+		return fmt.Errorf("%w: unknown model %q", ErrInvalidBilling, billing.Model)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+// budgetThresholds are the burn percentages that raise an alert, in order
+var budgetThresholds = []int{50, 80, 100}
+
+var ErrProjectNotFound = errors.New("project not found")
+
+// TimeSource provides the tracked time that burns a project's budget
+type TimeSource interface {
+	GetProjectTimeEntries(actor types.Actor, projectID string, from, to time.Time) ([]*types.TimeEntry, error)
+}
+
+// ExpenseSource provides the expenses that burn a project's budget
+type ExpenseSource interface {
+	GetExpensesByProject(actor types.Actor, projectID string) ([]*types.Expense, error)
+}
+
+// BudgetReport is the burn of a project's budget for the current period
+type BudgetReport struct {
+	ProjectID       string               `json:"project_id"`
+	Budget          *types.ProjectBudget `json:"budget"`
+	PeriodStart     *time.Time           `json:"period_start,omitempty"` // nil for total budgets
+	PeriodEnd       *time.Time           `json:"period_end,omitempty"`
+	Hours           float64              `json:"hours"`
+	TimeAmount      float64              `json:"time_amount"`
+	ExpenseAmount   float64              `json:"expense_amount"`
+	Amount          float64              `json:"amount"`
+	HoursPercent    float64              `json:"hours_percent,omitempty"`
+	AmountPercent   float64              `json:"amount_percent,omitempty"`
+	HoursRemaining  float64              `json:"hours_remaining,omitempty"`
+	AmountRemaining float64              `json:"amount_remaining,omitempty"`
+	GeneratedAt     time.Time            `json:"generated_at"`
+}
+
+// BudgetStatus remembers which alerts were already raised in a period so each
+// threshold fires once
+type BudgetStatus struct {
+	ProjectID     string    `json:"project_id"`
+	Period        string    `json:"period"` // "total" or the month, e.g. 2025-03
+	HoursAlerted  int       `json:"hours_alerted"`
+	AmountAlerted int       `json:"amount_alerted"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SetProjectBudget sets or, with a nil budget, removes a project's budget
+func (s *Service) SetProjectBudget(actor types.Actor, projectID string, budget *types.ProjectBudget) (*types.Project, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+
+	project, err := s.projectRepo.GetByID(actor.WorkspaceID, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	if budget != nil {
+		if budget.Period == "" {
+			budget.Period = types.BudgetPeriodTotal
+		}
+		if budget.Period != types.BudgetPeriodTotal && budget.Period != types.BudgetPeriodMonthly {
+			return nil, fmt.Errorf("period must be %q or %q", types.BudgetPeriodTotal, types.BudgetPeriodMonthly)
+		}
+		if budget.Hours < 0 || budget.Amount < 0 || budget.Hours+budget.Amount == 0 {
+			return nil, fmt.Errorf("an hours or amount budget is required")
+		}
+	}
+
+	project.Budget = budget
+	project.UpdatedAt = time.Now()
+
+	if err := s.projectRepo.Update(project); err != nil {
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+
+	// Start alerting afresh against the new limits
+	if err := s.projectRepo.DeleteBudgetStatus(projectID); err != nil {
+		log.Printf("Failed to reset budget status for %s: %v", projectID, err)
+	}
+
+	event := types.NewEvent("project_budget_updated", "client_service", map[string]any{
+		"project_id": project.ID,
+		"user_id":    project.UserID,
+		"budget":     budget,
+	})
+
+	s.eventBus.Publish("client.project.budget_updated", event)
+
+	if budget != nil {
+		if _, err := s.checkBudget(project, time.Now()); err != nil {
+			log.Printf("Failed to check budget for %s: %v", projectID, err)
+		}
+	}
+	return project, nil
+}
+
+// GetBudgetReport computes the current burn of a project's budget
+func (s *Service) GetBudgetReport(actor types.Actor, projectID string) (*BudgetReport, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+
+	project, err := s.projectRepo.GetByID(actor.WorkspaceID, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.Budget == nil {
+		return nil, fmt.Errorf("project has no budget")
+	}
+
+	return s.checkBudget(project, time.Now())
+}
+
+// computeBurn adds up the time and expenses every workspace member booked
+// against the budget period
+func (s *Service) computeBurn(project *types.Project, now time.Time) (*BudgetReport, error) {
+	workspace := types.SystemActor(types.WorkspaceOf(project.WorkspaceID, project.UserID))
+	budget := project.Budget
+	report := &BudgetReport{
+		ProjectID:   project.ID,
+		Budget:      budget,
+		GeneratedAt: now,
+	}
+
+	from, to := time.Time{}, now.AddDate(100, 0, 0)
+	if budget.Period == types.BudgetPeriodMonthly {
+		utc := now.UTC()
+		from = time.Date(utc.Year(), utc.Month(), 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 1, 0)
+		report.PeriodStart, report.PeriodEnd = &from, &to
+	}
+
+	entries, err := s.timeSource.GetProjectTimeEntries(workspace, project.ID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time entries: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.EndTime == nil {
+			continue
+		}
+		hours := float64(entry.Duration) / 3600
+		rate := project.HourlyRate
+		if rate == 0 {
+			rate = entry.HourlyRate
+		}
+		report.Hours += hours
+		report.TimeAmount += hours * rate
+	}
+
+	expenses, err := s.expenseSource.GetExpensesByProject(workspace, project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expenses: %w", err)
+	}
+	for _, expense := range expenses {
+		date := expense.Date
+		if date.IsZero() {
+			date = expense.CreatedAt
+		}
+		if date.Before(from) || !date.Before(to) {
+			continue
+		}
+		report.ExpenseAmount += expense.Amount
+	}
+
+	report.Hours = round2(report.Hours)
+	report.TimeAmount = round2(report.TimeAmount)
+	report.ExpenseAmount = round2(report.ExpenseAmount)
+	report.Amount = round2(report.TimeAmount + report.ExpenseAmount)
+
+	if budget.Hours > 0 {
+		report.HoursPercent = round2(report.Hours / budget.Hours * 100)
+		report.HoursRemaining = round2(budget.Hours - report.Hours)
+	}
+	if budget.Amount > 0 {
+		report.AmountPercent = round2(report.Amount / budget.Amount * 100)
+		report.AmountRemaining = round2(budget.Amount - report.Amount)
+	}
+	return report, nil
+}
+
+// checkBudget computes the burn and publishes an alert for every threshold
+// crossed since the last check in the same period
+func (s *Service) checkBudget(project *types.Project, now time.Time) (*BudgetReport, error) {
+	report, err := s.computeBurn(project, now)
+	if err != nil {
+		return nil, err
+	}
+
+	period := types.BudgetPeriodTotal
+	if project.Budget.Period == types.BudgetPeriodMonthly {
+		period = now.UTC().Format("2006-01")
+	}
+
+	status, err := s.projectRepo.GetBudgetStatus(project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget status: %w", err)
+	}
+	if status == nil || status.Period != period {
+		status = &BudgetStatus{ProjectID: project.ID, Period: period}
+	}
+
+	hoursAlerted := s.publishBudgetAlerts(project, report, "hours", report.HoursPercent, status.HoursAlerted)
+	amountAlerted := s.publishBudgetAlerts(project, report, "amount", report.AmountPercent, status.AmountAlerted)
+
+	if hoursAlerted != status.HoursAlerted || amountAlerted != status.AmountAlerted || status.UpdatedAt.IsZero() {
+		status.HoursAlerted = hoursAlerted
+		status.AmountAlerted = amountAlerted
+		status.UpdatedAt = now
+		if err := s.projectRepo.SaveBudgetStatus(status); err != nil {
+			return nil, fmt.Errorf("failed to save budget status: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// publishBudgetAlerts raises an alert for each threshold crossed above the one
+// already alerted and returns the highest threshold now alerted
+func (s *Service) publishBudgetAlerts(project *types.Project, report *BudgetReport, kind string, percent float64, alerted int) int {
+	for _, threshold := range budgetThresholds {
+		if threshold <= alerted || percent < float64(threshold) {
+			continue
+		}
+
+		event := types.NewEvent("project_budget_threshold", "client_service", map[string]any{
+			"project_id": project.ID,
+			"client_id":  project.ClientID,
+			"user_id":    project.UserID,
+			"kind":       kind,
+			"threshold":  threshold,
+			"percent":    percent,
+			"hours":      report.Hours,
+			"amount":     report.Amount,
+			"period":     project.Budget.Period,
+		})
+
+		s.eventBus.Publish("client.project.budget_threshold", event)
+		log.Printf("📊 Project %s reached %d%% of its %s budget (%.1f%%)", project.Name, threshold, kind, percent)
+
+		alerted = threshold
+	}
+	return alerted
+}
+
+// handleBudgetTimeEvent recomputes burn when time is tracked on a budgeted project
+func (s *Service) handleBudgetTimeEvent(event *types.Event) error {
+	projectID, _ := event.Data["project_id"].(string)
+	return s.refreshBudget(event.WorkspaceID(), projectID)
+}
+
+// handleBudgetExpenseEvent recomputes burn when a project's expenses change,
+// including the project an expense was moved away from
+func (s *Service) handleBudgetExpenseEvent(event *types.Event) error {
+	workspaceID := event.WorkspaceID()
+	switch event.Type {
+	case "expense_created", "expense_updated", "expense_deleted":
+		projectID, _ := event.Data["project_id"].(string)
+		return s.refreshBudget(workspaceID, projectID)
+	case "expense_field_changed":
+		if event.Data["field"] == "project_id" {
+			oldProjectID, _ := event.Data["old_value"].(string)
+			return s.refreshBudget(workspaceID, oldProjectID)
+		}
+	}
+	return nil
+}
+
+func (s *Service) refreshBudget(workspaceID, projectID string) error {
+	if projectID == "" {
+		return nil
+	}
+
+	project, err := s.projectRepo.GetByID(workspaceID, projectID)
+	if err != nil {
+		return err
+	}
+	if project == nil || project.Budget == nil {
+		return nil
+	}
+
+	_, err = s.checkBudget(project, time.Now())
+	return err
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+var ErrInvalidProfile = errors.New("invalid business profile")
+
+// SetClientBusinessDetails sets a client's postal address and business
+// identifiers. A nil value clears it. The VAT ID is checked against the
+// address country.
+func (s *Service) SetClientBusinessDetails(actor types.Actor, clientID string, address *types.PostalAddress, identifiers *types.BusinessIdentifiers) (*types.Client, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	client, err := s.GetClient(actor, clientID)
+	if err != nil {
+		return nil, ErrClientNotFound
+	}
+
+	country := ""
+	if address != nil {
+		address.Normalize()
+		if err := address.Validate(); err != nil {
+			return nil, err
+		}
+		country = address.Country
+	}
+	if identifiers != nil {
+		identifiers.Normalize()
+		if err := identifiers.Validate(country); err != nil {
+			return nil, err
+		}
+		if *identifiers == (types.BusinessIdentifiers{}) {
+			identifiers = nil
+		}
+	}
+
+	client.PostalAddress = address
+	client.Identifiers = identifiers
+	if address != nil {
+		client.Address = address.Format()
+	}
+	client.UpdatedAt = time.Now()
+
+	if err := s.clientRepo.Update(client); err != nil {
+		return nil, fmt.Errorf("failed to update client: %w", err)
+	}
+
+	event := types.NewEvent("client_business_details_updated", "client_service", map[string]any{
+		"client_id":  client.ID,
+		"user_id":    client.UserID,
+		"country":    country,
+		"has_vat_id": identifiers != nil && identifiers.VATID != "",
+	})
+
+	s.eventBus.Publish("client.business_details_updated", event)
+	return client, nil
+}
+
+// GetBusinessProfile returns the workspace's business profile, or nil if none
+// is set
+func (s *Service) GetBusinessProfile(actor types.Actor) (*types.BusinessProfile, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	return s.profileRepo.Get(actor.WorkspaceID)
+}
+
+// SaveBusinessProfile validates and stores the workspace's business profile
+func (s *Service) SaveBusinessProfile(actor types.Actor, profile types.BusinessProfile) (*types.BusinessProfile, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+
+	profile.UserID = actor.UserID
+	profile.WorkspaceID = actor.WorkspaceID
+	profile.LegalName = strings.TrimSpace(profile.LegalName)
+	profile.TradingName = strings.TrimSpace(profile.TradingName)
+	profile.Email = strings.TrimSpace(profile.Email)
+	profile.Phone = strings.TrimSpace(profile.Phone)
+
+	if profile.LegalName == "" {
+		return nil, fmt.Errorf("%w: legal_name is required", ErrInvalidProfile)
+	}
+
+	profile.Address.Normalize()
+	if err := profile.Address.Validate(); err != nil {
+		return nil, err
+	}
+	profile.Identifiers.Normalize()
+	if err := profile.Identifiers.Validate(profile.Address.Country); err != nil {
+		return nil, err
+	}
+	profile.UpdatedAt = time.Now()
+
+	if err := s.profileRepo.Save(&profile); err != nil {
+		return nil, fmt.Errorf("failed to save business profile: %w", err)
+	}
+
+	event := types.NewEvent("business_profile_updated", "client_service", map[string]any{
+		"user_id":      actor.UserID,
+		"workspace_id": actor.WorkspaceID,
+		"country":      profile.Address.Country,
+	})
+
+	s.eventBus.Publish("client.business_profile.updated", event)
+	log.Printf("🏢 Business profile saved for %s (%s)", profile.LegalName, profile.Address.Country)
+
+	return &profile, nil
+}
@@ -0,0 +1,251 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+var (
+	ErrClientNotFound  = errors.New("client not found")
+	ErrContactNotFound = errors.New("contact not found")
+	ErrInvalidContact  = errors.New("invalid contact")
+)
+
+var validContactRoles = []string{
+	types.ContactRolePrimary,
+	types.ContactRoleBilling,
+	types.ContactRoleProjectLead,
+	types.ContactRoleCC,
+}
+
+// CreateContact adds a contact to a client
+func (s *Service) CreateContact(actor types.Actor, clientID string, contact types.Contact) (*types.Contact, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	client, err := s.GetClient(actor, clientID)
+	if err != nil {
+		return nil, ErrClientNotFound
+	}
+
+	contact.ID = types.GenerateID()
+	contact.ClientID = client.ID
+	contact.UserID = actor.UserID
+	contact.WorkspaceID = actor.WorkspaceID
+	contact.CreatedAt = time.Now()
+	contact.UpdatedAt = time.Now()
+
+	if err := normalizeContact(&contact); err != nil {
+		return nil, err
+	}
+
+	if err := s.contactRepo.Save(&contact); err != nil {
+		return nil, fmt.Errorf("failed to create contact: %w", err)
+	}
+
+	event := types.NewEvent("contact_created", "client_service", map[string]any{
+		"contact_id": contact.ID,
+		"client_id":  contact.ClientID,
+		"user_id":    contact.UserID,
+		"roles":      contact.Roles,
+	})
+
+	s.eventBus.Publish("client.contact.created", event)
+	log.Printf("📇 Contact added to %s: %s <%s>", client.Name, contact.Name, contact.Email)
+
+	return &contact, nil
+}
+
+// GetContacts returns a client's contacts, primary contacts first
+func (s *Service) GetContacts(actor types.Actor, clientID string) ([]*types.Contact, error) {
+	if _, err := s.GetClient(actor, clientID); err != nil {
+		return nil, ErrClientNotFound
+	}
+
+	contacts, err := s.contactRepo.GetByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(contacts, func(a, b *types.Contact) int {
+		ap, bp := a.HasRole(types.ContactRolePrimary), b.HasRole(types.ContactRolePrimary)
+		switch {
+		case ap && !bp:
+			return -1
+		case bp && !ap:
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return contacts, nil
+}
+
+// UpdateContact replaces the editable fields of a contact
+func (s *Service) UpdateContact(actor types.Actor, clientID, contactID string, update types.Contact) (*types.Contact, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.GetClient(actor, clientID); err != nil {
+		return nil, ErrClientNotFound
+	}
+
+	contact, err := s.contactRepo.Get(clientID, contactID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+	if contact == nil {
+		return nil, ErrContactNotFound
+	}
+
+	contact.Name = update.Name
+	contact.Email = update.Email
+	contact.Phone = update.Phone
+	contact.Title = update.Title
+	contact.Roles = update.Roles
+	contact.Notes = update.Notes
+	contact.UpdatedAt = time.Now()
+
+	if err := normalizeContact(contact); err != nil {
+		return nil, err
+	}
+
+	if err := s.contactRepo.Save(contact); err != nil {
+		return nil, fmt.Errorf("failed to update contact: %w", err)
+	}
+
+	event := types.NewEvent("contact_updated", "client_service", map[string]any{
+		"contact_id": contact.ID,
+		"client_id":  contact.ClientID,
+		"user_id":    contact.UserID,
+		"roles":      contact.Roles,
+	})
+
+	s.eventBus.Publish("client.contact.updated", event)
+	return contact, nil
+}
+
+// DeleteContact removes a contact from a client
+func (s *Service) DeleteContact(actor types.Actor, clientID, contactID string) error {
+	if err := actor.RequireWrite(); err != nil {
+		return err
+	}
+
+	if _, err := s.GetClient(actor, clientID); err != nil {
+		return ErrClientNotFound
+	}
+
+	contact, err := s.contactRepo.Get(clientID, contactID)
+	if err != nil {
+		return fmt.Errorf("failed to get contact: %w", err)
+	}
+	if contact == nil {
+		return ErrContactNotFound
+	}
+
+	if err := s.contactRepo.Delete(clientID, contactID); err != nil {
+		return fmt.Errorf("failed to delete contact: %w", err)
+	}
+
+	event := types.NewEvent("contact_deleted", "client_service", map[string]any{
+		"contact_id": contact.ID,
+		"client_id":  contact.ClientID,
+		"user_id":    contact.UserID,
+	})
+
+	s.eventBus.Publish("client.contact.deleted", event)
+	return nil
+}
+
+// GetInvoiceRecipients picks who receives a client's invoices: billing contacts,
+// else primary contacts, else the client's own email. Project leads and CC
+// contacts are copied.
+func (s *Service) GetInvoiceRecipients(actor types.Actor, clientID string) (*types.InvoiceRecipients, error) {
+	client, err := s.GetClient(actor, clientID)
+	if err != nil {
+		return nil, ErrClientNotFound
+	}
+
+	contacts, err := s.GetContacts(actor, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contacts: %w", err)
+	}
+
+	recipients := &types.InvoiceRecipients{}
+	for _, role := range []string{types.ContactRoleBilling, types.ContactRolePrimary} {
+		for _, contact := range contacts {
+			if contact.HasRole(role) {
+				recipients.To = appendAddress(recipients.To, contact)
+			}
+		}
+		if len(recipients.To) > 0 {
+			break
+		}
+	}
+	if len(recipients.To) == 0 && client.Email != "" {
+		recipients.To = []string{(&mail.Address{Name: client.Name, Address: client.Email}).String()}
+	}
+	if len(recipients.To) == 0 {
+		return nil, fmt.Errorf("client %s has no billing, primary or client email address", client.Name)
+	}
+
+	for _, contact := range contacts {
+		if contact.HasRole(types.ContactRoleCC) || contact.HasRole(types.ContactRoleProjectLead) {
+			address := formatAddress(contact)
+			if !slices.Contains(recipients.To, address) && !slices.Contains(recipients.CC, address) {
+				recipients.CC = append(recipients.CC, address)
+			}
+		}
+	}
+	return recipients, nil
+}
+
+func appendAddress(addresses []string, contact *types.Contact) []string {
+	address := formatAddress(contact)
+	if slices.Contains(addresses, address) {
+		return addresses
+	}
+	return append(addresses, address)
+}
+
+func formatAddress(contact *types.Contact) string {
+	return (&mail.Address{Name: contact.Name, Address: contact.Email}).String()
+}
+
+// normalizeContact trims fields, validates the email and de-duplicates roles
+func normalizeContact(contact *types.Contact) error {
+	contact.Name = strings.TrimSpace(contact.Name)
+	contact.Email = strings.TrimSpace(contact.Email)
+	contact.Phone = strings.TrimSpace(contact.Phone)
+	contact.Title = strings.TrimSpace(contact.Title)
+
+	if contact.Name == "" || contact.Email == "" {
+		return fmt.Errorf("%w: name and email are required", ErrInvalidContact)
+	}
+	address, err := mail.ParseAddress(contact.Email)
+	if err != nil || address.Name != "" {
+		return fmt.Errorf("%w: bad email %q", ErrInvalidContact, contact.Email)
+	}
+	contact.Email = address.Address
+
+	roles := []string{}
+	for _, role := range contact.Roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !slices.Contains(validContactRoles, role) {
+			return fmt.Errorf("%w: unknown role %q (use %s)", ErrInvalidContact, role, strings.Join(validContactRoles, ", "))
+		}
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	contact.Roles = roles
+	return nil
+}
@@ -1,13 +1,20 @@
 package client
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
+	"datastar-go/internal/modules/auth"
 	"datastar-go/internal/shared/types"
 )
 
+// maxVCardSize limits vCard uploads
+const maxVCardSize = 1 << 20
+
 type Handlers struct {
 	service *Service
 }
@@ -17,8 +24,13 @@ func NewHandlers(service *Service) *Handlers {
 }
 
 func (h *Handlers) handleCreateClient(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
-		UserID  string `json:"user_id"`
 		Name    string `json:"name"`
 		Email   string `json:"email"`
 		Company string `json:"company"`
@@ -29,14 +41,14 @@ func (h *Handlers) handleCreateClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.UserID == "" || req.Name == "" || req.Email == "" {
+	if req.Name == "" || req.Email == "" {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
 
-	client, err := h.service.CreateClient(req.UserID, req.Name, req.Email, req.Company)
+	client, err := h.service.CreateClient(actor, req.Name, req.Email, req.Company)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -50,15 +62,16 @@ func (h *Handlers) handleCreateClient(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) handleGetClients(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "user_id required", http.StatusBadRequest)
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	clients, err := h.service.GetClients(userID)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	clients, err := h.service.ListClients(actor, includeArchived)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -72,6 +85,12 @@ func (h *Handlers) handleGetClients(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) handleUpdateClient(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
 		ClientID string         `json:"client_id"`
 		Updates  map[string]any `json:"updates"`
@@ -87,9 +106,9 @@ func (h *Handlers) handleUpdateClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client, err := h.service.UpdateClient(req.ClientID, req.Updates)
+	client, err := h.service.UpdateClient(actor, req.ClientID, req.Updates)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), archiveErrorStatus(err))
 		return
 	}
 
@@ -103,12 +122,18 @@ func (h *Handlers) handleUpdateClient(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
 		ClientID    string  `json:"client_id"`
-		UserID      string  `json:"user_id"`
 		Name        string  `json:"name"`
 		Description string  `json:"description"`
 		HourlyRate  float64 `json:"hourly_rate"`
+		StartDate   string  `json:"start_date"` // RFC 3339 or YYYY-MM-DD; future dates plan the project
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -116,14 +141,24 @@ func (h *Handlers) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.ClientID == "" || req.UserID == "" || req.Name == "" {
+	if req.ClientID == "" || req.Name == "" {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
 
-	project, err := h.service.CreateProject(req.ClientID, req.UserID, req.Name, req.Description, req.HourlyRate)
+	var startDate *time.Time
+	if req.StartDate != "" {
+		date, err := parseDate(req.StartDate)
+		if err != nil {
+			http.Error(w, "Invalid start_date", http.StatusBadRequest)
+			return
+		}
+		startDate = &date
+	}
+
+	project, err := h.service.CreateProject(actor, req.ClientID, req.Name, req.Description, req.HourlyRate, startDate)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), archiveErrorStatus(err))
 		return
 	}
 
@@ -137,15 +172,16 @@ func (h *Handlers) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) handleGetProjects(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "user_id required", http.StatusBadRequest)
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	projects, err := h.service.GetProjects(userID)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	projects, err := h.service.ListProjects(actor, includeArchived)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -159,15 +195,22 @@ func (h *Handlers) handleGetProjects(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) handleGetClientProjects(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	clientID := r.URL.Query().Get("client_id")
 	if clientID == "" {
 		http.Error(w, "client_id required", http.StatusBadRequest)
 		return
 	}
 
-	projects, err := h.service.GetProjectsByClient(clientID)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	projects, err := h.service.GetProjectsByClient(actor, clientID, includeArchived)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), archiveErrorStatus(err))
 		return
 	}
 
@@ -181,6 +224,12 @@ func (h *Handlers) handleGetClientProjects(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *Handlers) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
 		ProjectID string         `json:"project_id"`
 		Updates   map[string]any `json:"updates"`
@@ -196,9 +245,126 @@ func (h *Handlers) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := h.service.UpdateProject(req.ProjectID, req.Updates)
+	project, err := h.service.UpdateProject(actor, req.ProjectID, req.Updates)
+	if err != nil {
+		http.Error(w, err.Error(), lifecycleErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    project,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetProjectBudget(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	projectID := r.URL.Query().Get("project_id")
+	if projectID == "" {
+		http.Error(w, "project_id required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetBudgetReport(actor, projectID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrProjectNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), auth.ErrorStatus(err, status))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    report,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleSetProjectBudget(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ProjectID string               `json:"project_id"`
+		Budget    *types.ProjectBudget `json:"budget"` // null removes the budget
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProjectID == "" {
+		http.Error(w, "project_id required", http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.service.SetProjectBudget(actor, req.ProjectID, req.Budget)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrProjectNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), auth.ErrorStatus(err, status))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    project,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleSetProjectBilling(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ProjectID string                `json:"project_id"`
+		Billing   *types.ProjectBilling `json:"billing"` // null reverts to hourly
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProjectID == "" {
+		http.Error(w, "project_id required", http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.service.SetProjectBilling(actor, req.ProjectID, req.Billing)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrProjectNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrInvalidBilling):
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), auth.ErrorStatus(err, status))
 		return
 	}
 
@@ -211,6 +377,709 @@ func (h *Handlers) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *Handlers) handleCreateContact(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ClientID string `json:"client_id"`
+		types.Contact
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ClientID == "" || req.Name == "" || req.Email == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	contact, err := h.service.CreateContact(actor, req.ClientID, req.Contact)
+	if err != nil {
+		http.Error(w, err.Error(), contactErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    contact,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetContacts(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" {
+		http.Error(w, "client_id required", http.StatusBadRequest)
+		return
+	}
+
+	contacts, err := h.service.GetContacts(actor, clientID)
+	if err != nil {
+		http.Error(w, err.Error(), contactErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    contacts,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleUpdateContact(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ClientID  string `json:"client_id"`
+		ContactID string `json:"contact_id"`
+		types.Contact
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ClientID == "" || req.ContactID == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	contact, err := h.service.UpdateContact(actor, req.ClientID, req.ContactID, req.Contact)
+	if err != nil {
+		http.Error(w, err.Error(), contactErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    contact,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleDeleteContact(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clientID := r.URL.Query().Get("client_id")
+	contactID := r.URL.Query().Get("contact_id")
+	if clientID == "" || contactID == "" {
+		http.Error(w, "client_id and contact_id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteContact(actor, clientID, contactID); err != nil {
+		http.Error(w, err.Error(), contactErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Message: "Contact deleted successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleExportVCard(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" {
+		http.Error(w, "client_id required", http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.service.ExportVCard(actor, clientID, r.URL.Query().Get("contact_id"), &buf); err != nil {
+		http.Error(w, err.Error(), contactErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vcard; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="contacts.vcf"`)
+	w.Write(buf.Bytes())
+}
+
+func (h *Handlers) handleImportVCard(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" {
+		http.Error(w, "client_id required", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxVCardSize)
+	result, err := h.service.ImportVCard(actor, clientID, r.Body)
+	if err != nil {
+		status := contactErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), auth.ErrorStatus(err, status))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    result,
+		Message: fmt.Sprintf("%d contacts created, %d updated, %d skipped", len(result.Created), len(result.Updated), len(result.Skipped)),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func contactErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrClientNotFound), errors.Is(err, ErrContactNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidContact):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *Handlers) handleSetBusinessDetails(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ClientID      string                     `json:"client_id"`
+		PostalAddress *types.PostalAddress       `json:"postal_address"` // null clears the address
+		Identifiers   *types.BusinessIdentifiers `json:"identifiers"`    // null clears the identifiers
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ClientID == "" {
+		http.Error(w, "client_id required", http.StatusBadRequest)
+		return
+	}
+
+	client, err := h.service.SetClientBusinessDetails(actor, req.ClientID, req.PostalAddress, req.Identifiers)
+	if err != nil {
+		http.Error(w, err.Error(), businessErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    client,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetBusinessProfile(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := h.service.GetBusinessProfile(actor)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	if profile == nil {
+		http.Error(w, "business profile not set", http.StatusNotFound)
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    profile,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleSaveBusinessProfile(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var profile types.BusinessProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.service.SaveBusinessProfile(actor, profile)
+	if err != nil {
+		http.Error(w, err.Error(), businessErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    saved,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleValidateVATID(w http.ResponseWriter, r *http.Request) {
+	vatID := r.URL.Query().Get("vat_id")
+	if vatID == "" {
+		http.Error(w, "vat_id required", http.StatusBadRequest)
+		return
+	}
+
+	identifiers := types.BusinessIdentifiers{VATID: vatID}
+	identifiers.Normalize()
+	err := identifiers.Validate(r.URL.Query().Get("country"))
+
+	data := map[string]any{
+		"vat_id": identifiers.VATID,
+		"valid":  err == nil,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    data,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleArchiveClient(w http.ResponseWriter, r *http.Request) {
+	h.handleClientArchival(w, r, h.service.ArchiveClient)
+}
+
+func (h *Handlers) handleUnarchiveClient(w http.ResponseWriter, r *http.Request) {
+	h.handleClientArchival(w, r, h.service.UnarchiveClient)
+}
+
+func (h *Handlers) handleClientArchival(w http.ResponseWriter, r *http.Request, apply func(types.Actor, string) (*types.Client, error)) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ClientID string `json:"client_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ClientID == "" {
+		http.Error(w, "client_id required", http.StatusBadRequest)
+		return
+	}
+
+	client, err := apply(actor, req.ClientID)
+	if err != nil {
+		http.Error(w, err.Error(), archiveErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    client,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleDeleteClient(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" {
+		http.Error(w, "client_id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteClient(actor, clientID); err != nil {
+		http.Error(w, err.Error(), archiveErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Message: "Client deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleArchiveProject(w http.ResponseWriter, r *http.Request) {
+	h.handleProjectArchival(w, r, h.service.ArchiveProject)
+}
+
+func (h *Handlers) handleUnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	h.handleProjectArchival(w, r, h.service.UnarchiveProject)
+}
+
+func (h *Handlers) handleProjectArchival(w http.ResponseWriter, r *http.Request, apply func(types.Actor, string) (*types.Project, error)) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ProjectID string `json:"project_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProjectID == "" {
+		http.Error(w, "project_id required", http.StatusBadRequest)
+		return
+	}
+
+	project, err := apply(actor, req.ProjectID)
+	if err != nil {
+		http.Error(w, err.Error(), archiveErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    project,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	projectID := r.URL.Query().Get("project_id")
+	if projectID == "" {
+		http.Error(w, "project_id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteProject(actor, projectID); err != nil {
+		http.Error(w, err.Error(), archiveErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Message: "Project deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleTransitionProject(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ProjectID string `json:"project_id"`
+		Status    string `json:"status"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProjectID == "" || req.Status == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.service.TransitionProject(actor, req.ProjectID, req.Status)
+	if err != nil {
+		http.Error(w, err.Error(), lifecycleErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    project,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleCreateMilestone(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ProjectID string `json:"project_id"`
+		types.Milestone
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProjectID == "" || req.Name == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	milestone, err := h.service.CreateMilestone(actor, req.ProjectID, req.Milestone)
+	if err != nil {
+		http.Error(w, err.Error(), lifecycleErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    milestone,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetMilestones(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	projectID := r.URL.Query().Get("project_id")
+	if projectID == "" {
+		http.Error(w, "project_id required", http.StatusBadRequest)
+		return
+	}
+
+	milestones, err := h.service.GetMilestones(actor, projectID)
+	if err != nil {
+		http.Error(w, err.Error(), lifecycleErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    milestones,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleUpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		MilestoneID string `json:"milestone_id"`
+		types.Milestone
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.MilestoneID == "" || req.Name == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	milestone, err := h.service.UpdateMilestone(actor, req.MilestoneID, req.Milestone)
+	if err != nil {
+		http.Error(w, err.Error(), lifecycleErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    milestone,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleCompleteMilestone(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		MilestoneID string `json:"milestone_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.MilestoneID == "" {
+		http.Error(w, "milestone_id required", http.StatusBadRequest)
+		return
+	}
+
+	milestone, err := h.service.CompleteMilestone(actor, req.MilestoneID)
+	if err != nil {
+		http.Error(w, err.Error(), lifecycleErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    milestone,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleDeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	milestoneID := r.URL.Query().Get("milestone_id")
+	if milestoneID == "" {
+		http.Error(w, "milestone_id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteMilestone(actor, milestoneID); err != nil {
+		http.Error(w, err.Error(), lifecycleErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Message: "Milestone deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func lifecycleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrProjectNotFound), errors.Is(err, ErrMilestoneNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrMilestoneInvoiced), errors.Is(err, ErrMilestoneCompleted):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidProject), errors.Is(err, ErrInvalidMilestone):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func archiveErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrClientNotFound), errors.Is(err, ErrProjectNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrHasInvoices), errors.Is(err, ErrUnbilledTime), errors.Is(err, ErrClientArchived):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func businessErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrClientNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, types.ErrInvalidAddress), errors.Is(err, types.ErrInvalidVATID), errors.Is(err, ErrInvalidProfile):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 func (h *Handlers) handleHealth(w http.ResponseWriter, r *http.Request) {
 	response := map[string]any{
 		"status":    "healthy",
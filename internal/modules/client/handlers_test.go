@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"datastar-go/internal/modules/auth"
+	"datastar-go/internal/shared/testutil"
+	"datastar-go/internal/shared/types"
+)
+
+type noTime struct{}
+
+func (noTime) GetProjectTimeEntries(types.Actor, string, time.Time, time.Time) ([]*types.TimeEntry, error) {
+	return nil, nil
+}
+
+type noExpenses struct{}
+
+func (noExpenses) GetExpensesByProject(types.Actor, string) ([]*types.Expense, error) {
+	return nil, nil
+}
+
+func TestHandlersRejectCrossTenantAccess(t *testing.T) {
+	s := NewService(&testutil.EventBus{}, testutil.MemoryDB(t), noTime{}, noExpenses{})
+	h := NewHandlers(s)
+
+	owner := types.PersonalActor("user-1")
+	stranger := types.PersonalActor("user-2")
+	accountant := types.Actor{UserID: "user-3", WorkspaceID: owner.WorkspaceID, Role: types.RoleAccountant}
+
+	client, err := s.CreateClient(owner, "Acme", "billing@acme.test", "Acme Ltd")
+	if err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+	project, err := s.CreateProject(owner, client.ID, "Site", "", 100, nil)
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	clientQuery := "?client_id=" + client.ID
+	projectQuery := "?project_id=" + project.ID
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		actor   types.Actor
+		method  string
+		target  string
+		body    string
+		want    int
+	}{
+		{"update client", h.handleUpdateClient, stranger, http.MethodPut, "/api/client/update", `{"client_id":"` + client.ID + `","updates":{"name":"Mine"}}`, http.StatusNotFound},
+		{"delete client", h.handleDeleteClient, stranger, http.MethodDelete, "/api/client/delete" + clientQuery, "", http.StatusNotFound},
+		{"list contacts", h.handleGetContacts, stranger, http.MethodGet, "/api/client/contact/list" + clientQuery, "", http.StatusNotFound},
+		{"list client projects", h.handleGetClientProjects, stranger, http.MethodGet, "/api/project/client" + clientQuery, "", http.StatusNotFound},
+		{"create project", h.handleCreateProject, stranger, http.MethodPost, "/api/project/create", `{"client_id":"` + client.ID + `","name":"Mine","hourly_rate":1}`, http.StatusNotFound},
+		{"update project", h.handleUpdateProject, stranger, http.MethodPut, "/api/project/update", `{"project_id":"` + project.ID + `","updates":{"name":"Mine"}}`, http.StatusNotFound},
+		{"transition project", h.handleTransitionProject, stranger, http.MethodPost, "/api/project/status", `{"project_id":"` + project.ID + `","status":"paused"}`, http.StatusNotFound},
+		{"list milestones", h.handleGetMilestones, stranger, http.MethodGet, "/api/project/milestone/list" + projectQuery, "", http.StatusNotFound},
+		{"create milestone", h.handleCreateMilestone, stranger, http.MethodPost, "/api/project/milestone/create", `{"project_id":"` + project.ID + `","name":"Launch"}`, http.StatusNotFound},
+		{"project budget", h.handleGetProjectBudget, stranger, http.MethodGet, "/api/project/budget" + projectQuery, "", http.StatusNotFound},
+		{"delete project", h.handleDeleteProject, stranger, http.MethodDelete, "/api/project/delete" + projectQuery, "", http.StatusNotFound},
+		{"accountant updates client", h.handleUpdateClient, accountant, http.MethodPut, "/api/client/update", `{"client_id":"` + client.ID + `","updates":{"name":"Mine"}}`, http.StatusForbidden},
+		{"accountant transitions project", h.handleTransitionProject, accountant, http.MethodPost, "/api/project/status", `{"project_id":"` + project.ID + `","status":"paused"}`, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			r = r.WithContext(context.WithValue(r.Context(), auth.ActorKey, tc.actor))
+			w := httptest.NewRecorder()
+			tc.handler(w, r)
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+}
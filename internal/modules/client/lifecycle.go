@@ -0,0 +1,347 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+var (
+	ErrInvalidProject     = errors.New("invalid project")
+	ErrInvalidTransition  = errors.New("invalid project status transition")
+	ErrMilestoneNotFound  = errors.New("milestone not found")
+	ErrInvalidMilestone   = errors.New("invalid milestone")
+	ErrMilestoneInvoiced  = errors.New("milestone already invoiced")
+	ErrMilestoneCompleted = errors.New("milestone already completed")
+)
+
+// projectTransitions lists the statuses a project may move to from each
+// status. Completed projects can be reopened; cancelled ones are final.
+var projectTransitions = map[string][]string{
+	types.ProjectStatusPlanned:   {types.ProjectStatusActive, types.ProjectStatusCancelled},
+	types.ProjectStatusActive:    {types.ProjectStatusPaused, types.ProjectStatusCompleted, types.ProjectStatusCancelled},
+	types.ProjectStatusPaused:    {types.ProjectStatusActive, types.ProjectStatusCompleted, types.ProjectStatusCancelled},
+	types.ProjectStatusCompleted: {types.ProjectStatusActive},
+	types.ProjectStatusCancelled: {},
+}
+
+// TransitionProject moves a project to a new lifecycle status, keeping its
+// start and end dates in step
+func (s *Service) TransitionProject(actor types.Actor, projectID, status string) (*types.Project, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	project, err := s.getLiveProject(actor, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := project.Status
+	if from == "" {
+		from = types.ProjectStatusActive
+	}
+	if err := applyProjectStatus(project, status, time.Now()); err != nil {
+		return nil, err
+	}
+	if from == project.Status {
+		return project, nil
+	}
+
+	if err := s.projectRepo.Update(project); err != nil {
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+
+	s.publishStatusChange(project, from)
+	return project, nil
+}
+
+// applyProjectStatus validates a transition and sets the dates it implies:
+// activating starts a planned project, finishing sets the end date and
+// reopening clears it
+func applyProjectStatus(project *types.Project, status string, now time.Time) error {
+	from := project.Status
+	if from == "" {
+		from = types.ProjectStatusActive // projects created before lifecycles
+	}
+	status = strings.ToLower(strings.TrimSpace(status))
+
+	if status == from {
+		project.Status = status
+		return nil
+	}
+	allowed, known := projectTransitions[from]
+	if _, valid := projectTransitions[status]; !valid || !known || !slices.Contains(allowed, status) {
+		return fmt.Errorf("%w: %s to %q", ErrInvalidTransition, from, status)
+	}
+
+	switch status {
+	case types.ProjectStatusActive:
+		if project.StartDate.IsZero() || project.StartDate.After(now) {
+			project.StartDate = now
+		}
+		project.EndDate = nil
+	case types.ProjectStatusCompleted, types.ProjectStatusCancelled:
+		project.EndDate = &now
+	}
+
+	project.Status = status
+	project.UpdatedAt = now
+	return nil
+}
+
+func (s *Service) publishStatusChange(project *types.Project, from string) {
+	event := types.NewEvent("project_status_changed", "client_service", map[string]any{
+		"project_id": project.ID,
+		"client_id":  project.ClientID,
+		"user_id":    project.UserID,
+		"from":       from,
+		"to":         project.Status,
+	})
+
+	s.eventBus.Publish("client.project.status_changed", event)
+	log.Printf("📋 Project %s: %s → %s", project.Name, from, project.Status)
+}
+
+// CreateMilestone adds a milestone to a project
+func (s *Service) CreateMilestone(actor types.Actor, projectID string, milestone types.Milestone) (*types.Milestone, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	project, err := s.getLiveProject(actor, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	milestone.Name = strings.TrimSpace(milestone.Name)
+	if milestone.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidMilestone)
+	}
+	if milestone.Amount < 0 {
+		return nil, fmt.Errorf("%w: amount can't be negative", ErrInvalidMilestone)
+	}
+
+	milestone.ID = types.GenerateID()
+	milestone.ProjectID = project.ID
+	milestone.ClientID = project.ClientID
+	milestone.UserID = actor.UserID
+	milestone.WorkspaceID = project.WorkspaceID
+	milestone.Status = types.MilestoneStatusPending
+	milestone.CompletedAt = nil
+	milestone.InvoiceID = ""
+	milestone.CreatedAt = time.Now()
+	milestone.UpdatedAt = time.Now()
+
+	if err := s.milestoneRepo.Save(&milestone); err != nil {
+		return nil, fmt.Errorf("failed to create milestone: %w", err)
+	}
+
+	event := types.NewEvent("milestone_created", "client_service", map[string]any{
+		"milestone_id": milestone.ID,
+		"project_id":   milestone.ProjectID,
+		"user_id":      milestone.UserID,
+		"amount":       milestone.Amount,
+		"due_date":     milestone.DueDate,
+	})
+
+	s.eventBus.Publish("client.project.milestone_created", event)
+	return &milestone, nil
+}
+
+// GetMilestones returns a project's milestones by due date, undated last
+func (s *Service) GetMilestones(actor types.Actor, projectID string) ([]*types.Milestone, error) {
+	if _, err := s.getLiveProject(actor, projectID); err != nil {
+		return nil, err
+	}
+
+	milestones, err := s.milestoneRepo.GetByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(milestones, func(a, b *types.Milestone) int {
+		switch {
+		case a.DueDate == nil && b.DueDate == nil:
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case a.DueDate == nil:
+			return 1
+		case b.DueDate == nil:
+			return -1
+		}
+		return a.DueDate.Compare(*b.DueDate)
+	})
+	return milestones, nil
+}
+
+// GetMilestone returns one of the user's milestones
+func (s *Service) GetMilestone(actor types.Actor, milestoneID string) (*types.Milestone, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+
+	milestone, err := s.milestoneRepo.Get(actor.WorkspaceID, milestoneID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get milestone: %w", err)
+	}
+	if milestone == nil {
+		return nil, ErrMilestoneNotFound
+	}
+	return milestone, nil
+}
+
+// UpdateMilestone replaces a milestone's name, description, due date and
+// amount. The amount is fixed once the milestone is invoiced.
+func (s *Service) UpdateMilestone(actor types.Actor, milestoneID string, update types.Milestone) (*types.Milestone, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	milestone, err := s.GetMilestone(actor, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	update.Name = strings.TrimSpace(update.Name)
+	if update.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidMilestone)
+	}
+	if update.Amount < 0 {
+		return nil, fmt.Errorf("%w: amount can't be negative", ErrInvalidMilestone)
+	}
+	if milestone.InvoiceID != "" && update.Amount != milestone.Amount {
+		return nil, ErrMilestoneInvoiced
+	}
+
+	milestone.Name = update.Name
+	milestone.Description = update.Description
+	milestone.DueDate = update.DueDate
+	milestone.Amount = update.Amount
+	milestone.UpdatedAt = time.Now()
+
+	if err := s.milestoneRepo.Save(milestone); err != nil {
+		return nil, fmt.Errorf("failed to update milestone: %w", err)
+	}
+
+	event := types.NewEvent("milestone_updated", "client_service", map[string]any{
+		"milestone_id": milestone.ID,
+		"project_id":   milestone.ProjectID,
+		"user_id":      milestone.UserID,
+	})
+
+	s.eventBus.Publish("client.project.milestone_updated", event)
+	return milestone, nil
+}
+
+// DeleteMilestone removes a milestone that has not been invoiced
+func (s *Service) DeleteMilestone(actor types.Actor, milestoneID string) error {
+	if err := actor.RequireWrite(); err != nil {
+		return err
+	}
+
+	milestone, err := s.GetMilestone(actor, milestoneID)
+	if err != nil {
+		return err
+	}
+	if milestone.InvoiceID != "" {
+		return ErrMilestoneInvoiced
+	}
+
+	if err := s.milestoneRepo.Delete(milestoneID); err != nil {
+		return fmt.Errorf("failed to delete milestone: %w", err)
+	}
+
+	event := types.NewEvent("milestone_deleted", "client_service", map[string]any{
+		"milestone_id": milestone.ID,
+		"project_id":   milestone.ProjectID,
+		"user_id":      milestone.UserID,
+	})
+
+	s.eventBus.Publish("client.project.milestone_deleted", event)
+	return nil
+}
+
+// CompleteMilestone marks a milestone done and announces it so it can be
+// invoiced
+func (s *Service) CompleteMilestone(actor types.Actor, milestoneID string) (*types.Milestone, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	milestone, err := s.GetMilestone(actor, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if milestone.Status == types.MilestoneStatusCompleted {
+		return nil, ErrMilestoneCompleted
+	}
+
+	now := time.Now()
+	milestone.Status = types.MilestoneStatusCompleted
+	milestone.CompletedAt = &now
+	milestone.UpdatedAt = now
+
+	if err := s.milestoneRepo.Save(milestone); err != nil {
+		return nil, fmt.Errorf("failed to complete milestone: %w", err)
+	}
+
+	onTime := milestone.DueDate == nil || !now.After(*milestone.DueDate)
+	event := types.NewEvent("milestone_completed", "client_service", map[string]any{
+		"milestone_id": milestone.ID,
+		"project_id":   milestone.ProjectID,
+		"client_id":    milestone.ClientID,
+		"user_id":      milestone.UserID,
+		"name":         milestone.Name,
+		"amount":       milestone.Amount,
+		"on_time":      onTime,
+	})
+
+	s.eventBus.Publish("client.project.milestone_completed", event)
+	log.Printf("🏁 Milestone completed: %s ($%.2f)", milestone.Name, milestone.Amount)
+
+	return milestone, nil
+}
+
+// MarkMilestoneInvoiced records the invoice billing a milestone's fee. A
+// milestone can only be on one invoice.
+func (s *Service) MarkMilestoneInvoiced(actor types.Actor, milestoneID, invoiceID string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+
+	milestone, err := s.GetMilestone(actor, milestoneID)
+	if err != nil {
+		return err
+	}
+	if milestone.InvoiceID != "" && milestone.InvoiceID != invoiceID {
+		return fmt.Errorf("%w: %s is on invoice %s", ErrMilestoneInvoiced, milestone.Name, milestone.InvoiceID)
+	}
+
+	milestone.InvoiceID = invoiceID
+	milestone.UpdatedAt = time.Now()
+	return s.milestoneRepo.Save(milestone)
+}
+
+// MarkMilestoneUninvoiced releases a milestone from a deleted or cancelled invoice
+func (s *Service) MarkMilestoneUninvoiced(actor types.Actor, milestoneID, invoiceID string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+
+	milestone, err := s.GetMilestone(actor, milestoneID)
+	if err != nil {
+		return err
+	}
+	if milestone.InvoiceID != invoiceID {
+		return nil
+	}
+
+	milestone.InvoiceID = ""
+	milestone.UpdatedAt = time.Now()
+	return s.milestoneRepo.Save(milestone)
+}
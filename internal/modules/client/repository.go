@@ -17,6 +17,18 @@ type ProjectRepository struct {
 	db *badger.DB
 }
 
+type ContactRepository struct {
+	db *badger.DB
+}
+
+type MilestoneRepository struct {
+	db *badger.DB
+}
+
+type ProfileRepository struct {
+	db *badger.DB
+}
+
 func NewClientRepository(db *badger.DB) *ClientRepository {
 	return &ClientRepository{db: db}
 }
@@ -25,6 +37,18 @@ func NewProjectRepository(db *badger.DB) *ProjectRepository {
 	return &ProjectRepository{db: db}
 }
 
+func NewMilestoneRepository(db *badger.DB) *MilestoneRepository {
+	return &MilestoneRepository{db: db}
+}
+
+func NewProfileRepository(db *badger.DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+func NewContactRepository(db *badger.DB) *ContactRepository {
+	return &ContactRepository{db: db}
+}
+
 func (r *ClientRepository) Save(client *types.Client) error {
 	key := fmt.Sprintf("client:%s", client.ID)
 	data, err := json.Marshal(client)
@@ -37,7 +61,9 @@ func (r *ClientRepository) Save(client *types.Client) error {
 	})
 }
 
-func (r *ClientRepository) Get(id string) (*types.Client, error) {
+// Get returns a workspace's client, or nil if it doesn't exist or belongs to
+// another workspace
+func (r *ClientRepository) Get(workspaceID, id string) (*types.Client, error) {
 	key := fmt.Sprintf("client:%s", id)
 	var client types.Client
 
@@ -52,7 +78,7 @@ func (r *ClientRepository) Get(id string) (*types.Client, error) {
 		})
 	})
 
-	if err == badger.ErrKeyNotFound {
+	if err == badger.ErrKeyNotFound || (err == nil && !types.InWorkspace(client.WorkspaceID, client.UserID, workspaceID)) {
 		return nil, nil
 	}
 	return &client, err
@@ -62,15 +88,15 @@ func (r *ClientRepository) Create(client *types.Client) error {
 	return r.Save(client)
 }
 
-func (r *ClientRepository) GetByID(id string) (*types.Client, error) {
-	return r.Get(id)
+func (r *ClientRepository) GetByID(workspaceID, id string) (*types.Client, error) {
+	return r.Get(workspaceID, id)
 }
 
 func (r *ClientRepository) Update(client *types.Client) error {
 	return r.Save(client)
 }
 
-func (r *ClientRepository) GetByUserID(userID string) ([]*types.Client, error) {
+func (r *ClientRepository) GetByWorkspaceID(workspaceID string) ([]*types.Client, error) {
 	var clients []*types.Client
 	err := r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
@@ -85,7 +111,7 @@ func (r *ClientRepository) GetByUserID(userID string) ([]*types.Client, error) {
 				if err := json.Unmarshal(val, &client); err != nil {
 					return err
 				}
-				if client.UserID == userID {
+				if types.InWorkspace(client.WorkspaceID, client.UserID, workspaceID) {
 					clients = append(clients, &client)
 				}
 				return nil
@@ -111,7 +137,9 @@ func (r *ProjectRepository) Save(project *types.Project) error {
 	})
 }
 
-func (r *ProjectRepository) Get(id string) (*types.Project, error) {
+// Get returns a workspace's project, or nil if it doesn't exist or belongs to
+// another workspace
+func (r *ProjectRepository) Get(workspaceID, id string) (*types.Project, error) {
 	key := fmt.Sprintf("project:%s", id)
 	var project types.Project
 
@@ -126,7 +154,7 @@ func (r *ProjectRepository) Get(id string) (*types.Project, error) {
 		})
 	})
 
-	if err == badger.ErrKeyNotFound {
+	if err == badger.ErrKeyNotFound || (err == nil && !types.InWorkspace(project.WorkspaceID, project.UserID, workspaceID)) {
 		return nil, nil
 	}
 	return &project, err
@@ -136,15 +164,15 @@ func (r *ProjectRepository) Create(project *types.Project) error {
 	return r.Save(project)
 }
 
-func (r *ProjectRepository) GetByID(id string) (*types.Project, error) {
-	return r.Get(id)
+func (r *ProjectRepository) GetByID(workspaceID, id string) (*types.Project, error) {
+	return r.Get(workspaceID, id)
 }
 
 func (r *ProjectRepository) Update(project *types.Project) error {
 	return r.Save(project)
 }
 
-func (r *ProjectRepository) GetByUserID(userID string) ([]*types.Project, error) {
+func (r *ProjectRepository) GetByWorkspaceID(workspaceID string) ([]*types.Project, error) {
 	var projects []*types.Project
 	err := r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
@@ -159,7 +187,7 @@ func (r *ProjectRepository) GetByUserID(userID string) ([]*types.Project, error)
 				if err := json.Unmarshal(val, &project); err != nil {
 					return err
 				}
-				if project.UserID == userID {
+				if types.InWorkspace(project.WorkspaceID, project.UserID, workspaceID) {
 					projects = append(projects, &project)
 				}
 				return nil
@@ -201,3 +229,220 @@ func (r *ProjectRepository) GetByClientID(clientID string) ([]*types.Project, er
 	})
 	return projects, err
 }
+
+func (r *ProjectRepository) SaveBudgetStatus(status *BudgetStatus) error {
+	key := fmt.Sprintf("project_budget_status:%s", status.ProjectID)
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), data)
+	})
+}
+
+func (r *ProjectRepository) GetBudgetStatus(projectID string) (*BudgetStatus, error) {
+	key := fmt.Sprintf("project_budget_status:%s", projectID)
+	var status BudgetStatus
+
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &status)
+		})
+	})
+
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	return &status, err
+}
+
+func (r *ProjectRepository) DeleteBudgetStatus(projectID string) error {
+	key := fmt.Sprintf("project_budget_status:%s", projectID)
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (r *ContactRepository) Save(contact *types.Contact) error {
+	key := fmt.Sprintf("contact:%s:%s", contact.ClientID, contact.ID)
+	data, err := json.Marshal(contact)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), data)
+	})
+}
+
+func (r *ContactRepository) Get(clientID, id string) (*types.Contact, error) {
+	key := fmt.Sprintf("contact:%s:%s", clientID, id)
+	var contact types.Contact
+
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &contact)
+		})
+	})
+
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	return &contact, err
+}
+
+func (r *ContactRepository) GetByClientID(clientID string) ([]*types.Contact, error) {
+	var contacts []*types.Contact
+	err := r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(fmt.Sprintf("contact:%s:", clientID))
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var contact types.Contact
+				if err := json.Unmarshal(val, &contact); err != nil {
+					return err
+				}
+				contacts = append(contacts, &contact)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return contacts, err
+}
+
+func (r *ContactRepository) Delete(clientID, id string) error {
+	key := fmt.Sprintf("contact:%s:%s", clientID, id)
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (r *ProfileRepository) Save(profile *types.BusinessProfile) error {
+	workspaceID := profile.WorkspaceID
+	if workspaceID == "" {
+		workspaceID = profile.UserID
+	}
+	key := fmt.Sprintf("business_profile:%s", workspaceID)
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), data)
+	})
+}
+
+// Get returns a workspace's business profile. A personal workspace's profile
+// is stored under its user's ID, which is the workspace ID.
+func (r *ProfileRepository) Get(workspaceID string) (*types.BusinessProfile, error) {
+	key := fmt.Sprintf("business_profile:%s", workspaceID)
+	var profile types.BusinessProfile
+
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &profile)
+		})
+	})
+
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	return &profile, err
+}
+
+func (r *MilestoneRepository) Save(milestone *types.Milestone) error {
+	key := fmt.Sprintf("milestone:%s", milestone.ID)
+	data, err := json.Marshal(milestone)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), data)
+	})
+}
+
+// Get returns a workspace's milestone, or nil if it doesn't exist or belongs
+// to another workspace
+func (r *MilestoneRepository) Get(workspaceID, id string) (*types.Milestone, error) {
+	key := fmt.Sprintf("milestone:%s", id)
+	var milestone types.Milestone
+
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &milestone)
+		})
+	})
+
+	if err == badger.ErrKeyNotFound || (err == nil && !types.InWorkspace(milestone.WorkspaceID, milestone.UserID, workspaceID)) {
+		return nil, nil
+	}
+	return &milestone, err
+}
+
+func (r *MilestoneRepository) GetByProjectID(projectID string) ([]*types.Milestone, error) {
+	var milestones []*types.Milestone
+	err := r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte("milestone:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var milestone types.Milestone
+				if err := json.Unmarshal(val, &milestone); err != nil {
+					return err
+				}
+				if milestone.ProjectID == projectID {
+					milestones = append(milestones, &milestone)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return milestones, err
+}
+
+func (r *MilestoneRepository) Delete(id string) error {
+	key := fmt.Sprintf("milestone:%s", id)
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"testing"
+
+	"datastar-go/internal/shared/testutil"
+	"datastar-go/internal/shared/types"
+)
+
+func TestRepositoriesScopeRecordsToWorkspace(t *testing.T) {
+	db := testutil.MemoryDB(t)
+	clients := NewClientRepository(db)
+	projects := NewProjectRepository(db)
+	milestones := NewMilestoneRepository(db)
+
+	if err := clients.Save(&types.Client{ID: "client-1", UserID: "user-1", WorkspaceID: "ws-1"}); err != nil {
+		t.Fatalf("save client: %v", err)
+	}
+	if err := clients.Save(&types.Client{ID: "client-2", UserID: "user-1"}); err != nil { // stored before workspaces
+		t.Fatalf("save client: %v", err)
+	}
+	if err := projects.Save(&types.Project{ID: "proj-1", UserID: "user-1", WorkspaceID: "ws-1", ClientID: "client-1"}); err != nil {
+		t.Fatalf("save project: %v", err)
+	}
+	if err := milestones.Save(&types.Milestone{ID: "ms-1", UserID: "user-1", WorkspaceID: "ws-1", ProjectID: "proj-1"}); err != nil {
+		t.Fatalf("save milestone: %v", err)
+	}
+
+	lookups := []struct {
+		name        string
+		get         func(workspaceID, id string) (bool, error)
+		workspaceID string
+		id          string
+		found       bool
+	}{
+		{"client", clientGetter(clients), "ws-1", "client-1", true},
+		{"client", clientGetter(clients), "ws-2", "client-1", false},
+		{"client", clientGetter(clients), "user-1", "client-1", false},
+		{"legacy client", clientGetter(clients), "user-1", "client-2", true},
+		{"legacy client", clientGetter(clients), "ws-1", "client-2", false},
+		{"project", projectGetter(projects), "ws-1", "proj-1", true},
+		{"project", projectGetter(projects), "ws-2", "proj-1", false},
+		{"milestone", milestoneGetter(milestones), "ws-1", "ms-1", true},
+		{"milestone", milestoneGetter(milestones), "ws-2", "ms-1", false},
+		{"missing project", projectGetter(projects), "ws-1", "proj-9", false},
+	}
+	for _, lookup := range lookups {
+		found, err := lookup.get(lookup.workspaceID, lookup.id)
+		if err != nil {
+			t.Errorf("%s %s in %s: %v", lookup.name, lookup.id, lookup.workspaceID, err)
+		}
+		if found != lookup.found {
+			t.Errorf("%s %s in %s: found = %v, want %v", lookup.name, lookup.id, lookup.workspaceID, found, lookup.found)
+		}
+	}
+
+	list, err := projects.GetByWorkspaceID("ws-2")
+	if err != nil || len(list) != 0 {
+		t.Errorf("projects of ws-2 = %d, %v; want none", len(list), err)
+	}
+}
+
+func clientGetter(repo *ClientRepository) func(string, string) (bool, error) {
+	return func(workspaceID, id string) (bool, error) {
+		client, err := repo.GetByID(workspaceID, id)
+		return client != nil, err
+	}
+}
+
+func projectGetter(repo *ProjectRepository) func(string, string) (bool, error) {
+	return func(workspaceID, id string) (bool, error) {
+		project, err := repo.GetByID(workspaceID, id)
+		return project != nil, err
+	}
+}
+
+func milestoneGetter(repo *MilestoneRepository) func(string, string) (bool, error) {
+	return func(workspaceID, id string) (bool, error) {
+		milestone, err := repo.Get(workspaceID, id)
+		return milestone != nil, err
+	}
+}
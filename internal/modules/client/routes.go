@@ -9,10 +9,35 @@ func (h *Handlers) SetupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/client/create", h.handleCreateClient)
 	mux.HandleFunc("GET /api/client/list", h.handleGetClients)
 	mux.HandleFunc("PUT /api/client/update", h.handleUpdateClient)
+	mux.HandleFunc("POST /api/client/archive", h.handleArchiveClient)
+	mux.HandleFunc("POST /api/client/unarchive", h.handleUnarchiveClient)
+	mux.HandleFunc("DELETE /api/client/delete", h.handleDeleteClient)
+	mux.HandleFunc("PUT /api/client/business", h.handleSetBusinessDetails)
+	mux.HandleFunc("GET /api/client/vat/validate", h.handleValidateVATID)
+	mux.HandleFunc("POST /api/client/contact/create", h.handleCreateContact)
+	mux.HandleFunc("GET /api/client/contact/list", h.handleGetContacts)
+	mux.HandleFunc("PUT /api/client/contact/update", h.handleUpdateContact)
+	mux.HandleFunc("DELETE /api/client/contact/delete", h.handleDeleteContact)
+	mux.HandleFunc("GET /api/client/contact/vcard", h.handleExportVCard)
+	mux.HandleFunc("POST /api/client/contact/vcard", h.handleImportVCard)
 	mux.HandleFunc("POST /api/project/create", h.handleCreateProject)
 	mux.HandleFunc("GET /api/project/list", h.handleGetProjects)
 	mux.HandleFunc("GET /api/project/client", h.handleGetClientProjects)
 	mux.HandleFunc("PUT /api/project/update", h.handleUpdateProject)
+	mux.HandleFunc("PUT /api/project/billing", h.handleSetProjectBilling)
+	mux.HandleFunc("POST /api/project/status", h.handleTransitionProject)
+	mux.HandleFunc("POST /api/project/milestone/create", h.handleCreateMilestone)
+	mux.HandleFunc("GET /api/project/milestone/list", h.handleGetMilestones)
+	mux.HandleFunc("PUT /api/project/milestone/update", h.handleUpdateMilestone)
+	mux.HandleFunc("POST /api/project/milestone/complete", h.handleCompleteMilestone)
+	mux.HandleFunc("DELETE /api/project/milestone/delete", h.handleDeleteMilestone)
+	mux.HandleFunc("POST /api/project/archive", h.handleArchiveProject)
+	mux.HandleFunc("POST /api/project/unarchive", h.handleUnarchiveProject)
+	mux.HandleFunc("DELETE /api/project/delete", h.handleDeleteProject)
+	mux.HandleFunc("GET /api/project/budget", h.handleGetProjectBudget)
+	mux.HandleFunc("PUT /api/project/budget", h.handleSetProjectBudget)
+	mux.HandleFunc("GET /api/profile/business", h.handleGetBusinessProfile)
+	mux.HandleFunc("PUT /api/profile/business", h.handleSaveBusinessProfile)
 	mux.HandleFunc("GET /api/client/health", h.handleHealth)
 
 	log.Println("Client API routes configured")
@@ -3,6 +3,7 @@ package client
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"datastar-go/internal/shared/types"
@@ -11,16 +12,27 @@ import (
 )
 
 type Service struct {
-	eventBus    types.EventBus
-	clientRepo  *ClientRepository
-	projectRepo *ProjectRepository
+	eventBus      types.EventBus
+	clientRepo    *ClientRepository
+	projectRepo   *ProjectRepository
+	contactRepo   *ContactRepository
+	milestoneRepo *MilestoneRepository
+	profileRepo   *ProfileRepository
+	timeSource    TimeSource
+	expenseSource ExpenseSource
+	invoiceSource InvoiceSource
 }
 
-func NewService(eventBus types.EventBus, db *badger.DB) *Service {
+func NewService(eventBus types.EventBus, db *badger.DB, timeSource TimeSource, expenseSource ExpenseSource) *Service {
 	service := &Service{
-		eventBus:    eventBus,
-		clientRepo:  NewClientRepository(db),
-		projectRepo: NewProjectRepository(db),
+		eventBus:      eventBus,
+		clientRepo:    NewClientRepository(db),
+		projectRepo:   NewProjectRepository(db),
+		contactRepo:   NewContactRepository(db),
+		milestoneRepo: NewMilestoneRepository(db),
+		profileRepo:   NewProfileRepository(db),
+		timeSource:    timeSource,
+		expenseSource: expenseSource,
 	}
 
 	service.setupEventSubscriptions()
@@ -30,19 +42,27 @@ func NewService(eventBus types.EventBus, db *badger.DB) *Service {
 func (s *Service) setupEventSubscriptions() {
 	s.eventBus.SubscribeQueue("invoice.generated", "client_service", s.handleInvoiceGenerated)
 	s.eventBus.SubscribeQueue("time.entry.completed", "client_service", s.handleTimeEntryCompleted)
+	s.eventBus.SubscribeQueue("time.session.stopped", "client_service_budget", s.handleBudgetTimeEvent)
+	s.eventBus.SubscribeQueue("expense.>", "client_service_budget", s.handleBudgetExpenseEvent)
 
 	log.Println("Client service event subscriptions configured")
 }
 
-func (s *Service) CreateClient(userID, name, email, company string) (*types.Client, error) {
+func (s *Service) CreateClient(actor types.Actor, name, email, company string) (*types.Client, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
 	client := &types.Client{
-		ID:        types.GenerateID(),
-		UserID:    userID,
-		Name:      name,
-		Email:     email,
-		Company:   company,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:          types.GenerateID(),
+		UserID:      actor.UserID,
+		WorkspaceID: actor.WorkspaceID,
+		Name:        name,
+		Email:       email,
+		Company:     company,
+		IsActive:    true,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 
 	err := s.clientRepo.Create(client)
@@ -51,11 +71,12 @@ func (s *Service) CreateClient(userID, name, email, company string) (*types.Clie
 	}
 
 	event := types.NewEvent("client_created", "client_service", map[string]any{
-		"client_id": client.ID,
-		"user_id":   client.UserID,
-		"name":      client.Name,
-		"email":     client.Email,
-		"company":   client.Company,
+		"client_id":    client.ID,
+		"user_id":      client.UserID,
+		"workspace_id": client.WorkspaceID,
+		"name":         client.Name,
+		"email":        client.Email,
+		"company":      client.Company,
 	})
 
 	s.eventBus.Publish("client.created", event)
@@ -64,30 +85,64 @@ func (s *Service) CreateClient(userID, name, email, company string) (*types.Clie
 	return client, nil
 }
 
-func (s *Service) CreateProject(clientID, userID, name, description string, hourlyRate float64) (*types.Project, error) {
+// CreateProject starts a project. A start date in the future creates it as
+// planned; otherwise it is active from the start date, or now if nil.
+func (s *Service) CreateProject(actor types.Actor, clientID, name, description string, hourlyRate float64, startDate *time.Time) (*types.Project, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	client, err := s.GetClient(actor, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsArchived() {
+		return nil, fmt.Errorf("%w: %s", ErrClientArchived, client.Name)
+	}
+
+	now := time.Now()
+	status, start := types.ProjectStatusActive, now
+	if startDate != nil {
+		start = *startDate
+		if start.After(now) {
+			status = types.ProjectStatusPlanned
+		}
+	}
+
+	currency := "USD"
+	if client.Currency != "" {
+		currency = client.Currency
+	}
+
 	project := &types.Project{
 		ID:          types.GenerateID(),
 		ClientID:    clientID,
-		UserID:      userID,
+		UserID:      actor.UserID,
+		WorkspaceID: actor.WorkspaceID,
 		Name:        name,
 		Description: description,
 		HourlyRate:  hourlyRate,
-		Status:      "active",
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Currency:    currency,
+		Status:      status,
+		StartDate:   start,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 
-	err := s.projectRepo.Create(project)
+	err = s.projectRepo.Create(project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create project: %w", err)
 	}
 
 	event := types.NewEvent("project_created", "client_service", map[string]any{
-		"project_id":  project.ID,
-		"client_id":   project.ClientID,
-		"user_id":     project.UserID,
-		"name":        project.Name,
-		"hourly_rate": project.HourlyRate,
+		"project_id":   project.ID,
+		"client_id":    project.ClientID,
+		"user_id":      project.UserID,
+		"workspace_id": project.WorkspaceID,
+		"name":         project.Name,
+		"hourly_rate":  project.HourlyRate,
+		"status":       project.Status,
+		"start_date":   project.StartDate,
 	})
 
 	s.eventBus.Publish("client.project.started", event)
@@ -96,22 +151,84 @@ func (s *Service) CreateProject(clientID, userID, name, description string, hour
 	return project, nil
 }
 
-func (s *Service) GetClients(userID string) ([]*types.Client, error) {
-	return s.clientRepo.GetByUserID(userID)
+// GetClients returns the workspace's clients that are not archived or deleted
+func (s *Service) GetClients(actor types.Actor) ([]*types.Client, error) {
+	return s.ListClients(actor, false)
+}
+
+// ListClients returns the workspace's clients, with archived ones if includeArchived
+func (s *Service) ListClients(actor types.Actor, includeArchived bool) ([]*types.Client, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+
+	clients, err := s.clientRepo.GetByWorkspaceID(actor.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return activeClients(clients, includeArchived), nil
+}
+
+// GetClient returns one of the workspace's clients unless it is missing or deleted
+func (s *Service) GetClient(actor types.Actor, clientID string) (*types.Client, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+
+	client, err := s.clientRepo.GetByID(actor.WorkspaceID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	if client == nil || client.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: %s", ErrClientNotFound, clientID)
+	}
+	return client, nil
+}
+
+// GetProject returns one of the workspace's projects unless it is missing or deleted
+func (s *Service) GetProject(actor types.Actor, projectID string) (*types.Project, error) {
+	return s.getLiveProject(actor, projectID)
 }
 
-func (s *Service) GetProjects(userID string) ([]*types.Project, error) {
-	return s.projectRepo.GetByUserID(userID)
+// GetProjects returns the workspace's projects that are not archived or deleted
+func (s *Service) GetProjects(actor types.Actor) ([]*types.Project, error) {
+	return s.ListProjects(actor, false)
 }
 
-func (s *Service) GetProjectsByClient(clientID string) ([]*types.Project, error) {
-	return s.projectRepo.GetByClientID(clientID)
+// ListProjects returns the workspace's projects, with archived ones if includeArchived
+func (s *Service) ListProjects(actor types.Actor, includeArchived bool) ([]*types.Project, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projectRepo.GetByWorkspaceID(actor.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return activeProjects(projects, includeArchived), nil
 }
 
-func (s *Service) UpdateClient(clientID string, updates map[string]any) (*types.Client, error) {
-	client, err := s.clientRepo.GetByID(clientID)
+// GetProjectsByClient returns a client's projects, with archived ones if includeArchived
+func (s *Service) GetProjectsByClient(actor types.Actor, clientID string, includeArchived bool) ([]*types.Project, error) {
+	if _, err := s.GetClient(actor, clientID); err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projectRepo.GetByClientID(clientID)
 	if err != nil {
-		return nil, fmt.Errorf("client not found: %w", err)
+		return nil, err
+	}
+	return activeProjects(projects, includeArchived), nil
+}
+
+func (s *Service) UpdateClient(actor types.Actor, clientID string, updates map[string]any) (*types.Client, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	client, err := s.GetClient(actor, clientID)
+	if err != nil {
+		return nil, err
 	}
 
 	if name, ok := updates["name"].(string); ok {
@@ -129,6 +246,9 @@ func (s *Service) UpdateClient(clientID string, updates map[string]any) (*types.
 	if address, ok := updates["address"].(string); ok {
 		client.Address = address
 	}
+	if markup, ok := updates["expense_markup"].(float64); ok && markup >= 0 {
+		client.ExpenseMarkup = markup
+	}
 
 	client.UpdatedAt = time.Now()
 
@@ -138,19 +258,28 @@ func (s *Service) UpdateClient(clientID string, updates map[string]any) (*types.
 	}
 
 	event := types.NewEvent("client_updated", "client_service", map[string]any{
-		"client_id": client.ID,
-		"user_id":   client.UserID,
-		"updates":   updates,
+		"client_id":    client.ID,
+		"user_id":      actor.UserID,
+		"workspace_id": actor.WorkspaceID,
+		"updates":      updates,
 	})
 
 	s.eventBus.Publish("client.updated", event)
 	return client, nil
 }
 
-func (s *Service) UpdateProject(projectID string, updates map[string]any) (*types.Project, error) {
-	project, err := s.projectRepo.GetByID(projectID)
+func (s *Service) UpdateProject(actor types.Actor, projectID string, updates map[string]any) (*types.Project, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	project, err := s.getLiveProject(actor, projectID)
 	if err != nil {
-		return nil, fmt.Errorf("project not found: %w", err)
+		return nil, err
+	}
+	fromStatus := project.Status
+	if fromStatus == "" {
+		fromStatus = types.ProjectStatusActive
 	}
 
 	if name, ok := updates["name"].(string); ok {
@@ -162,8 +291,34 @@ func (s *Service) UpdateProject(projectID string, updates map[string]any) (*type
 	if hourlyRate, ok := updates["hourly_rate"].(float64); ok {
 		project.HourlyRate = hourlyRate
 	}
+	if currency, ok := updates["currency"].(string); ok && currency != "" {
+		project.Currency = strings.ToUpper(currency)
+	}
+	if value, ok := updates["start_date"].(string); ok {
+		date, err := parseDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: start_date: %v", ErrInvalidProject, err)
+		}
+		project.StartDate = date
+	}
+	if value, ok := updates["end_date"]; ok {
+		if value == nil || value == "" {
+			project.EndDate = nil
+		} else if text, ok := value.(string); ok {
+			date, err := parseDate(text)
+			if err != nil {
+				return nil, fmt.Errorf("%w: end_date: %v", ErrInvalidProject, err)
+			}
+			project.EndDate = &date
+		}
+	}
+	if project.EndDate != nil && !project.StartDate.IsZero() && project.EndDate.Before(project.StartDate) {
+		return nil, fmt.Errorf("%w: end_date is before start_date", ErrInvalidProject)
+	}
 	if status, ok := updates["status"].(string); ok {
-		project.Status = status
+		if err := applyProjectStatus(project, status, time.Now()); err != nil {
+			return nil, err
+		}
 	}
 
 	project.UpdatedAt = time.Now()
@@ -174,12 +329,16 @@ func (s *Service) UpdateProject(projectID string, updates map[string]any) (*type
 	}
 
 	event := types.NewEvent("project_updated", "client_service", map[string]any{
-		"project_id": project.ID,
-		"user_id":    project.UserID,
-		"updates":    updates,
+		"project_id":   project.ID,
+		"user_id":      actor.UserID,
+		"workspace_id": actor.WorkspaceID,
+		"updates":      updates,
 	})
 
 	s.eventBus.Publish("client.project.updated", event)
+	if project.Status != fromStatus {
+		s.publishStatusChange(project, fromStatus)
+	}
 	return project, nil
 }
 
@@ -192,3 +351,11 @@ func (s *Service) handleTimeEntryCompleted(event *types.Event) error {
 	log.Printf("⏱️ Time tracked for project: %v", event.Data["project_id"])
 	return nil
 }
+
+// parseDate accepts RFC 3339 timestamps and plain YYYY-MM-DD dates
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,371 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"datastar-go/internal/shared/types"
+)
+
+// vcardLineLimit is the RFC 6350 line length in octets before folding
+const vcardLineLimit = 75
+
+// VCardImportResult summarizes a vCard import
+type VCardImportResult struct {
+	Created []*types.Contact `json:"created"`
+	Updated []*types.Contact `json:"updated"`
+	Skipped []string         `json:"skipped,omitempty"` // reason per card that was not imported
+}
+
+// vcardProperty is one content line: [group.]NAME;PARAM=VALUE:value
+type vcardProperty struct {
+	name   string
+	params map[string][]string
+	value  string // raw, still escaped
+}
+
+// ExportVCard writes a client's contacts as vCard 4.0 (RFC 6350). With a
+// contactID only that contact is exported.
+func (s *Service) ExportVCard(actor types.Actor, clientID, contactID string, w io.Writer) error {
+	client, err := s.GetClient(actor, clientID)
+	if err != nil {
+		return ErrClientNotFound
+	}
+
+	contacts, err := s.GetContacts(actor, clientID)
+	if err != nil {
+		return fmt.Errorf("failed to get contacts: %w", err)
+	}
+
+	for _, contact := range contacts {
+		if contactID != "" && contact.ID != contactID {
+			continue
+		}
+		if err := writeVCard(w, client, contact); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportVCard creates contacts from vCard 3.0/4.0 data. Cards whose email
+// matches an existing contact of the client update that contact instead.
+func (s *Service) ImportVCard(actor types.Actor, clientID string, r io.Reader) (*VCardImportResult, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.GetClient(actor, clientID); err != nil {
+		return nil, ErrClientNotFound
+	}
+
+	cards, err := parseVCards(r)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.contactRepo.GetByClientID(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contacts: %w", err)
+	}
+
+	result := &VCardImportResult{}
+	for i, card := range cards {
+		contact := contactFromVCard(card)
+
+		var match *types.Contact
+		for _, c := range existing {
+			if strings.EqualFold(c.Email, contact.Email) {
+				match = c
+				break
+			}
+		}
+
+		if match != nil {
+			if len(contact.Roles) == 0 {
+				contact.Roles = match.Roles
+			}
+			updated, err := s.UpdateContact(actor, clientID, match.ID, contact)
+			if err != nil {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("card %d (%s): %v", i+1, contact.Name, err))
+				continue
+			}
+			result.Updated = append(result.Updated, updated)
+			continue
+		}
+
+		created, err := s.CreateContact(actor, clientID, contact)
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("card %d (%s): %v", i+1, contact.Name, err))
+			continue
+		}
+		existing = append(existing, created)
+		result.Created = append(result.Created, created)
+	}
+
+	return result, nil
+}
+
+func writeVCard(w io.Writer, client *types.Client, contact *types.Contact) error {
+	var b strings.Builder
+	line := func(name, value string) {
+		writeFolded(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCARD")
+	line("VERSION", "4.0")
+	line("UID", "urn:uuid:"+contact.ID)
+	line("FN", vcardEscape(contact.Name))
+
+	given, family := splitName(contact.Name)
+	line("N", vcardEscape(family)+";"+vcardEscape(given)+";;;")
+
+	if org := client.Company; org != "" {
+		line("ORG", vcardEscape(org))
+	} else if client.Name != "" {
+		line("ORG", vcardEscape(client.Name))
+	}
+	if contact.Title != "" {
+		line("TITLE", vcardEscape(contact.Title))
+	}
+	line("EMAIL;TYPE=work", vcardEscape(contact.Email))
+	if contact.Phone != "" {
+		line("TEL;VALUE=uri;TYPE=work", "tel:"+telURI(contact.Phone))
+	}
+	if len(contact.Roles) > 0 {
+		escaped := make([]string, len(contact.Roles))
+		for i, role := range contact.Roles {
+			escaped[i] = vcardEscape(role)
+		}
+		line("CATEGORIES", strings.Join(escaped, ","))
+	}
+	if contact.Notes != "" {
+		line("NOTE", vcardEscape(contact.Notes))
+	}
+	line("REV", contact.UpdatedAt.UTC().Format("20060102T150405Z"))
+	line("END", "VCARD")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeFolded writes a content line, folding it at 75 octets without
+// splitting UTF-8 sequences
+func writeFolded(b *strings.Builder, line string) {
+	limit := vcardLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = vcardLineLimit - 1 // continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func parseVCards(r io.Reader) ([][]vcardProperty, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	// Unfold: a line starting with a space or tab continues the previous one
+	var lines []string
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += text[1:]
+			continue
+		}
+		if text != "" {
+			lines = append(lines, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vCard: %w", err)
+	}
+
+	var cards [][]vcardProperty
+	var current []vcardProperty
+	inCard := false
+	for _, text := range lines {
+		prop, ok := parseVCardLine(text)
+		if !ok {
+			continue
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VCARD"):
+			inCard, current = true, nil
+		case prop.name == "END" && strings.EqualFold(prop.value, "VCARD"):
+			if inCard {
+				cards = append(cards, current)
+			}
+			inCard = false
+		case inCard:
+			current = append(current, prop)
+		}
+	}
+
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("no vCards found")
+	}
+	return cards, nil
+}
+
+func parseVCardLine(text string) (vcardProperty, bool) {
+	// The value starts at the first colon outside a quoted parameter value
+	colon, quoted := -1, false
+	for i, r := range text {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return vcardProperty{}, false
+	}
+
+	parts := strings.Split(text[:colon], ";")
+	name := strings.ToUpper(parts[0])
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		name = name[dot+1:] // drop the group
+	}
+
+	params := map[string][]string{}
+	for _, param := range parts[1:] {
+		key, value, found := strings.Cut(param, "=")
+		key = strings.ToUpper(key)
+		if !found {
+			// vCard 2.1/3.0 bare type, e.g. EMAIL;WORK
+			key, value = "TYPE", param
+		}
+		for _, v := range strings.Split(value, ",") {
+			params[key] = append(params[key], strings.ToLower(strings.Trim(v, `"`)))
+		}
+	}
+
+	return vcardProperty{name: name, params: params, value: text[colon+1:]}, true
+}
+
+func contactFromVCard(props []vcardProperty) types.Contact {
+	var contact types.Contact
+	var n string
+	emailPref := 101
+
+	for _, prop := range props {
+		switch prop.name {
+		case "FN":
+			contact.Name = vcardUnescape(prop.value)
+		case "N":
+			n = prop.value
+		case "EMAIL":
+			// Keep the most preferred address (PREF=1 in 4.0, TYPE=pref in 3.0)
+			pref := 100
+			if slices.Contains(prop.params["TYPE"], "pref") {
+				pref = 1
+			}
+			if p := prop.params["PREF"]; len(p) > 0 {
+				fmt.Sscanf(p[0], "%d", &pref)
+			}
+			if pref < emailPref {
+				contact.Email = vcardUnescape(prop.value)
+				emailPref = pref
+			}
+		case "TEL":
+			if contact.Phone == "" {
+				contact.Phone = strings.TrimPrefix(vcardUnescape(prop.value), "tel:")
+			}
+		case "TITLE":
+			contact.Title = vcardUnescape(prop.value)
+		case "NOTE":
+			contact.Notes = vcardUnescape(prop.value)
+		case "CATEGORIES":
+			for _, category := range splitUnescaped(prop.value, ',') {
+				role := strings.ToLower(strings.TrimSpace(vcardUnescape(category)))
+				if slices.Contains(validContactRoles, role) {
+					contact.Roles = append(contact.Roles, role)
+				}
+			}
+		}
+	}
+
+	if contact.Name == "" && n != "" {
+		fields := splitUnescaped(n, ';')
+		var parts []string
+		for _, i := range []int{3, 1, 2, 0, 4} { // prefix, given, additional, family, suffix
+			if i < len(fields) {
+				if v := strings.TrimSpace(vcardUnescape(fields[i])); v != "" {
+					parts = append(parts, v)
+				}
+			}
+		}
+		contact.Name = strings.Join(parts, " ")
+	}
+	return contact
+}
+
+func vcardEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func vcardUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// splitUnescaped splits on sep where it is not escaped with a backslash
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == sep {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// splitName treats the last word as the family name
+func splitName(name string) (given, family string) {
+	fields := strings.Fields(name)
+	if len(fields) < 2 {
+		return name, ""
+	}
+	return strings.Join(fields[:len(fields)-1], " "), fields[len(fields)-1]
+}
+
+// telURI strips formatting from a phone number for a tel: URI
+func telURI(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '+' || r == '*' || r == '#' {
+			return r
+		}
+		return -1
+	}, phone)
+}
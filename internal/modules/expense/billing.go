@@ -0,0 +1,134 @@
+package expense
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+var ErrExpenseBilled = errors.New("expense is already billed")
+
+// GetBillableExpenses returns the workspace's billable expenses for a project
+// that have not been put on an invoice yet, oldest first
+func (s *Service) GetBillableExpenses(actor types.Actor, projectID string) ([]*types.Expense, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	expenses, err := s.repo.GetByProjectID(actor.WorkspaceID, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project expenses: %w", err)
+	}
+
+	var billable []*types.Expense
+	for _, expense := range expenses {
+		if expense.IsBillable && !expense.IsBilled {
+			billable = append(billable, expense)
+		}
+	}
+
+	sort.Slice(billable, func(i, j int) bool {
+		return expenseDate(billable[i]).Before(expenseDate(billable[j]))
+	})
+	return billable, nil
+}
+
+// GetExpense returns one of the workspace's expenses
+func (s *Service) GetExpense(actor types.Actor, expenseID string) (*types.Expense, error) {
+	expense, err := s.getOwnedExpense(actor, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, expenseID)
+	}
+	return expense, nil
+}
+
+// MarkExpensesBilled flags expenses as billed on an invoice. Nothing is
+// changed unless every expense is billable and not billed elsewhere.
+func (s *Service) MarkExpensesBilled(actor types.Actor, invoiceID string, expenseIDs []string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+	expenses := make([]*types.Expense, 0, len(expenseIDs))
+	for _, id := range expenseIDs {
+		expense, err := s.getOwnedExpense(actor, id)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, id)
+		}
+		if !expense.IsBillable {
+			return fmt.Errorf("expense %s is not billable", id)
+		}
+		if expense.IsBilled && expense.InvoiceID != invoiceID {
+			return fmt.Errorf("%w: %s", ErrExpenseBilled, id)
+		}
+		expenses = append(expenses, expense)
+	}
+
+	for _, expense := range expenses {
+		expense.IsBilled = true
+		expense.InvoiceID = invoiceID
+		expense.UpdatedAt = time.Now()
+
+		if err := s.repo.Update(expense); err != nil {
+			return fmt.Errorf("failed to update expense: %w", err)
+		}
+	}
+
+	event := types.NewEvent("expenses_billed", "expense_service", map[string]any{
+		"invoice_id":   invoiceID,
+		"user_id":      actor.UserID,
+		"workspace_id": actor.WorkspaceID,
+		"expense_ids":  expenseIDs,
+	})
+
+	s.eventBus.Publish("expense.billed", event)
+	return nil
+}
+
+// MarkExpensesUnbilled releases expenses from an invoice so they can be billed again
+func (s *Service) MarkExpensesUnbilled(actor types.Actor, invoiceID string, expenseIDs []string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+	var released []string
+	for _, id := range expenseIDs {
+		expense, err := s.getOwnedExpense(actor, id)
+		if errors.Is(err, ErrExpenseNotFound) {
+			continue // deleted since it was billed; nothing to release
+		}
+		if err != nil {
+			return err
+		}
+		if expense.InvoiceID != invoiceID {
+			continue
+		}
+
+		expense.IsBilled = false
+		expense.InvoiceID = ""
+		expense.UpdatedAt = time.Now()
+
+		if err := s.repo.Update(expense); err != nil {
+			return fmt.Errorf("failed to update expense: %w", err)
+		}
+		released = append(released, id)
+	}
+
+	event := types.NewEvent("expenses_unbilled", "expense_service", map[string]any{
+		"invoice_id":   invoiceID,
+		"user_id":      actor.UserID,
+		"workspace_id": actor.WorkspaceID,
+		"expense_ids":  released,
+	})
+
+	s.eventBus.Publish("expense.unbilled", event)
+	return nil
+}
+
+// expenseDate falls back to the creation time for expenses recorded without a date
+func expenseDate(expense *types.Expense) time.Time {
+	if expense.Date.IsZero() {
+		return expense.CreatedAt
+	}
+	return expense.Date
+}
@@ -0,0 +1,104 @@
+package expense
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps files on local disk named after the SHA-256 of their content,
+// so identical uploads share a single copy
+type BlobStore struct {
+	root string
+}
+
+// NewBlobStore creates a blob store rooted at the given directory
+func NewBlobStore(root string) (*BlobStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob store directory: %w", err)
+	}
+	return &BlobStore{root: root}, nil
+}
+
+// Put stores data and returns its hex-encoded SHA-256 hash
+func (b *BlobStore) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path, err := b.path(hash)
+	if err != nil {
+		return "", err
+	}
+
+	// Content-addressed: an existing file already holds these bytes
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Open opens the blob with the given hash for reading
+func (b *BlobStore) Open(hash string) (*os.File, error) {
+	path, err := b.path(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return file, err
+}
+
+// Delete removes the blob with the given hash, ignoring missing blobs
+func (b *BlobStore) Delete(hash string) error {
+	path, err := b.path(hash)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// path maps a hash to root/ab/cd/abcd... and rejects anything that is not a hash
+func (b *BlobStore) path(hash string) (string, error) {
+	if len(hash) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid blob hash")
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", fmt.Errorf("invalid blob hash")
+	}
+	return filepath.Join(b.root, hash[0:2], hash[2:4], hash), nil
+}
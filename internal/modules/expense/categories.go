@@ -0,0 +1,583 @@
+package expense
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+// learnThreshold is how many matching corrections it takes to propose a rule
+const learnThreshold = 2
+
+// Rule statuses
+const (
+	RuleActive   = "active"
+	RuleProposed = "proposed"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrRuleNotFound     = errors.New("rule not found")
+)
+
+// Category is an entry in a workspace's expense category catalog. Catalogs,
+// rules, corrections and settings are shared by a workspace, so their UserID
+// holds the workspace ID.
+type Category struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	Name        string    `json:"name"`
+	TaxCategory string    `json:"tax_category,omitempty"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CategoryRule assigns a catalog category to expenses that match every set criterion
+type CategoryRule struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	Name        string    `json:"name"`
+	Priority    int       `json:"priority"` // lower runs first
+	PayeeRegex  string    `json:"payee_regex,omitempty"`
+	MinAmount   *float64  `json:"min_amount,omitempty"`
+	MaxAmount   *float64  `json:"max_amount,omitempty"`
+	Account     string    `json:"account,omitempty"`
+	Category    string    `json:"category"`
+	TaxCategory string    `json:"tax_category,omitempty"`
+	Status      string    `json:"status"` // active, proposed
+	Source      string    `json:"source"` // user, learned
+	CreatedAt   time.Time `json:"created_at"`
+
+	payee *regexp.Regexp
+}
+
+// CategoryCorrection records a user changing the category the system chose
+type CategoryCorrection struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	Payee       string    `json:"payee"`
+	Category    string    `json:"category"`
+	TaxCategory string    `json:"tax_category,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CategorizationSettings holds per-workspace categorization preferences
+type CategorizationSettings struct {
+	UserID               string `json:"user_id"`
+	LearnFromCorrections bool   `json:"learn_from_corrections"`
+}
+
+// RecategorizeRequest selects expenses to re-categorize. Without an explicit
+// category the active rules are applied.
+type RecategorizeRequest struct {
+	ExpenseIDs        []string `json:"expense_ids,omitempty"` // empty means all of the workspace's expenses
+	Category          *string  `json:"category,omitempty"`
+	TaxCategory       *string  `json:"tax_category,omitempty"`
+	OnlyUncategorized bool     `json:"only_uncategorized"`
+	DryRun            bool     `json:"dry_run"`
+}
+
+// RecategorizeChange describes one expense whose category changed
+type RecategorizeChange struct {
+	ExpenseID      string `json:"expense_id"`
+	OldCategory    string `json:"old_category"`
+	NewCategory    string `json:"new_category"`
+	OldTaxCategory string `json:"old_tax_category"`
+	NewTaxCategory string `json:"new_tax_category"`
+	RuleID         string `json:"rule_id,omitempty"`
+}
+
+// compilePayeeRegex compiles a rule's payee regex, case-insensitively
+func compilePayeeRegex(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + expr)
+}
+
+// payeePattern returns the rule's compiled payee regex. Rules are compiled
+// when the repository loads them, so a batch of matches compiles each once.
+func (r *CategoryRule) payeePattern() (*regexp.Regexp, error) {
+	if r.payee == nil || r.payee.String() != "(?i)"+r.PayeeRegex {
+		re, err := compilePayeeRegex(r.PayeeRegex)
+		if err != nil {
+			return nil, err
+		}
+		r.payee = re
+	}
+	return r.payee, nil
+}
+
+// Matches reports whether the rule applies to a transaction
+func (r *CategoryRule) Matches(payee string, amount float64, account string) bool {
+	if r.PayeeRegex != "" {
+		re, err := r.payeePattern()
+		if err != nil || !re.MatchString(payee) {
+			return false
+		}
+	}
+	if r.MinAmount != nil && amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && amount > *r.MaxAmount {
+		return false
+	}
+	if r.Account != "" && !strings.EqualFold(r.Account, account) {
+		return false
+	}
+	return true
+}
+
+func (s *Service) CreateCategory(actor types.Actor, name, taxCategory, description string) (*Category, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("category name is required")
+	}
+
+	existing, err := s.repo.GetCategoriesByUserID(actor.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range existing {
+		if strings.EqualFold(c.Name, name) {
+			return nil, fmt.Errorf("category %q already exists", name)
+		}
+	}
+
+	category := &Category{
+		ID:          types.GenerateID(),
+		UserID:      actor.WorkspaceID,
+		Name:        name,
+		TaxCategory: taxCategory,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.repo.SaveCategory(category); err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	return category, nil
+}
+
+func (s *Service) GetCategories(actor types.Actor) ([]*Category, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	categories, err := s.repo.GetCategoriesByUserID(actor.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return strings.ToLower(categories[i].Name) < strings.ToLower(categories[j].Name)
+	})
+	return categories, nil
+}
+
+func (s *Service) DeleteCategory(actor types.Actor, categoryID string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+	categories, err := s.repo.GetCategoriesByUserID(actor.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	var category *Category
+	for _, c := range categories {
+		if c.ID == categoryID {
+			category = c
+		}
+	}
+	if category == nil {
+		return ErrCategoryNotFound
+	}
+
+	rules, err := s.repo.GetRulesByUserID(actor.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if strings.EqualFold(rule.Category, category.Name) {
+			return fmt.Errorf("category %q is used by rule %q", category.Name, rule.Name)
+		}
+	}
+
+	return s.repo.DeleteCategory(actor.WorkspaceID, categoryID)
+}
+
+func (s *Service) CreateRule(actor types.Actor, rule CategoryRule) (*CategoryRule, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	if rule.PayeeRegex == "" && rule.MinAmount == nil && rule.MaxAmount == nil && rule.Account == "" {
+		return nil, fmt.Errorf("rule needs at least one of payee_regex, min_amount, max_amount or account")
+	}
+	if rule.PayeeRegex != "" {
+		if _, err := compilePayeeRegex(rule.PayeeRegex); err != nil {
+			return nil, fmt.Errorf("invalid payee_regex: %w", err)
+		}
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return nil, fmt.Errorf("min_amount must not exceed max_amount")
+	}
+
+	category, err := s.findCategory(actor.WorkspaceID, rule.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ID = types.GenerateID()
+	rule.UserID = actor.WorkspaceID
+	rule.Category = category.Name
+	if rule.TaxCategory == "" {
+		rule.TaxCategory = category.TaxCategory
+	}
+	if rule.Name == "" {
+		rule.Name = category.Name
+	}
+	rule.Status = RuleActive
+	rule.Source = "user"
+	rule.CreatedAt = time.Now()
+
+	if err := s.repo.SaveRule(&rule); err != nil {
+		return nil, fmt.Errorf("failed to create rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// GetRules lists rules in evaluation order, optionally filtered by status
+func (s *Service) GetRules(actor types.Actor, status string) ([]*CategoryRule, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	return s.rules(actor.WorkspaceID, status)
+}
+
+// rules lists a workspace's rules in evaluation order
+func (s *Service) rules(workspaceID, status string) ([]*CategoryRule, error) {
+	rules, err := s.repo.GetRulesByUserID(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := rules[:0]
+	for _, rule := range rules {
+		if status == "" || rule.Status == status {
+			filtered = append(filtered, rule)
+		}
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if filtered[i].Priority != filtered[j].Priority {
+			return filtered[i].Priority < filtered[j].Priority
+		}
+		return filtered[i].CreatedAt.Before(filtered[j].CreatedAt)
+	})
+	return filtered, nil
+}
+
+func (s *Service) DeleteRule(actor types.Actor, ruleID string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetRule(actor.WorkspaceID, ruleID); err != nil {
+		return ErrRuleNotFound
+	}
+	return s.repo.DeleteRule(actor.WorkspaceID, ruleID)
+}
+
+// AcceptRule activates a rule proposed by learning mode
+func (s *Service) AcceptRule(actor types.Actor, ruleID string) (*CategoryRule, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	rule, err := s.repo.GetRule(actor.WorkspaceID, ruleID)
+	if err != nil {
+		return nil, ErrRuleNotFound
+	}
+
+	rule.Status = RuleActive
+	if err := s.repo.SaveRule(rule); err != nil {
+		return nil, fmt.Errorf("failed to accept rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (s *Service) GetCategorizationSettings(actor types.Actor) (*CategorizationSettings, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	return s.repo.GetCategorizationSettings(actor.WorkspaceID)
+}
+
+func (s *Service) UpdateCategorizationSettings(actor types.Actor, learnFromCorrections bool) (*CategorizationSettings, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	settings := &CategorizationSettings{
+		UserID:               actor.WorkspaceID,
+		LearnFromCorrections: learnFromCorrections,
+	}
+	if err := s.repo.SaveCategorizationSettings(settings); err != nil {
+		return nil, fmt.Errorf("failed to save settings: %w", err)
+	}
+	return settings, nil
+}
+
+// Recategorize sets or re-derives categories for many expenses at once
+func (s *Service) Recategorize(actor types.Actor, req RecategorizeRequest) ([]RecategorizeChange, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	expenses, err := s.repo.GetByWorkspaceID(actor.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.ExpenseIDs) > 0 {
+		wanted := make(map[string]bool, len(req.ExpenseIDs))
+		for _, id := range req.ExpenseIDs {
+			wanted[id] = true
+		}
+		selected := expenses[:0]
+		for _, expense := range expenses {
+			if wanted[expense.ID] {
+				selected = append(selected, expense)
+			}
+		}
+		expenses = selected
+	}
+
+	var explicit *Category
+	if req.Category != nil {
+		if explicit, err = s.findCategory(actor.WorkspaceID, *req.Category); err != nil {
+			return nil, err
+		}
+	}
+
+	rules, err := s.rules(actor.WorkspaceID, RuleActive)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]RecategorizeChange, 0)
+	for _, expense := range expenses {
+		if req.OnlyUncategorized && expense.Category != "" {
+			continue
+		}
+
+		change := RecategorizeChange{
+			ExpenseID:      expense.ID,
+			OldCategory:    expense.Category,
+			OldTaxCategory: expense.TaxCategory,
+		}
+
+		if explicit != nil {
+			change.NewCategory = explicit.Name
+			change.NewTaxCategory = explicit.TaxCategory
+			if req.TaxCategory != nil {
+				change.NewTaxCategory = *req.TaxCategory
+			}
+		} else {
+			rule := matchRule(rules, expensePayee(expense), expense.Amount, expense.Account)
+			if rule == nil {
+				continue
+			}
+			change.NewCategory = rule.Category
+			change.NewTaxCategory = rule.TaxCategory
+			change.RuleID = rule.ID
+		}
+
+		if change.NewCategory == change.OldCategory && change.NewTaxCategory == change.OldTaxCategory {
+			continue
+		}
+		changes = append(changes, change)
+
+		if req.DryRun {
+			continue
+		}
+
+		expense.Category = change.NewCategory
+		expense.TaxCategory = change.NewTaxCategory
+		expense.UpdatedAt = time.Now()
+		if err := s.repo.Update(expense); err != nil {
+			return nil, fmt.Errorf("failed to update expense %s: %w", expense.ID, err)
+		}
+	}
+
+	if !req.DryRun && len(changes) > 0 {
+		ids := make([]string, len(changes))
+		for i, change := range changes {
+			ids[i] = change.ExpenseID
+		}
+
+		event := types.NewEvent("expenses_recategorized", "expense_service", map[string]any{
+			"user_id":      actor.UserID,
+			"workspace_id": actor.WorkspaceID,
+			"expense_ids":  ids,
+			"count":        len(changes),
+		})
+
+		s.eventBus.Publish("expense.recategorized", event)
+		log.Printf("🏷️ Re-categorized %d expenses", len(changes))
+	}
+
+	return changes, nil
+}
+
+// categorize applies the workspace's active rules, falling back to the
+// catalog's tax category when only a category name is known
+func (s *Service) categorize(workspaceID, payee string, amount float64, account string) (*CategoryRule, error) {
+	rules, err := s.rules(workspaceID, RuleActive)
+	if err != nil {
+		return nil, err
+	}
+	return matchRule(rules, payee, amount, account), nil
+}
+
+// applyCategorization fills in category and tax category on a new expense
+func (s *Service) applyCategorization(expense *types.Expense) {
+	workspaceID := types.WorkspaceOf(expense.WorkspaceID, expense.UserID)
+	if expense.Category == "" {
+		rule, err := s.categorize(workspaceID, expensePayee(expense), expense.Amount, expense.Account)
+		if err != nil {
+			log.Printf("Failed to apply category rules: %v", err)
+			return
+		}
+		if rule != nil {
+			expense.Category = rule.Category
+			if expense.TaxCategory == "" {
+				expense.TaxCategory = rule.TaxCategory
+			}
+		}
+	}
+
+	if expense.Category != "" && expense.TaxCategory == "" {
+		if category, err := s.findCategory(workspaceID, expense.Category); err == nil {
+			expense.TaxCategory = category.TaxCategory
+		}
+	}
+}
+
+// recordCorrection remembers a manual category change and proposes a rule
+// once the same payee has been corrected to the same category often enough
+func (s *Service) recordCorrection(workspaceID, payee, category, taxCategory string) {
+	settings, err := s.repo.GetCategorizationSettings(workspaceID)
+	if err != nil || !settings.LearnFromCorrections {
+		return
+	}
+
+	key := normalizePayee(payee)
+	if key == "" || category == "" {
+		return
+	}
+
+	correction := &CategoryCorrection{
+		ID:          types.GenerateID(),
+		UserID:      workspaceID,
+		Payee:       key,
+		Category:    category,
+		TaxCategory: taxCategory,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.SaveCorrection(correction); err != nil {
+		log.Printf("Failed to record category correction: %v", err)
+		return
+	}
+
+	corrections, err := s.repo.GetCorrectionsByUserID(workspaceID)
+	if err != nil {
+		return
+	}
+	count := 0
+	for _, c := range corrections {
+		if c.Payee == key && strings.EqualFold(c.Category, category) {
+			count++
+		}
+	}
+	if count < learnThreshold {
+		return
+	}
+
+	// Don't propose what an existing rule already does
+	rules, err := s.repo.GetRulesByUserID(workspaceID)
+	if err != nil {
+		return
+	}
+	for _, rule := range rules {
+		if strings.EqualFold(rule.Category, category) && rule.Matches(payee, 0, "") && rule.MinAmount == nil && rule.MaxAmount == nil {
+			return
+		}
+	}
+
+	rule := &CategoryRule{
+		ID:          types.GenerateID(),
+		UserID:      workspaceID,
+		Name:        fmt.Sprintf("%s → %s", key, category),
+		Priority:    100,
+		PayeeRegex:  strings.Join(strings.Fields(regexp.QuoteMeta(key)), `\W+`),
+		Category:    category,
+		TaxCategory: taxCategory,
+		Status:      RuleProposed,
+		Source:      "learned",
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.SaveRule(rule); err != nil {
+		log.Printf("Failed to save proposed rule: %v", err)
+		return
+	}
+
+	event := types.NewEvent("rule_proposed", "expense_service", map[string]any{
+		"rule_id":      rule.ID,
+		"workspace_id": workspaceID,
+		"payee":        key,
+		"category":     category,
+	})
+
+	s.eventBus.Publish("expense.rule.proposed", event)
+	log.Printf("🏷️ Proposed category rule: %s", rule.Name)
+}
+
+func (s *Service) findCategory(workspaceID, name string) (*Category, error) {
+	categories, err := s.repo.GetCategoriesByUserID(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		if strings.EqualFold(c.Name, strings.TrimSpace(name)) {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q is not in the category catalog", ErrCategoryNotFound, name)
+}
+
+func matchRule(rules []*CategoryRule, payee string, amount float64, account string) *CategoryRule {
+	for _, rule := range rules {
+		if rule.Status == RuleActive && rule.Matches(payee, amount, account) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// expensePayee falls back to the description for manually entered expenses
+func expensePayee(expense *types.Expense) string {
+	if expense.Payee != "" {
+		return expense.Payee
+	}
+	return expense.Description
+}
+
+var payeeNoise = regexp.MustCompile(`[^\p{L}\s]+`)
+
+// normalizePayee strips digits and punctuation so "UBER *TRIP 4412" and
+// "Uber Trip 9921" learn as the same payee
+func normalizePayee(payee string) string {
+	return strings.Join(strings.Fields(strings.ToLower(payeeNoise.ReplaceAllString(payee, " "))), " ")
+}
@@ -0,0 +1,42 @@
+package expense
+
+import "testing"
+
+func TestLoadedRulesCarryCompiledPayeeRegex(t *testing.T) {
+	s := newTestService(t)
+	for _, rule := range []*CategoryRule{
+		{ID: "uber", UserID: "user-1", PayeeRegex: `uber\W+trip`, Category: "Travel", Status: RuleActive},
+		{ID: "broken", UserID: "user-1", PayeeRegex: `(`, Category: "Meals", Status: RuleActive},
+	} {
+		if err := s.repo.SaveRule(rule); err != nil {
+			t.Fatalf("SaveRule: %v", err)
+		}
+	}
+
+	rules, err := s.repo.GetRulesByUserID("user-1")
+	if err != nil {
+		t.Fatalf("GetRulesByUserID: %v", err)
+	}
+	for _, rule := range rules {
+		switch rule.ID {
+		case "uber":
+			if rule.payee == nil {
+				t.Error("loaded rule has no compiled regex")
+			}
+			if !rule.Matches("UBER *TRIP 4412", 12, "") {
+				t.Error("rule does not match case-insensitively")
+			}
+		case "broken":
+			if rule.Matches("(", 12, "") {
+				t.Error("invalid regex matched")
+			}
+		}
+	}
+
+	// A changed pattern is recompiled rather than served from the old one
+	rule := rules[0]
+	rule.PayeeRegex = "lyft"
+	if rule.Matches("UBER TRIP", 12, "") || !rule.Matches("Lyft ride", 12, "") {
+		t.Error("stale compiled regex used after the pattern changed")
+	}
+}
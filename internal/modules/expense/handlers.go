@@ -1,10 +1,16 @@
 package expense
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"time"
 
+	"datastar-go/internal/modules/auth"
 	"datastar-go/internal/shared/types"
 )
 
@@ -17,12 +23,19 @@ func NewHandlers(service *Service) *Handlers {
 }
 
 func (h *Handlers) handleCreateExpense(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
-		UserID      string  `json:"user_id"`
-		ProjectID   string  `json:"project_id"`
-		Category    string  `json:"category"`
-		Description string  `json:"description"`
-		Amount      float64 `json:"amount"`
+		ProjectID   string    `json:"project_id"`
+		Category    string    `json:"category"`
+		Description string    `json:"description"`
+		Amount      float64   `json:"amount"`
+		IsBillable  bool      `json:"is_billable"`
+		Date        time.Time `json:"date"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -30,14 +43,14 @@ func (h *Handlers) handleCreateExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.UserID == "" || req.Description == "" || req.Amount <= 0 {
+	if req.Description == "" || req.Amount <= 0 {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
 
-	expense, err := h.service.CreateExpense(req.UserID, req.ProjectID, req.Category, req.Description, req.Amount)
+	expense, err := h.service.CreateExpense(actor, req.ProjectID, req.Category, req.Description, req.Amount, req.IsBillable, req.Date)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), projectErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -51,15 +64,15 @@ func (h *Handlers) handleCreateExpense(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) handleGetExpenses(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "user_id required", http.StatusBadRequest)
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	expenses, err := h.service.GetExpenses(userID)
+	expenses, err := h.service.GetExpenses(actor)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -73,15 +86,21 @@ func (h *Handlers) handleGetExpenses(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) handleGetProjectExpenses(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	projectID := r.URL.Query().Get("project_id")
 	if projectID == "" {
 		http.Error(w, "project_id required", http.StatusBadRequest)
 		return
 	}
 
-	expenses, err := h.service.GetExpensesByProject(projectID)
+	expenses, err := h.service.GetExpensesByProject(actor, projectID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -94,10 +113,12 @@ func (h *Handlers) handleGetProjectExpenses(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleUpdateExpense accepts {"expense_id": ..., "updates": {...}} where
+// updates is a JSON Merge Patch of the expense
 func (h *Handlers) handleUpdateExpense(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		ExpenseID string         `json:"expense_id"`
-		Updates   map[string]any `json:"updates"`
+		ExpenseID string          `json:"expense_id"`
+		Updates   json.RawMessage `json:"updates"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -110,9 +131,38 @@ func (h *Handlers) handleUpdateExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expense, err := h.service.UpdateExpense(req.ExpenseID, req.Updates)
+	h.updateExpense(w, r, req.ExpenseID, bytes.NewReader(req.Updates))
+}
+
+// handlePatchExpense accepts an application/merge-patch+json body for ?expense_id=
+func (h *Handlers) handlePatchExpense(w http.ResponseWriter, r *http.Request) {
+	expenseID := r.URL.Query().Get("expense_id")
+	if expenseID == "" {
+		http.Error(w, "expense_id required", http.StatusBadRequest)
+		return
+	}
+
+	h.updateExpense(w, r, expenseID, r.Body)
+}
+
+func (h *Handlers) updateExpense(w http.ResponseWriter, r *http.Request, expenseID string, body io.Reader) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var patch ExpensePatch
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		http.Error(w, "Invalid patch: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.UpdateExpense(actor, expenseID, patch)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), updateErrorStatus(err))
 		return
 	}
 
@@ -125,16 +175,34 @@ func (h *Handlers) handleUpdateExpense(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func updateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrExpenseNotFound), errors.Is(err, ErrProjectNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidPatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrExpenseBilled):
+		return http.StatusConflict
+	}
+	return auth.ErrorStatus(err, http.StatusInternalServerError)
+}
+
 func (h *Handlers) handleDeleteExpense(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	expenseID := r.URL.Query().Get("expense_id")
 	if expenseID == "" {
 		http.Error(w, "expense_id required", http.StatusBadRequest)
 		return
 	}
 
-	err := h.service.DeleteExpense(expenseID)
+	err := h.service.DeleteExpense(actor, expenseID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), updateErrorStatus(err))
 		return
 	}
 
@@ -147,6 +215,933 @@ func (h *Handlers) handleDeleteExpense(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *Handlers) handleUploadReceipt(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Leave headroom for the multipart envelope around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, MaxReceiptSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, ErrReceiptTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	expenseID := r.FormValue("expense_id")
+	if expenseID == "" {
+		http.Error(w, "expense_id required", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("receipt")
+	if err != nil {
+		http.Error(w, "receipt file required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	expense, err := h.service.AttachReceipt(actor, expenseID, header.Filename, file)
+	if err != nil {
+		http.Error(w, err.Error(), receiptErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    expense,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetReceipt(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	expenseID := r.URL.Query().Get("expense_id")
+	if expenseID == "" {
+		http.Error(w, "expense_id required", http.StatusBadRequest)
+		return
+	}
+
+	thumbnail, _ := strconv.ParseBool(r.URL.Query().Get("thumbnail"))
+
+	file, receipt, err := h.service.OpenReceipt(actor, expenseID, thumbnail)
+	if err != nil {
+		http.Error(w, err.Error(), receiptErrorStatus(err))
+		return
+	}
+	defer file.Close()
+
+	contentType := receipt.ContentType
+	if thumbnail {
+		contentType = "image/jpeg"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", receipt.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, receipt.FileName, receipt.UploadedAt, file)
+}
+
+func (h *Handlers) handleDeleteReceipt(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	expenseID := r.URL.Query().Get("expense_id")
+	if expenseID == "" {
+		http.Error(w, "expense_id required", http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.RemoveReceipt(actor, expenseID)
+	if err != nil {
+		http.Error(w, err.Error(), receiptErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    expense,
+		Message: "Receipt removed successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleStageImport(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxStatementSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("statement")
+	if err != nil {
+		http.Error(w, "statement file required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxStatementSize+1))
+	if err != nil {
+		http.Error(w, "Failed to read statement", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.service.StageImport(actor, r.FormValue("format"), r.FormValue("profile"), r.FormValue("account"), header.Filename, data)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    batch,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetImport(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	importID := r.URL.Query().Get("import_id")
+	if importID == "" {
+		http.Error(w, "import_id required", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.service.GetImport(actor, importID)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusNotFound))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    batch,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetImports(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	batches, err := h.service.GetImports(actor)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    batches,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleReviewImport(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ImportID string        `json:"import_id"`
+		Rows     []RowDecision `json:"rows"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ImportID == "" || len(req.Rows) == 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.service.ReviewImport(actor, req.ImportID, req.Rows)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrImportNotFound) || errors.Is(err, ErrProjectNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), auth.ErrorStatus(err, status))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    batch,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleCommitImport(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ImportID string `json:"import_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.ImportID == "" {
+		http.Error(w, "import_id required", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.service.CommitImport(actor, req.ImportID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrImportNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), auth.ErrorStatus(err, status))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    batch,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleDeleteImport(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	importID := r.URL.Query().Get("import_id")
+	if importID == "" {
+		http.Error(w, "import_id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteImport(actor, importID); err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusNotFound))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Message: "Import deleted successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetCSVProfiles(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profiles, err := h.service.GetCSVProfiles(actor)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    profiles,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleSaveCSVProfile(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var profile CSVProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SaveCSVProfile(actor, &profile); err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    profile,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name"`
+		TaxCategory string `json:"tax_category"`
+		Description string `json:"description"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.CreateCategory(actor, req.Name, req.TaxCategory, req.Description)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    category,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetCategories(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	categories, err := h.service.GetCategories(actor)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    categories,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	categoryID := r.URL.Query().Get("category_id")
+	if categoryID == "" {
+		http.Error(w, "category_id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteCategory(actor, categoryID); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, ErrCategoryNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), auth.ErrorStatus(err, status))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Message: "Category deleted successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleCreateRule(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CategoryRule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.CreateRule(actor, req)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    rule,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetRules(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rules, err := h.service.GetRules(actor, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    rules,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleAcceptRule(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		RuleID string `json:"rule_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.AcceptRule(actor, req.RuleID)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusNotFound))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    rule,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID := r.URL.Query().Get("rule_id")
+	if ruleID == "" {
+		http.Error(w, "rule_id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteRule(actor, ruleID); err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusNotFound))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Message: "Rule deleted successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetCategorizationSettings(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.service.GetCategorizationSettings(actor)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    settings,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleUpdateCategorizationSettings(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		LearnFromCorrections bool `json:"learn_from_corrections"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.UpdateCategorizationSettings(actor, req.LearnFromCorrections)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    settings,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleRecategorize(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RecategorizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.service.Recategorize(actor, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrCategoryNotFound) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), auth.ErrorStatus(err, status))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    changes,
+		Message: fmt.Sprintf("%d expenses re-categorized", len(changes)),
+	}
+	if req.DryRun {
+		response.Message = fmt.Sprintf("%d expenses would be re-categorized", len(changes))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleCreateMileage(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req MileageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Distance <= 0 || req.Jurisdiction == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.CreateMileageExpense(actor, req)
+	if err != nil {
+		http.Error(w, err.Error(), rateErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    expense,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleCreatePerDiem(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PerDiemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Jurisdiction == "" || req.FullDays+req.PartialDays <= 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.CreatePerDiemExpense(actor, req)
+	if err != nil {
+		http.Error(w, err.Error(), rateErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    expense,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetRates(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rates, err := h.service.GetRates(actor)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    rates,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleSaveRate(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Kind    string       `json:"kind"`
+		Mileage *MileageRate `json:"mileage"`
+		PerDiem *PerDiemRate `json:"per_diem"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		rate any
+		err  error
+	)
+	switch {
+	case req.Kind == RateKindMileage && req.Mileage != nil:
+		rate, err = h.service.SaveMileageRate(actor, *req.Mileage)
+	case req.Kind == RateKindPerDiem && req.PerDiem != nil:
+		rate, err = h.service.SavePerDiemRate(actor, *req.PerDiem)
+	default:
+		http.Error(w, "kind must be mileage or per_diem with a matching rate", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    rate,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleCreateRecurring(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RecurringExpense
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Description == "" || req.Amount <= 0 || req.Schedule == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.CreateRecurringExpense(actor, req)
+	if err != nil {
+		http.Error(w, err.Error(), projectErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    template,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetRecurring(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	templates, err := h.service.GetRecurringExpenses(actor)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    templates,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleUpdateRecurringStatus(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		RecurringID string `json:"recurring_id"`
+		Status      string `json:"status"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.RecurringID == "" || req.Status == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.SetRecurringStatus(actor, req.RecurringID, req.Status)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrRecurringNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), auth.ErrorStatus(err, status))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    template,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleDeleteRecurring(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	recurringID := r.URL.Query().Get("recurring_id")
+	if recurringID == "" {
+		http.Error(w, "recurring_id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteRecurringExpense(actor, recurringID); err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusNotFound))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Message: "Recurring expense deleted successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func rateErrorStatus(err error) int {
+	if errors.Is(err, ErrRateNotFound) {
+		return http.StatusUnprocessableEntity
+	}
+	return projectErrorStatus(err, http.StatusBadRequest)
+}
+
+// projectErrorStatus reports an unknown or foreign project as not found
+func projectErrorStatus(err error, status int) int {
+	if errors.Is(err, ErrProjectNotFound) {
+		return http.StatusNotFound
+	}
+	return auth.ErrorStatus(err, status)
+}
+
+func receiptErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrExpenseNotFound), errors.Is(err, ErrReceiptNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrReceiptTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrReceiptTypeRejected):
+		return http.StatusUnsupportedMediaType
+	default:
+		return auth.ErrorStatus(err, http.StatusInternalServerError)
+	}
+}
+
 func (h *Handlers) handleHealth(w http.ResponseWriter, r *http.Request) {
 	response := map[string]any{
 		"status":    "healthy",
@@ -0,0 +1,85 @@
+package expense
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"datastar-go/internal/modules/auth"
+	"datastar-go/internal/shared/types"
+)
+
+// workspaceProjects maps project IDs to the workspace that owns them
+type workspaceProjects map[string]string
+
+func (p workspaceProjects) GetProject(actor types.Actor, projectID string) (*types.Project, error) {
+	if p[projectID] != actor.WorkspaceID {
+		return nil, errors.New("project not found")
+	}
+	return &types.Project{ID: projectID, WorkspaceID: actor.WorkspaceID}, nil
+}
+
+func serveAs(h http.HandlerFunc, actor types.Actor, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), auth.ActorKey, actor))
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestHandlersRejectCrossTenantAccess(t *testing.T) {
+	s := newTestService(t)
+	s.SetProjectSource(workspaceProjects{"proj-1": "user-1", "proj-2": "user-2"})
+	h := NewHandlers(s)
+
+	owner := types.PersonalActor("user-1")
+	stranger := types.PersonalActor("user-2")
+	accountant := types.Actor{UserID: "user-3", WorkspaceID: owner.WorkspaceID, Role: types.RoleAccountant}
+
+	expense, err := s.CreateExpense(owner, "proj-1", "travel", "Train", 40, true, time.Now())
+	if err != nil {
+		t.Fatalf("CreateExpense: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		actor   types.Actor
+		method  string
+		target  string
+		body    string
+		want    int
+	}{
+		{"update another workspace's expense", h.handleUpdateExpense, stranger, http.MethodPut, "/api/expense/update", `{"expense_id":"` + expense.ID + `","updates":{"amount":1}}`, http.StatusNotFound},
+		{"delete another workspace's expense", h.handleDeleteExpense, stranger, http.MethodDelete, "/api/expense/delete?expense_id=" + expense.ID, "", http.StatusNotFound},
+		{"create on another workspace's project", h.handleCreateExpense, owner, http.MethodPost, "/api/expense/create", `{"project_id":"proj-2","category":"travel","description":"Taxi","amount":20}`, http.StatusNotFound},
+		{"move to another workspace's project", h.handlePatchExpense, owner, http.MethodPatch, "/api/expense/update?expense_id=" + expense.ID, `{"project_id":"proj-2"}`, http.StatusNotFound},
+		{"move to an unknown project", h.handlePatchExpense, owner, http.MethodPatch, "/api/expense/update?expense_id=" + expense.ID, `{"project_id":"missing"}`, http.StatusNotFound},
+		{"accountant creates", h.handleCreateExpense, accountant, http.MethodPost, "/api/expense/create", `{"project_id":"proj-1","category":"travel","description":"Taxi","amount":20}`, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serveAs(tc.handler, tc.actor, tc.method, tc.target, tc.body)
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+
+	stored, err := s.getOwnedExpense(owner, expense.ID)
+	if err != nil {
+		t.Fatalf("getOwnedExpense: %v", err)
+	}
+	if stored.ProjectID != "proj-1" || stored.Amount != 40 {
+		t.Errorf("expense changed by rejected requests: %+v", stored)
+	}
+
+	w := serveAs(h.handlePatchExpense, owner, http.MethodPatch, "/api/expense/update?expense_id="+expense.ID, `{"project_id":null}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("clearing the project = %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,461 @@
+package expense
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+// MaxStatementSize is the largest bank statement accepted for import
+const MaxStatementSize = 5 << 20 // 5 MB
+
+// Import row statuses
+const (
+	RowPending   = "pending"
+	RowDuplicate = "duplicate"
+	RowAccepted  = "accepted"
+	RowSkipped   = "skipped"
+	RowImported  = "imported"
+)
+
+var ErrImportNotFound = errors.New("import not found")
+
+// ImportBatch is a parsed bank statement staged for review
+type ImportBatch struct {
+	ID             string       `json:"id"`
+	UserID         string       `json:"user_id"`
+	WorkspaceID    string       `json:"workspace_id,omitempty"`
+	Format         string       `json:"format"`
+	Profile        string       `json:"profile,omitempty"`
+	FileName       string       `json:"file_name"`
+	Status         string       `json:"status"` // staged, committed
+	Rows           []*ImportRow `json:"rows"`
+	IgnoredCredits int          `json:"ignored_credits"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// ImportRow is one outgoing transaction awaiting a review decision
+type ImportRow struct {
+	ID          string        `json:"id"`
+	Line        StatementLine `json:"line"`
+	Status      string        `json:"status"`
+	DuplicateOf string        `json:"duplicate_of,omitempty"`
+	Category    string        `json:"category,omitempty"`
+	TaxCategory string        `json:"tax_category,omitempty"`
+	ProjectID   string        `json:"project_id,omitempty"`
+	RuleID      string        `json:"rule_id,omitempty"` // rule that suggested the category
+	ExpenseID   string        `json:"expense_id,omitempty"`
+}
+
+// RowDecision is a reviewer's verdict on a staged row
+type RowDecision struct {
+	RowID       string  `json:"row_id"`
+	Action      string  `json:"action"` // accept, skip
+	Category    *string `json:"category,omitempty"`
+	TaxCategory *string `json:"tax_category,omitempty"`
+	ProjectID   *string `json:"project_id,omitempty"`
+}
+
+// StageImport parses a statement and stages its outgoing transactions for review.
+// Format is detected from the content when empty; CSV files need a profile.
+func (s *Service) StageImport(actor types.Actor, format, profileName, account, fileName string, data []byte) (*ImportBatch, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+	if len(data) > MaxStatementSize {
+		return nil, fmt.Errorf("statement exceeds %d MB limit", MaxStatementSize>>20)
+	}
+	if format == "" {
+		format = DetectStatementFormat(data)
+	}
+
+	var lines []StatementLine
+	var err error
+	switch format {
+	case FormatCSV:
+		profile, perr := s.resolveCSVProfile(actor, profileName)
+		if perr != nil {
+			return nil, perr
+		}
+		profileName = profile.Name
+		lines, err = ParseCSVStatement(data, *profile)
+	case FormatOFX, "qfx":
+		format = FormatOFX
+		lines, err = ParseOFXStatement(data)
+	case FormatCAMT053:
+		lines, err = ParseCAMT053Statement(data)
+	default:
+		return nil, fmt.Errorf("unsupported statement format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse statement: %w", err)
+	}
+
+	existing, err := s.repo.GetByWorkspaceID(actor.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing expenses: %w", err)
+	}
+
+	rules, err := s.GetRules(actor, RuleActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load category rules: %w", err)
+	}
+
+	batch := &ImportBatch{
+		ID:          types.GenerateID(),
+		UserID:      actor.UserID,
+		WorkspaceID: actor.WorkspaceID,
+		Format:      format,
+		FileName:    fileName,
+		Status:      "staged",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if format == FormatCSV {
+		batch.Profile = profileName
+	}
+
+	for _, line := range lines {
+		// Only money leaving the account can be an expense
+		if line.Amount >= 0 {
+			batch.IgnoredCredits++
+			continue
+		}
+		if line.Account == "" {
+			line.Account = account
+		}
+		if line.Currency == "" {
+			line.Currency = "USD"
+		}
+
+		row := &ImportRow{
+			ID:     types.GenerateID(),
+			Line:   line,
+			Status: RowPending,
+		}
+
+		payee := line.Payee
+		if payee == "" {
+			payee = line.Description
+		}
+		if rule := matchRule(rules, payee, abs(line.Amount), line.Account); rule != nil {
+			row.Category = rule.Category
+			row.TaxCategory = rule.TaxCategory
+			row.RuleID = rule.ID
+		}
+
+		if dup := findDuplicateExpense(existing, line); dup != nil {
+			row.Status = RowDuplicate
+			row.DuplicateOf = dup.ID
+		} else if prev := findDuplicateRow(batch.Rows, line); prev != nil {
+			row.Status = RowDuplicate
+			row.DuplicateOf = prev.ID
+		}
+
+		batch.Rows = append(batch.Rows, row)
+	}
+
+	if err := s.repo.SaveImport(batch); err != nil {
+		return nil, fmt.Errorf("failed to stage import: %w", err)
+	}
+
+	duplicates := 0
+	for _, row := range batch.Rows {
+		if row.Status == RowDuplicate {
+			duplicates++
+		}
+	}
+
+	event := types.NewEvent("import_staged", "expense_service", map[string]any{
+		"import_id":    batch.ID,
+		"user_id":      actor.UserID,
+		"workspace_id": actor.WorkspaceID,
+		"format":       format,
+		"rows":         len(batch.Rows),
+		"duplicates":   duplicates,
+	})
+
+	s.eventBus.Publish("expense.import.staged", event)
+	log.Printf("🏦 Statement staged: %d rows (%d duplicates) from %s", len(batch.Rows), duplicates, format)
+
+	return batch, nil
+}
+
+// GetImport returns one of the workspace's staged imports
+func (s *Service) GetImport(actor types.Actor, importID string) (*ImportBatch, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	batch, err := s.repo.GetImport(importID)
+	if err != nil || !types.InWorkspace(batch.WorkspaceID, batch.UserID, actor.WorkspaceID) {
+		return nil, ErrImportNotFound
+	}
+	return batch, nil
+}
+
+// GetImports lists a workspace's imports, newest first
+func (s *Service) GetImports(actor types.Actor) ([]*ImportBatch, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	batches, err := s.repo.GetImportsByWorkspaceID(actor.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].CreatedAt.After(batches[j].CreatedAt)
+	})
+	return batches, nil
+}
+
+// ReviewImport records accept/skip decisions and per-row overrides
+func (s *Service) ReviewImport(actor types.Actor, importID string, decisions []RowDecision) (*ImportBatch, error) {
+	batch, err := s.GetImport(actor, importID)
+	if err != nil {
+		return nil, err
+	}
+	if err := actor.RequireEdit(batch.UserID); err != nil {
+		return nil, err
+	}
+
+	rows := make(map[string]*ImportRow, len(batch.Rows))
+	for _, row := range batch.Rows {
+		rows[row.ID] = row
+	}
+
+	for _, decision := range decisions {
+		row, ok := rows[decision.RowID]
+		if !ok {
+			return nil, fmt.Errorf("row %s not found in import", decision.RowID)
+		}
+		if row.Status == RowImported {
+			return nil, fmt.Errorf("row %s has already been imported", row.ID)
+		}
+
+		switch decision.Action {
+		case "accept":
+			row.Status = RowAccepted
+		case "skip":
+			row.Status = RowSkipped
+		case "":
+		default:
+			return nil, fmt.Errorf("invalid action %q for row %s", decision.Action, row.ID)
+		}
+
+		// The tax category is settled first so a learned rule records the
+		// one the user chose
+		if decision.Category != nil {
+			category, err := s.findCategory(actor.WorkspaceID, *decision.Category)
+			if err != nil {
+				return nil, err
+			}
+			taxCategory := category.TaxCategory
+			if decision.TaxCategory != nil {
+				taxCategory = *decision.TaxCategory
+			}
+			if category.Name != row.Category {
+				payee := row.Line.Payee
+				if payee == "" {
+					payee = row.Line.Description
+				}
+				s.recordCorrection(actor.WorkspaceID, payee, category.Name, taxCategory)
+			}
+			row.Category = category.Name
+			row.TaxCategory = taxCategory
+		} else if decision.TaxCategory != nil {
+			row.TaxCategory = *decision.TaxCategory
+		}
+		if decision.ProjectID != nil {
+			if err := s.checkProject(actor, *decision.ProjectID); err != nil {
+				return nil, err
+			}
+			row.ProjectID = *decision.ProjectID
+		}
+	}
+
+	batch.UpdatedAt = time.Now()
+	if err := s.repo.SaveImport(batch); err != nil {
+		return nil, fmt.Errorf("failed to save import review: %w", err)
+	}
+
+	return batch, nil
+}
+
+// CommitImport creates expenses for every accepted row
+func (s *Service) CommitImport(actor types.Actor, importID string) (*ImportBatch, error) {
+	batch, err := s.GetImport(actor, importID)
+	if err != nil {
+		return nil, err
+	}
+	if err := actor.RequireEdit(batch.UserID); err != nil {
+		return nil, err
+	}
+
+	created := 0
+	for _, row := range batch.Rows {
+		if row.Status != RowAccepted {
+			continue
+		}
+
+		description := row.Line.Description
+		if description == "" {
+			description = row.Line.Payee
+		}
+
+		expense := &types.Expense{
+			ID:          types.GenerateID(),
+			UserID:      actor.UserID,
+			WorkspaceID: actor.WorkspaceID,
+			ProjectID:   row.ProjectID,
+			Amount:      math.Round(abs(row.Line.Amount)*100) / 100,
+			Currency:    row.Line.Currency,
+			Category:    row.Category,
+			TaxCategory: row.TaxCategory,
+			Description: description,
+			Payee:       row.Line.Payee,
+			Account:     row.Line.Account,
+			Reference:   row.Line.Reference,
+			Date:        row.Line.Date,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+
+		if err := s.addExpense(expense); err != nil {
+			// Persist progress so a retry does not create the same rows twice
+			batch.UpdatedAt = time.Now()
+			s.repo.SaveImport(batch)
+			return nil, err
+		}
+
+		row.Status = RowImported
+		row.ExpenseID = expense.ID
+		created++
+	}
+
+	batch.Status = "committed"
+	for _, row := range batch.Rows {
+		if row.Status == RowPending || row.Status == RowAccepted {
+			batch.Status = "staged"
+			break
+		}
+	}
+	batch.UpdatedAt = time.Now()
+
+	if err := s.repo.SaveImport(batch); err != nil {
+		return nil, fmt.Errorf("failed to save import: %w", err)
+	}
+
+	event := types.NewEvent("import_committed", "expense_service", map[string]any{
+		"import_id":        batch.ID,
+		"user_id":          actor.UserID,
+		"workspace_id":     actor.WorkspaceID,
+		"expenses_created": created,
+	})
+
+	s.eventBus.Publish("expense.import.committed", event)
+	log.Printf("🏦 Statement import committed: %d expenses created", created)
+
+	return batch, nil
+}
+
+// DeleteImport discards a staged import; created expenses are kept
+func (s *Service) DeleteImport(actor types.Actor, importID string) error {
+	batch, err := s.GetImport(actor, importID)
+	if err != nil {
+		return err
+	}
+	if err := actor.RequireEdit(batch.UserID); err != nil {
+		return err
+	}
+	return s.repo.DeleteImport(importID)
+}
+
+// GetCSVProfiles returns the built-in profiles followed by the workspace's own
+func (s *Service) GetCSVProfiles(actor types.Actor) ([]*CSVProfile, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(builtinCSVProfiles))
+	for name := range builtinCSVProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	profiles := make([]*CSVProfile, 0, len(names))
+	for _, name := range names {
+		profile := builtinCSVProfiles[name]
+		profiles = append(profiles, &profile)
+	}
+
+	custom, err := s.repo.GetCSVProfilesByUserID(actor.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return append(profiles, custom...), nil
+}
+
+// SaveCSVProfile stores a user-defined column mapping profile for the workspace
+func (s *Service) SaveCSVProfile(actor types.Actor, profile *CSVProfile) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+	profile.Name = strings.TrimSpace(profile.Name)
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	if _, builtin := builtinCSVProfiles[profile.Name]; builtin {
+		return fmt.Errorf("profile name %q is reserved", profile.Name)
+	}
+	return s.repo.SaveCSVProfile(actor.WorkspaceID, profile)
+}
+
+func (s *Service) resolveCSVProfile(actor types.Actor, name string) (*CSVProfile, error) {
+	if name == "" {
+		name = "generic"
+	}
+	if profile, ok := builtinCSVProfiles[name]; ok {
+		return &profile, nil
+	}
+	profile, err := s.repo.GetCSVProfile(actor.WorkspaceID, name)
+	if err != nil {
+		return nil, fmt.Errorf("CSV profile %q not found", name)
+	}
+	return profile, nil
+}
+
+// findDuplicateExpense matches on reference when both sides carry one,
+// otherwise on booking day and amount
+func findDuplicateExpense(expenses []*types.Expense, line StatementLine) *types.Expense {
+	for _, expense := range expenses {
+		if isDuplicate(expense.Date, expense.Amount, expense.Reference, line) {
+			return expense
+		}
+	}
+	return nil
+}
+
+func findDuplicateRow(rows []*ImportRow, line StatementLine) *ImportRow {
+	for _, row := range rows {
+		if isDuplicate(row.Line.Date, row.Line.Amount, row.Line.Reference, line) {
+			return row
+		}
+	}
+	return nil
+}
+
+func isDuplicate(date time.Time, amount float64, reference string, line StatementLine) bool {
+	if reference != "" && line.Reference != "" {
+		return reference == line.Reference
+	}
+	if math.Abs(abs(amount)-abs(line.Amount)) >= 0.005 {
+		return false
+	}
+	return date.Format("2006-01-02") == line.Date.Format("2006-01-02")
+}
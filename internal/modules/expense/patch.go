@@ -0,0 +1,137 @@
+package expense
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+var ErrInvalidPatch = errors.New("invalid expense patch")
+
+// ExpensePatch is a JSON Merge Patch of the user-editable expense fields.
+// Absent keys are left alone, null clears optional fields. Billing state,
+// receipts and recurrence links are managed by their own endpoints.
+type ExpensePatch struct {
+	ProjectID   types.Optional[string]    `json:"project_id"`
+	Amount      types.Optional[float64]   `json:"amount"`
+	Currency    types.Optional[string]    `json:"currency"`
+	Category    types.Optional[string]    `json:"category"`
+	Description types.Optional[string]    `json:"description"`
+	Payee       types.Optional[string]    `json:"payee"`
+	Account     types.Optional[string]    `json:"account"`
+	Reference   types.Optional[string]    `json:"reference"`
+	Date        types.Optional[time.Time] `json:"date"`
+	IsBillable  types.Optional[bool]      `json:"is_billable"`
+	TaxCategory types.Optional[string]    `json:"tax_category"`
+	Notes       types.Optional[string]    `json:"notes"`
+}
+
+// FieldChange records one field an update actually changed
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// apply validates the patch against the expense and returns the updated copy
+// together with the fields that changed. The original is never modified.
+func (p *ExpensePatch) apply(original *types.Expense) (*types.Expense, []FieldChange, error) {
+	expense := *original
+	var changes []FieldChange
+
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidPatch, fmt.Sprintf(format, args...))
+	}
+
+	computed := expense.Type == types.ExpenseTypeMileage || expense.Type == types.ExpenseTypePerDiem
+	if computed && (p.Amount.Set || p.Currency.Set) {
+		return nil, nil, invalid("amount and currency of %s expenses are computed from the rate table", expense.Type)
+	}
+	if expense.IsBilled && (p.Amount.Set || p.Currency.Set || p.ProjectID.Set) {
+		return nil, nil, fmt.Errorf("%w: remove it from invoice %s before changing amount, currency or project", ErrExpenseBilled, expense.InvoiceID)
+	}
+
+	if p.Amount.Set {
+		if p.Amount.Null || p.Amount.Value <= 0 {
+			return nil, nil, invalid("amount must be positive")
+		}
+		changes = setField(changes, "amount", &expense.Amount, p.Amount.Value)
+	}
+	if p.Currency.Set {
+		currency := strings.ToUpper(strings.TrimSpace(p.Currency.Value))
+		if p.Currency.Null || !isCurrencyCode(currency) {
+			return nil, nil, invalid("currency must be a three-letter ISO 4217 code")
+		}
+		changes = setField(changes, "currency", &expense.Currency, currency)
+	}
+	if p.Description.Set {
+		description := strings.TrimSpace(p.Description.Value)
+		if p.Description.Null || description == "" {
+			return nil, nil, invalid("description cannot be empty")
+		}
+		changes = setField(changes, "description", &expense.Description, description)
+	}
+	if p.Date.Set {
+		if p.Date.Null || p.Date.Value.IsZero() {
+			return nil, nil, invalid("date cannot be cleared")
+		}
+		if !p.Date.Value.Equal(expense.Date) {
+			changes = append(changes, FieldChange{Field: "date", Old: expense.Date, New: p.Date.Value})
+			expense.Date = p.Date.Value
+		}
+	}
+	if p.IsBillable.Set {
+		if p.IsBillable.Null {
+			return nil, nil, invalid("is_billable cannot be null")
+		}
+		if expense.IsBilled && !p.IsBillable.Value {
+			return nil, nil, fmt.Errorf("%w: remove it from invoice %s first", ErrExpenseBilled, expense.InvoiceID)
+		}
+		changes = setField(changes, "is_billable", &expense.IsBillable, p.IsBillable.Value)
+	}
+
+	// Clearable text fields: null and "" both mean empty
+	for _, field := range []struct {
+		name  string
+		patch types.Optional[string]
+		dst   *string
+	}{
+		{"project_id", p.ProjectID, &expense.ProjectID},
+		{"category", p.Category, &expense.Category},
+		{"payee", p.Payee, &expense.Payee},
+		{"account", p.Account, &expense.Account},
+		{"reference", p.Reference, &expense.Reference},
+		{"tax_category", p.TaxCategory, &expense.TaxCategory},
+		{"notes", p.Notes, &expense.Notes},
+	} {
+		if field.patch.Set {
+			changes = setField(changes, field.name, field.dst, strings.TrimSpace(field.patch.Value))
+		}
+	}
+
+	return &expense, changes, nil
+}
+
+func setField[T comparable](changes []FieldChange, name string, dst *T, value T) []FieldChange {
+	if *dst == value {
+		return changes
+	}
+	changes = append(changes, FieldChange{Field: name, Old: *dst, New: value})
+	*dst = value
+	return changes
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,483 @@
+package expense
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+// Distance units
+const (
+	UnitKilometers = "km"
+	UnitMiles      = "mi"
+
+	kilometersPerMile = 1.609344
+)
+
+// Rate kinds
+const (
+	RateKindMileage = "mileage"
+	RateKindPerDiem = "per_diem"
+)
+
+// defaultVehicle is used when a mileage request does not name a vehicle
+const defaultVehicle = "car"
+
+var ErrRateNotFound = errors.New("no rate configured for this jurisdiction and year")
+
+// MileageRate is the reimbursable amount per distance unit for a vehicle.
+// Custom rates are shared by a workspace, so UserID holds the workspace ID.
+type MileageRate struct {
+	UserID       string  `json:"user_id,omitempty"` // empty for built-in rates
+	Jurisdiction string  `json:"jurisdiction"`      // ISO country code, e.g. US, DE, GB
+	Year         int     `json:"year"`
+	Vehicle      string  `json:"vehicle"`
+	Unit         string  `json:"unit"` // km, mi
+	Rate         float64 `json:"rate"`
+	Currency     string  `json:"currency"`
+	Source       string  `json:"source,omitempty"`
+}
+
+// PerDiemRate is the daily meals and incidentals allowance for a location.
+// An empty Location is the jurisdiction-wide default.
+type PerDiemRate struct {
+	UserID       string  `json:"user_id,omitempty"`
+	Jurisdiction string  `json:"jurisdiction"`
+	Location     string  `json:"location,omitempty"`
+	Year         int     `json:"year"`
+	FullDay      float64 `json:"full_day"`
+	PartialDay   float64 `json:"partial_day"`
+	Currency     string  `json:"currency"`
+	Source       string  `json:"source,omitempty"`
+}
+
+// RateTable is the combined view of built-in and user-defined rates
+type RateTable struct {
+	Mileage []*MileageRate `json:"mileage"`
+	PerDiem []*PerDiemRate `json:"per_diem"`
+}
+
+// builtinMileageRates are the published statutory rates. User rates with the
+// same jurisdiction, year and vehicle take precedence.
+var builtinMileageRates = []*MileageRate{
+	{Jurisdiction: "US", Year: 2024, Vehicle: "car", Unit: UnitMiles, Rate: 0.67, Currency: "USD", Source: "IRS standard mileage rate"},
+	{Jurisdiction: "US", Year: 2025, Vehicle: "car", Unit: UnitMiles, Rate: 0.70, Currency: "USD", Source: "IRS standard mileage rate"},
+	{Jurisdiction: "DE", Year: 2024, Vehicle: "car", Unit: UnitKilometers, Rate: 0.30, Currency: "EUR", Source: "Wegstreckenentschädigung"},
+	{Jurisdiction: "DE", Year: 2025, Vehicle: "car", Unit: UnitKilometers, Rate: 0.30, Currency: "EUR", Source: "Wegstreckenentschädigung"},
+	{Jurisdiction: "DE", Year: 2024, Vehicle: "motorcycle", Unit: UnitKilometers, Rate: 0.20, Currency: "EUR", Source: "Wegstreckenentschädigung"},
+	{Jurisdiction: "DE", Year: 2025, Vehicle: "motorcycle", Unit: UnitKilometers, Rate: 0.20, Currency: "EUR", Source: "Wegstreckenentschädigung"},
+	{Jurisdiction: "GB", Year: 2024, Vehicle: "car", Unit: UnitMiles, Rate: 0.45, Currency: "GBP", Source: "HMRC approved mileage rate"},
+	{Jurisdiction: "GB", Year: 2025, Vehicle: "car", Unit: UnitMiles, Rate: 0.45, Currency: "GBP", Source: "HMRC approved mileage rate"},
+	{Jurisdiction: "GB", Year: 2024, Vehicle: "motorcycle", Unit: UnitMiles, Rate: 0.24, Currency: "GBP", Source: "HMRC approved mileage rate"},
+	{Jurisdiction: "GB", Year: 2025, Vehicle: "motorcycle", Unit: UnitMiles, Rate: 0.24, Currency: "GBP", Source: "HMRC approved mileage rate"},
+	{Jurisdiction: "GB", Year: 2024, Vehicle: "bicycle", Unit: UnitMiles, Rate: 0.20, Currency: "GBP", Source: "HMRC approved mileage rate"},
+	{Jurisdiction: "GB", Year: 2025, Vehicle: "bicycle", Unit: UnitMiles, Rate: 0.20, Currency: "GBP", Source: "HMRC approved mileage rate"},
+	{Jurisdiction: "US", Year: 2026, Vehicle: "car", Unit: UnitMiles, Rate: 0.725, Currency: "USD", Source: "IRS standard mileage rate"},
+	{Jurisdiction: "DE", Year: 2026, Vehicle: "car", Unit: UnitKilometers, Rate: 0.30, Currency: "EUR", Source: "Wegstreckenentschädigung"},
+	{Jurisdiction: "DE", Year: 2026, Vehicle: "motorcycle", Unit: UnitKilometers, Rate: 0.20, Currency: "EUR", Source: "Wegstreckenentschädigung"},
+	{Jurisdiction: "GB", Year: 2026, Vehicle: "car", Unit: UnitMiles, Rate: 0.45, Currency: "GBP", Source: "HMRC approved mileage rate"},
+	{Jurisdiction: "GB", Year: 2026, Vehicle: "motorcycle", Unit: UnitMiles, Rate: 0.24, Currency: "GBP", Source: "HMRC approved mileage rate"},
+	{Jurisdiction: "GB", Year: 2026, Vehicle: "bicycle", Unit: UnitMiles, Rate: 0.20, Currency: "GBP", Source: "HMRC approved mileage rate"},
+}
+
+// builtinPerDiemRates are jurisdiction-wide defaults. The US partial day is
+// 75% of the full M&IE rate; Germany uses the flat arrival/departure allowance.
+var builtinPerDiemRates = []*PerDiemRate{
+	{Jurisdiction: "US", Year: 2024, FullDay: 59, PartialDay: 44.25, Currency: "USD", Source: "GSA standard CONUS M&IE"},
+	{Jurisdiction: "US", Year: 2025, FullDay: 68, PartialDay: 51, Currency: "USD", Source: "GSA standard CONUS M&IE"},
+	{Jurisdiction: "DE", Year: 2024, FullDay: 28, PartialDay: 14, Currency: "EUR", Source: "Verpflegungsmehraufwand"},
+	{Jurisdiction: "DE", Year: 2025, FullDay: 28, PartialDay: 14, Currency: "EUR", Source: "Verpflegungsmehraufwand"},
+	{Jurisdiction: "US", Year: 2026, FullDay: 68, PartialDay: 51, Currency: "USD", Source: "GSA standard CONUS M&IE"},
+	{Jurisdiction: "DE", Year: 2026, FullDay: 28, PartialDay: 14, Currency: "EUR", Source: "Verpflegungsmehraufwand"},
+}
+
+// MileageRequest describes a trip to be recorded as a mileage expense
+type MileageRequest struct {
+	ProjectID    string    `json:"project_id"`
+	Date         time.Time `json:"date"`
+	Description  string    `json:"description"`
+	Category     string    `json:"category"`
+	Distance     float64   `json:"distance"`
+	Unit         string    `json:"unit"` // defaults to the rate's unit
+	Vehicle      string    `json:"vehicle"`
+	Jurisdiction string    `json:"jurisdiction"`
+	Origin       string    `json:"origin"`
+	Destination  string    `json:"destination"`
+	RoundTrip    bool      `json:"round_trip"`
+	IsBillable   bool      `json:"is_billable"`
+}
+
+// PerDiemRequest describes a business trip to be recorded as a per-diem expense
+type PerDiemRequest struct {
+	ProjectID    string    `json:"project_id"`
+	Date         time.Time `json:"date"` // first day of travel
+	Description  string    `json:"description"`
+	Category     string    `json:"category"`
+	Location     string    `json:"location"`
+	Jurisdiction string    `json:"jurisdiction"`
+	FullDays     int       `json:"full_days"`
+	PartialDays  int       `json:"partial_days"`
+	IsBillable   bool      `json:"is_billable"`
+}
+
+// CreateMileageExpense records a trip, computing the amount from the rate table
+func (s *Service) CreateMileageExpense(actor types.Actor, req MileageRequest) (*types.Expense, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+	if req.Distance <= 0 {
+		return nil, fmt.Errorf("distance must be positive")
+	}
+	if req.Date.IsZero() {
+		req.Date = time.Now()
+	}
+	if req.Vehicle == "" {
+		req.Vehicle = defaultVehicle
+	}
+
+	if err := s.checkProject(actor, req.ProjectID); err != nil {
+		return nil, err
+	}
+
+	rate, err := s.findMileageRate(actor, req.Jurisdiction, req.Date.Year(), req.Vehicle)
+	if err != nil {
+		return nil, err
+	}
+
+	distance := req.Distance
+	if req.RoundTrip {
+		distance *= 2
+	}
+	if req.Unit != "" && req.Unit != rate.Unit {
+		distance, err = convertDistance(distance, req.Unit, rate.Unit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	description := req.Description
+	if description == "" {
+		description = fmt.Sprintf("Mileage: %.1f %s", distance, rate.Unit)
+		if req.Origin != "" && req.Destination != "" {
+			description = fmt.Sprintf("Mileage: %s → %s (%.1f %s)", req.Origin, req.Destination, distance, rate.Unit)
+		}
+	}
+
+	expense := &types.Expense{
+		ID:          types.GenerateID(),
+		UserID:      actor.UserID,
+		WorkspaceID: actor.WorkspaceID,
+		Type:        types.ExpenseTypeMileage,
+		ProjectID:   req.ProjectID,
+		Amount:      roundCents(distance * rate.Rate),
+		Currency:    rate.Currency,
+		Category:    categoryOrDefault(req.Category, "travel"),
+		Description: description,
+		Date:        req.Date,
+		IsBillable:  req.IsBillable,
+		TaxCategory: "mileage",
+		Mileage: &types.Mileage{
+			Distance:     roundCents(distance),
+			Unit:         rate.Unit,
+			Vehicle:      rate.Vehicle,
+			Jurisdiction: rate.Jurisdiction,
+			Year:         rate.Year,
+			Rate:         rate.Rate,
+			RateSource:   rate.Source,
+			Origin:       req.Origin,
+			Destination:  req.Destination,
+			RoundTrip:    req.RoundTrip,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.addExpense(expense); err != nil {
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+// CreatePerDiemExpense records a trip's meal allowance, computing the amount from the rate table
+func (s *Service) CreatePerDiemExpense(actor types.Actor, req PerDiemRequest) (*types.Expense, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+	if req.FullDays < 0 || req.PartialDays < 0 || req.FullDays+req.PartialDays == 0 {
+		return nil, fmt.Errorf("at least one full or partial day is required")
+	}
+	if req.Date.IsZero() {
+		req.Date = time.Now()
+	}
+
+	if err := s.checkProject(actor, req.ProjectID); err != nil {
+		return nil, err
+	}
+
+	rate, err := s.findPerDiemRate(actor, req.Jurisdiction, req.Location, req.Date.Year())
+	if err != nil {
+		return nil, err
+	}
+
+	description := req.Description
+	if description == "" {
+		place := req.Location
+		if place == "" {
+			place = rate.Jurisdiction
+		}
+		description = fmt.Sprintf("Per diem: %s (%d full, %d partial days)", place, req.FullDays, req.PartialDays)
+	}
+
+	amount := float64(req.FullDays)*rate.FullDay + float64(req.PartialDays)*rate.PartialDay
+
+	expense := &types.Expense{
+		ID:          types.GenerateID(),
+		UserID:      actor.UserID,
+		WorkspaceID: actor.WorkspaceID,
+		Type:        types.ExpenseTypePerDiem,
+		ProjectID:   req.ProjectID,
+		Amount:      roundCents(amount),
+		Currency:    rate.Currency,
+		Category:    categoryOrDefault(req.Category, "meals"),
+		Description: description,
+		Date:        req.Date,
+		IsBillable:  req.IsBillable,
+		TaxCategory: "per_diem",
+		PerDiem: &types.PerDiem{
+			Location:       req.Location,
+			Jurisdiction:   rate.Jurisdiction,
+			Year:           rate.Year,
+			FullDays:       req.FullDays,
+			PartialDays:    req.PartialDays,
+			FullDayRate:    rate.FullDay,
+			PartialDayRate: rate.PartialDay,
+			RateSource:     rate.Source,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.addExpense(expense); err != nil {
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+// GetRates returns the built-in rates merged with the workspace's own, custom
+// rates first
+func (s *Service) GetRates(actor types.Actor) (*RateTable, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	mileage, err := s.repo.GetMileageRatesByUserID(actor.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mileage rates: %w", err)
+	}
+	perDiem, err := s.repo.GetPerDiemRatesByUserID(actor.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get per diem rates: %w", err)
+	}
+
+	sortMileageRates(mileage)
+	sortPerDiemRates(perDiem)
+
+	table := &RateTable{
+		Mileage: append(mileage, builtinMileageRates...),
+		PerDiem: append(perDiem, builtinPerDiemRates...),
+	}
+	return table, nil
+}
+
+// SaveMileageRate adds or replaces a user-defined mileage rate
+func (s *Service) SaveMileageRate(actor types.Actor, rate MileageRate) (*MileageRate, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	rate.UserID = actor.WorkspaceID
+	rate.Jurisdiction = strings.ToUpper(strings.TrimSpace(rate.Jurisdiction))
+	rate.Vehicle = strings.ToLower(strings.TrimSpace(rate.Vehicle))
+	if rate.Vehicle == "" {
+		rate.Vehicle = defaultVehicle
+	}
+
+	if rate.Jurisdiction == "" || rate.Year == 0 || rate.Rate <= 0 || rate.Currency == "" {
+		return nil, fmt.Errorf("jurisdiction, year, rate and currency are required")
+	}
+	if rate.Unit != UnitKilometers && rate.Unit != UnitMiles {
+		return nil, fmt.Errorf("unit must be %q or %q", UnitKilometers, UnitMiles)
+	}
+
+	if err := s.repo.SaveMileageRate(&rate); err != nil {
+		return nil, fmt.Errorf("failed to save mileage rate: %w", err)
+	}
+
+	log.Printf("🚗 Mileage rate saved: %s %d %s %.2f %s/%s", rate.Jurisdiction, rate.Year, rate.Vehicle, rate.Rate, rate.Currency, rate.Unit)
+	return &rate, nil
+}
+
+// SavePerDiemRate adds or replaces a user-defined per-diem rate
+func (s *Service) SavePerDiemRate(actor types.Actor, rate PerDiemRate) (*PerDiemRate, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	rate.UserID = actor.WorkspaceID
+	rate.Jurisdiction = strings.ToUpper(strings.TrimSpace(rate.Jurisdiction))
+	rate.Location = strings.TrimSpace(rate.Location)
+
+	if rate.Jurisdiction == "" || rate.Year == 0 || rate.FullDay <= 0 || rate.Currency == "" {
+		return nil, fmt.Errorf("jurisdiction, year, full_day and currency are required")
+	}
+	if rate.PartialDay < 0 || rate.PartialDay > rate.FullDay {
+		return nil, fmt.Errorf("partial_day must be between 0 and full_day")
+	}
+
+	if err := s.repo.SavePerDiemRate(&rate); err != nil {
+		return nil, fmt.Errorf("failed to save per diem rate: %w", err)
+	}
+
+	log.Printf("🍽️ Per diem rate saved: %s/%s %d %.2f %s", rate.Jurisdiction, rate.Location, rate.Year, rate.FullDay, rate.Currency)
+	return &rate, nil
+}
+
+// findMileageRate prefers the workspace's own rate over the built-in one.
+// Without a rate for the year it falls back to the latest earlier one.
+func (s *Service) findMileageRate(actor types.Actor, jurisdiction string, year int, vehicle string) (*MileageRate, error) {
+	jurisdiction = strings.ToUpper(strings.TrimSpace(jurisdiction))
+	vehicle = strings.ToLower(strings.TrimSpace(vehicle))
+	if jurisdiction == "" {
+		return nil, fmt.Errorf("jurisdiction is required")
+	}
+
+	userRates, err := s.repo.GetMileageRatesByUserID(actor.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mileage rates: %w", err)
+	}
+
+	var best *MileageRate
+	for _, rates := range [][]*MileageRate{userRates, builtinMileageRates} {
+		for _, rate := range rates {
+			if rate.Jurisdiction != jurisdiction || rate.Vehicle != vehicle || rate.Year > year {
+				continue
+			}
+			if best == nil || rate.Year > best.Year {
+				best = rate
+			}
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: %s %d %s", ErrRateNotFound, jurisdiction, year, vehicle)
+	}
+
+	if best.Year != year {
+		fallback := *best
+		fallback.Source = fallbackSource(best.Source, best.Year, year)
+		return &fallback, nil
+	}
+	return best, nil
+}
+
+// findPerDiemRate prefers a location-specific rate, then the jurisdiction default,
+// checking the workspace's own rates before the built-in ones at each step.
+// Without a rate for the year it falls back to the latest earlier one, and a
+// newer jurisdiction default beats an older location rate.
+func (s *Service) findPerDiemRate(actor types.Actor, jurisdiction, location string, year int) (*PerDiemRate, error) {
+	jurisdiction = strings.ToUpper(strings.TrimSpace(jurisdiction))
+	if jurisdiction == "" {
+		return nil, fmt.Errorf("jurisdiction is required")
+	}
+
+	userRates, err := s.repo.GetPerDiemRatesByUserID(actor.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get per diem rates: %w", err)
+	}
+
+	var best *PerDiemRate
+	for _, wanted := range []string{strings.TrimSpace(location), ""} {
+		for _, rates := range [][]*PerDiemRate{userRates, builtinPerDiemRates} {
+			for _, rate := range rates {
+				if rate.Jurisdiction != jurisdiction || rate.Year > year || !strings.EqualFold(rate.Location, wanted) {
+					continue
+				}
+				if best == nil || rate.Year > best.Year {
+					best = rate
+				}
+			}
+		}
+		if wanted == "" {
+			break
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: %s %d", ErrRateNotFound, jurisdiction, year)
+	}
+
+	if best.Year != year {
+		fallback := *best
+		fallback.Source = fallbackSource(best.Source, best.Year, year)
+		return &fallback, nil
+	}
+	return best, nil
+}
+
+// fallbackSource notes that a rate from an earlier year was applied
+func fallbackSource(source string, rateYear, year int) string {
+	if source == "" {
+		source = "workspace rate"
+	}
+	return fmt.Sprintf("%s (%d rate, none published for %d)", source, rateYear, year)
+}
+
+func convertDistance(distance float64, from, to string) (float64, error) {
+	switch {
+	case from == to:
+		return distance, nil
+	case from == UnitMiles && to == UnitKilometers:
+		return distance * kilometersPerMile, nil
+	case from == UnitKilometers && to == UnitMiles:
+		return distance / kilometersPerMile, nil
+	}
+	return 0, fmt.Errorf("unit must be %q or %q", UnitKilometers, UnitMiles)
+}
+
+func categoryOrDefault(category, fallback string) string {
+	if category == "" {
+		return fallback
+	}
+	return category
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func sortMileageRates(rates []*MileageRate) {
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Jurisdiction != rates[j].Jurisdiction {
+			return rates[i].Jurisdiction < rates[j].Jurisdiction
+		}
+		if rates[i].Year != rates[j].Year {
+			return rates[i].Year > rates[j].Year
+		}
+		return rates[i].Vehicle < rates[j].Vehicle
+	})
+}
+
+func sortPerDiemRates(rates []*PerDiemRate) {
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Jurisdiction != rates[j].Jurisdiction {
+			return rates[i].Jurisdiction < rates[j].Jurisdiction
+		}
+		if rates[i].Year != rates[j].Year {
+			return rates[i].Year > rates[j].Year
+		}
+		return rates[i].Location < rates[j].Location
+	})
+}
@@ -0,0 +1,86 @@
+package expense
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"datastar-go/internal/shared/testutil"
+	"datastar-go/internal/shared/types"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return NewService(&testutil.EventBus{}, testutil.MemoryDB(t), nil)
+}
+
+func TestFindMileageRateFallsBackToLatestEarlierYear(t *testing.T) {
+	s := newTestService(t)
+	actor := types.PersonalActor("user-1")
+
+	rate, err := s.findMileageRate(actor, "GB", 2031, "car")
+	if err != nil {
+		t.Fatalf("findMileageRate: %v", err)
+	}
+	if rate.Year != 2026 {
+		t.Errorf("year = %d, want 2026", rate.Year)
+	}
+	if !strings.Contains(rate.Source, "none published for 2031") {
+		t.Errorf("source %q does not note the fallback", rate.Source)
+	}
+	for _, builtin := range builtinMileageRates {
+		if strings.Contains(builtin.Source, "none published") {
+			t.Fatalf("fallback modified the built-in rate %+v", builtin)
+		}
+	}
+
+	exact, err := s.findMileageRate(actor, "GB", 2025, "car")
+	if err != nil {
+		t.Fatalf("findMileageRate: %v", err)
+	}
+	if exact.Year != 2025 || exact.Source != "HMRC approved mileage rate" {
+		t.Errorf("exact year rate = %+v", exact)
+	}
+
+	if _, err := s.findMileageRate(actor, "GB", 2023, "car"); !errors.Is(err, ErrRateNotFound) {
+		t.Errorf("year before any rate: err = %v, want ErrRateNotFound", err)
+	}
+}
+
+func TestFindPerDiemRatePrefersNewerDefaultOverOlderLocation(t *testing.T) {
+	s := newTestService(t)
+	actor := types.PersonalActor("user-1")
+
+	if _, err := s.SavePerDiemRate(actor, PerDiemRate{Jurisdiction: "DE", Location: "Berlin", Year: 2024, FullDay: 40, PartialDay: 20, Currency: "EUR"}); err != nil {
+		t.Fatalf("SavePerDiemRate: %v", err)
+	}
+
+	rate, err := s.findPerDiemRate(actor, "DE", "Berlin", 2026)
+	if err != nil {
+		t.Fatalf("findPerDiemRate: %v", err)
+	}
+	if rate.Year != 2026 || rate.Location != "" {
+		t.Errorf("rate = %+v, want the 2026 jurisdiction default", rate)
+	}
+
+	rate, err = s.findPerDiemRate(actor, "DE", "Berlin", 2024)
+	if err != nil {
+		t.Fatalf("findPerDiemRate: %v", err)
+	}
+	if rate.Location != "Berlin" || rate.FullDay != 40 {
+		t.Errorf("rate = %+v, want the Berlin rate", rate)
+	}
+}
+
+func TestCreateMileageExpenseWithoutDateUsesCurrentRate(t *testing.T) {
+	s := newTestService(t)
+
+	expense, err := s.CreateMileageExpense(types.PersonalActor("user-1"), MileageRequest{Distance: 10, Jurisdiction: "US"})
+	if err != nil {
+		t.Fatalf("CreateMileageExpense: %v", err)
+	}
+	if expense.Mileage.Year > time.Now().Year() {
+		t.Errorf("rate year %d is after this year", expense.Mileage.Year)
+	}
+}
@@ -0,0 +1,288 @@
+package expense
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+const (
+	// MaxReceiptSize is the largest receipt file accepted for upload
+	MaxReceiptSize = 10 << 20 // 10 MB
+
+	thumbnailMaxSize   = 320
+	thumbnailMaxPixels = 40_000_000
+)
+
+// allowedReceiptTypes lists the sniffed MIME types accepted as receipts
+var allowedReceiptTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+var (
+	ErrExpenseNotFound     = errors.New("expense not found")
+	ErrReceiptNotFound     = errors.New("expense has no receipt")
+	ErrReceiptTooLarge     = fmt.Errorf("receipt exceeds %d MB limit", MaxReceiptSize>>20)
+	ErrReceiptTypeRejected = errors.New("receipt must be a JPEG, PNG, GIF, WebP image or a PDF")
+)
+
+// AttachReceipt stores an uploaded receipt and links it to the expense,
+// replacing any receipt that was attached before
+func (s *Service) AttachReceipt(actor types.Actor, expenseID, fileName string, r io.Reader) (*types.Expense, error) {
+	expense, err := s.getOwnedExpense(actor, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := actor.RequireEdit(expense.UserID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxReceiptSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read receipt: %w", err)
+	}
+	if len(data) > MaxReceiptSize {
+		return nil, ErrReceiptTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedReceiptTypes[contentType] {
+		return nil, ErrReceiptTypeRejected
+	}
+
+	hash, err := s.receipts.Put(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store receipt: %w", err)
+	}
+
+	receipt := &types.ReceiptFile{
+		Hash:        hash,
+		FileName:    sanitizeFileName(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		UploadedAt:  time.Now(),
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		thumbnail, err := makeThumbnail(data)
+		if err != nil {
+			log.Printf("Receipt thumbnail skipped for %s: %v", expense.ID, err)
+		} else if receipt.ThumbnailHash, err = s.receipts.Put(thumbnail); err != nil {
+			log.Printf("Failed to store receipt thumbnail for %s: %v", expense.ID, err)
+			receipt.ThumbnailHash = ""
+		}
+	}
+
+	previous := expense.ReceiptFile
+	expense.ReceiptFile = receipt
+	expense.Receipt = "/api/expense/receipt?expense_id=" + expense.ID
+	expense.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(expense); err != nil {
+		return nil, fmt.Errorf("failed to update expense: %w", err)
+	}
+
+	if previous != nil && previous.Hash != receipt.Hash {
+		s.releaseReceipt(previous, expense.ID)
+	}
+
+	event := types.NewEvent("receipt_attached", "expense_service", map[string]any{
+		"expense_id":   expense.ID,
+		"user_id":      expense.UserID,
+		"hash":         receipt.Hash,
+		"content_type": receipt.ContentType,
+		"size":         receipt.Size,
+	})
+
+	s.eventBus.Publish("expense.receipt.attached", event)
+	log.Printf("🧾 Receipt attached to expense %s (%s, %d bytes)", expense.ID, receipt.ContentType, receipt.Size)
+
+	return expense, nil
+}
+
+// OpenReceipt opens the receipt (or its thumbnail) of one of the workspace's expenses
+func (s *Service) OpenReceipt(actor types.Actor, expenseID string, thumbnail bool) (*os.File, *types.ReceiptFile, error) {
+	expense, err := s.getOwnedExpense(actor, expenseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if expense.ReceiptFile == nil {
+		return nil, nil, ErrReceiptNotFound
+	}
+
+	hash := expense.ReceiptFile.Hash
+	if thumbnail {
+		if expense.ReceiptFile.ThumbnailHash == "" {
+			return nil, nil, ErrReceiptNotFound
+		}
+		hash = expense.ReceiptFile.ThumbnailHash
+	}
+
+	file, err := s.receipts.Open(hash)
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil, nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, expense.ReceiptFile, nil
+}
+
+// RemoveReceipt detaches the receipt from an expense and deletes unreferenced files
+func (s *Service) RemoveReceipt(actor types.Actor, expenseID string) (*types.Expense, error) {
+	expense, err := s.getOwnedExpense(actor, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := actor.RequireEdit(expense.UserID); err != nil {
+		return nil, err
+	}
+	if expense.ReceiptFile == nil {
+		return nil, ErrReceiptNotFound
+	}
+
+	receipt := expense.ReceiptFile
+	expense.ReceiptFile = nil
+	expense.Receipt = ""
+	expense.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(expense); err != nil {
+		return nil, fmt.Errorf("failed to update expense: %w", err)
+	}
+
+	s.releaseReceipt(receipt, expense.ID)
+
+	event := types.NewEvent("receipt_removed", "expense_service", map[string]any{
+		"expense_id": expense.ID,
+		"user_id":    expense.UserID,
+		"hash":       receipt.Hash,
+	})
+
+	s.eventBus.Publish("expense.receipt.removed", event)
+	return expense, nil
+}
+
+// releaseReceipt deletes the receipt blobs unless another expense still uses them
+func (s *Service) releaseReceipt(receipt *types.ReceiptFile, expenseID string) {
+	users, err := s.repo.GetByReceiptHash(receipt.Hash)
+	if err != nil {
+		log.Printf("Failed to check receipt references for %s: %v", receipt.Hash, err)
+		return
+	}
+	for _, other := range users {
+		if other.ID != expenseID {
+			return
+		}
+	}
+
+	if err := s.receipts.Delete(receipt.Hash); err != nil {
+		log.Printf("Failed to delete receipt %s: %v", receipt.Hash, err)
+	}
+	if receipt.ThumbnailHash != "" {
+		if err := s.receipts.Delete(receipt.ThumbnailHash); err != nil {
+			log.Printf("Failed to delete receipt thumbnail %s: %v", receipt.ThumbnailHash, err)
+		}
+	}
+}
+
+// getOwnedExpense returns one of the workspace's expenses
+func (s *Service) getOwnedExpense(actor types.Actor, expenseID string) (*types.Expense, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	expense, err := s.repo.GetByID(actor.WorkspaceID, expenseID)
+	if err != nil {
+		return nil, ErrExpenseNotFound
+	}
+	return expense, nil
+}
+
+// makeThumbnail scales an image down to fit thumbnailMaxSize and encodes it as JPEG
+func makeThumbnail(data []byte) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > thumbnailMaxPixels {
+		return nil, fmt.Errorf("image too large for thumbnail (%dx%d)", config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > thumbnailMaxSize || height > thumbnailMaxSize {
+		if width >= height {
+			height = max(1, height*thumbnailMaxSize/width)
+			width = thumbnailMaxSize
+		} else {
+			width = max(1, width*thumbnailMaxSize/height)
+			height = thumbnailMaxSize
+		}
+	}
+
+	// Flatten onto white so transparent PNGs don't turn black in JPEG
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	for y := 0; y < height; y++ {
+		sy := bounds.Min.Y + y*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			sx := bounds.Min.X + x*bounds.Dx()/width
+			dst.Set(x, y, blendOverWhite(src.At(sx, sy)))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func blendOverWhite(c color.Color) color.Color {
+	r, g, b, a := c.RGBA()
+	if a == 0xffff {
+		return color.RGBA64{uint16(r), uint16(g), uint16(b), 0xffff}
+	}
+	white := 0xffff - a
+	return color.RGBA64{uint16(r + white), uint16(g + white), uint16(b + white), 0xffff}
+}
+
+// sanitizeFileName keeps only the base name so it is safe in Content-Disposition
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == '"' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "receipt"
+	}
+	return name
+}
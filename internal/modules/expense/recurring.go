@@ -0,0 +1,366 @@
+package expense
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+// Recurring schedules
+const (
+	ScheduleMonthly = "monthly"
+	ScheduleYearly  = "yearly"
+	ScheduleCron    = "cron"
+)
+
+// Recurring template statuses
+const (
+	RecurringActive = "active"
+	RecurringPaused = "paused"
+	RecurringEnded  = "ended"
+)
+
+// maxCatchUp limits how many missed occurrences one scheduler run creates per
+// template, so a long outage or a start date far in the past can't flood the ledger
+const maxCatchUp = 60
+
+var ErrRecurringNotFound = errors.New("recurring expense not found")
+
+// RecurringExpense is a template that creates an expense on every due date
+type RecurringExpense struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	WorkspaceID string     `json:"workspace_id,omitempty"`
+	ProjectID   string     `json:"project_id,omitempty"`
+	Description string     `json:"description"`
+	Payee       string     `json:"payee,omitempty"`
+	Amount      float64    `json:"amount"`
+	Currency    string     `json:"currency"`
+	Category    string     `json:"category"`
+	TaxCategory string     `json:"tax_category,omitempty"`
+	IsBillable  bool       `json:"is_billable"`
+	Schedule    string     `json:"schedule"`           // monthly, yearly, cron
+	Interval    int        `json:"interval,omitempty"` // every N months/years, default 1
+	Cron        string     `json:"cron,omitempty"`     // five-field expression for the cron schedule
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date,omitempty"` // last date an expense may be created for
+	NextRun     time.Time  `json:"next_run"`
+	Occurrence  int        `json:"occurrence"` // index of NextRun for monthly/yearly schedules
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	Created     int        `json:"created"` // number of expenses generated so far
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CreateRecurringExpense validates a template and schedules its first occurrence
+func (s *Service) CreateRecurringExpense(actor types.Actor, template RecurringExpense) (*RecurringExpense, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+	if template.Description == "" || template.Amount <= 0 {
+		return nil, fmt.Errorf("description and a positive amount are required")
+	}
+	if template.StartDate.IsZero() {
+		template.StartDate = time.Now()
+	}
+	if template.EndDate != nil && template.EndDate.Before(template.StartDate) {
+		return nil, fmt.Errorf("end_date must not be before start_date")
+	}
+	if err := s.checkProject(actor, template.ProjectID); err != nil {
+		return nil, err
+	}
+	if template.Interval <= 0 {
+		template.Interval = 1
+	}
+	if template.Currency == "" {
+		template.Currency = "USD"
+	}
+
+	template.ID = types.GenerateID()
+	template.UserID = actor.UserID
+	template.WorkspaceID = actor.WorkspaceID
+	template.Status = RecurringActive
+	template.Occurrence = 0
+	template.Created = 0
+	template.LastRun = nil
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+
+	switch template.Schedule {
+	case ScheduleMonthly, ScheduleYearly:
+		template.NextRun = template.occurrence(0)
+	case ScheduleCron:
+		schedule, err := ParseCron(template.Cron)
+		if err != nil {
+			return nil, err
+		}
+		// The start date itself counts when it matches the expression
+		template.NextRun = schedule.Next(template.StartDate.Add(-time.Minute))
+		if template.NextRun.IsZero() {
+			return nil, fmt.Errorf("cron expression %q never fires", template.Cron)
+		}
+	default:
+		return nil, fmt.Errorf("schedule must be %q, %q or %q", ScheduleMonthly, ScheduleYearly, ScheduleCron)
+	}
+
+	if err := s.repo.SaveRecurring(&template); err != nil {
+		return nil, fmt.Errorf("failed to save recurring expense: %w", err)
+	}
+
+	log.Printf("🔁 Recurring expense scheduled: %s (%s, next %s)", template.Description, template.Schedule, template.NextRun.Format("2006-01-02"))
+	return &template, nil
+}
+
+// GetRecurringExpenses returns the workspace's templates ordered by next due date
+func (s *Service) GetRecurringExpenses(actor types.Actor) ([]*RecurringExpense, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	templates, err := s.repo.GetRecurringByWorkspaceID(actor.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].NextRun.Before(templates[j].NextRun)
+	})
+	return templates, nil
+}
+
+// SetRecurringStatus pauses or resumes a template. Resuming skips the dates
+// missed while paused instead of back-filling them.
+func (s *Service) SetRecurringStatus(actor types.Actor, id, status string) (*RecurringExpense, error) {
+	template, err := s.repo.GetRecurring(actor.WorkspaceID, id)
+	if err != nil {
+		return nil, ErrRecurringNotFound
+	}
+	if err := actor.RequireEdit(template.UserID); err != nil {
+		return nil, err
+	}
+	if template.Status == RecurringEnded {
+		return nil, fmt.Errorf("recurring expense has ended")
+	}
+
+	switch status {
+	case RecurringPaused:
+	case RecurringActive:
+		if template.Status == RecurringPaused {
+			now := time.Now()
+			for !template.NextRun.IsZero() && template.NextRun.Before(now) {
+				if err := template.advance(); err != nil {
+					return nil, err
+				}
+			}
+			template.checkEnded()
+		}
+	default:
+		return nil, fmt.Errorf("status must be %q or %q", RecurringActive, RecurringPaused)
+	}
+
+	if template.Status != RecurringEnded {
+		template.Status = status
+	}
+	template.UpdatedAt = time.Now()
+
+	if err := s.repo.SaveRecurring(template); err != nil {
+		return nil, fmt.Errorf("failed to save recurring expense: %w", err)
+	}
+	return template, nil
+}
+
+// DeleteRecurringExpense removes a template; expenses it already created are kept
+func (s *Service) DeleteRecurringExpense(actor types.Actor, id string) error {
+	template, err := s.repo.GetRecurring(actor.WorkspaceID, id)
+	if err != nil {
+		return ErrRecurringNotFound
+	}
+	if err := actor.RequireEdit(template.UserID); err != nil {
+		return err
+	}
+	return s.repo.DeleteRecurring(actor.WorkspaceID, id)
+}
+
+// StartRecurringScheduler creates due recurring expenses now and then every
+// interval until ctx is cancelled
+func (s *Service) StartRecurringScheduler(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if created, err := s.RunDueRecurring(time.Now()); err != nil {
+				log.Printf("Recurring expense run failed: %v", err)
+			} else if created > 0 {
+				log.Printf("🔁 Created %d recurring expenses", created)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	log.Printf("Recurring expense scheduler started (every %s)", interval)
+}
+
+// RunDueRecurring creates an expense for every active template occurrence due
+// at or before now and returns how many were created
+func (s *Service) RunDueRecurring(now time.Time) (int, error) {
+	templates, err := s.repo.GetAllRecurring()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load recurring expenses: %w", err)
+	}
+
+	created := 0
+	for _, template := range templates {
+		if template.Status != RecurringActive {
+			continue
+		}
+
+		n, err := s.runTemplate(template, now)
+		created += n
+		if err != nil {
+			log.Printf("Recurring expense %s failed: %v", template.ID, err)
+		}
+	}
+	return created, nil
+}
+
+func (s *Service) runTemplate(template *RecurringExpense, now time.Time) (int, error) {
+	created := 0
+	for created < maxCatchUp && !template.NextRun.IsZero() && !template.NextRun.After(now) {
+		if template.checkEnded() {
+			break
+		}
+
+		expense, err := s.createFromTemplate(template)
+		if err != nil {
+			return created, err
+		}
+		created++
+
+		due := template.NextRun
+		template.LastRun = &due
+		template.Created++
+		if err := template.advance(); err != nil {
+			return created, err
+		}
+
+		// The expense ID is derived from the due date, so a crash before this
+		// save makes the next run pick up the same expense instead of a copy
+		template.checkEnded()
+		template.UpdatedAt = time.Now()
+		if err := s.repo.SaveRecurring(template); err != nil {
+			return created, fmt.Errorf("failed to save recurring expense: %w", err)
+		}
+
+		event := types.NewEvent("recurring_expense_created", "expense_service", map[string]any{
+			"recurring_id": template.ID,
+			"expense_id":   expense.ID,
+			"user_id":      expense.UserID,
+			"workspace_id": types.WorkspaceOf(expense.WorkspaceID, expense.UserID),
+			"amount":       expense.Amount,
+			"due_date":     due,
+		})
+
+		s.eventBus.Publish("expense.recurring.created", event)
+	}
+
+	if template.checkEnded() {
+		template.UpdatedAt = time.Now()
+		if err := s.repo.SaveRecurring(template); err != nil {
+			return created, fmt.Errorf("failed to save recurring expense: %w", err)
+		}
+	}
+	return created, nil
+}
+
+// createFromTemplate creates the expense for the template's NextRun, or
+// returns the one a run that was interrupted before saving the template created
+func (s *Service) createFromTemplate(template *RecurringExpense) (*types.Expense, error) {
+	id := template.occurrenceID()
+	if existing, err := s.repo.GetByID(types.WorkspaceOf(template.WorkspaceID, template.UserID), id); err == nil {
+		return existing, nil
+	}
+
+	expense := &types.Expense{
+		ID:          id,
+		UserID:      template.UserID,
+		WorkspaceID: template.WorkspaceID,
+		ProjectID:   template.ProjectID,
+		Amount:      template.Amount,
+		Currency:    template.Currency,
+		Category:    template.Category,
+		TaxCategory: template.TaxCategory,
+		Description: template.Description,
+		Payee:       template.Payee,
+		Date:        template.NextRun,
+		IsBillable:  template.IsBillable,
+		RecurringID: template.ID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if expense.Category == "" {
+		s.applyCategorization(expense)
+	}
+
+	if err := s.addExpense(expense); err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// occurrenceID is the expense ID for the template's NextRun occurrence
+func (r *RecurringExpense) occurrenceID() string {
+	return r.ID + "-" + r.NextRun.UTC().Format("20060102T150405")
+}
+
+// advance moves NextRun to the following occurrence
+func (r *RecurringExpense) advance() error {
+	switch r.Schedule {
+	case ScheduleMonthly, ScheduleYearly:
+		r.Occurrence++
+		r.NextRun = r.occurrence(r.Occurrence)
+	case ScheduleCron:
+		schedule, err := ParseCron(r.Cron)
+		if err != nil {
+			return err
+		}
+		r.NextRun = schedule.Next(r.NextRun)
+	}
+	return nil
+}
+
+// checkEnded marks the template ended once NextRun passes the end date
+func (r *RecurringExpense) checkEnded() bool {
+	if r.NextRun.IsZero() || (r.EndDate != nil && r.NextRun.After(*r.EndDate)) {
+		r.Status = RecurringEnded
+	}
+	return r.Status == RecurringEnded
+}
+
+// occurrence computes the n-th monthly or yearly date from the start date.
+// Days past the end of a short month are clamped, and later months return
+// to the original day (Jan 31, Feb 28, Mar 31).
+func (r *RecurringExpense) occurrence(n int) time.Time {
+	months := n * r.Interval
+	if r.Schedule == ScheduleYearly {
+		months *= 12
+	}
+
+	start := r.StartDate
+	first := time.Date(start.Year(), start.Month()+time.Month(months), 1,
+		start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+
+	return first.AddDate(0, 0, min(start.Day(), lastDay)-1)
+}
@@ -0,0 +1,53 @@
+package expense
+
+import (
+	"testing"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+func TestRunDueRecurringSkipsOccurrenceCreatedBeforeCrash(t *testing.T) {
+	s := newTestService(t)
+	actor := types.PersonalActor("user-1")
+
+	template, err := s.CreateRecurringExpense(actor, RecurringExpense{
+		Description: "Hosting",
+		Amount:      20,
+		Category:    "software",
+		Schedule:    ScheduleMonthly,
+		StartDate:   time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("CreateRecurringExpense: %v", err)
+	}
+
+	// A run that created January's expense but died before saving the template
+	if _, err := s.createFromTemplate(template); err != nil {
+		t.Fatalf("createFromTemplate: %v", err)
+	}
+
+	created, err := s.RunDueRecurring(time.Date(2026, time.March, 20, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("RunDueRecurring: %v", err)
+	}
+	if created != 3 {
+		t.Errorf("created = %d, want 3", created)
+	}
+
+	expenses, err := s.GetExpenses(actor)
+	if err != nil {
+		t.Fatalf("GetExpenses: %v", err)
+	}
+	if len(expenses) != 3 {
+		t.Errorf("got %d expenses, want one per month", len(expenses))
+	}
+
+	saved, err := s.repo.GetRecurring(actor.WorkspaceID, template.ID)
+	if err != nil {
+		t.Fatalf("GetRecurring: %v", err)
+	}
+	if saved.Created != 3 || saved.Occurrence != 3 {
+		t.Errorf("template created=%d occurrence=%d, want 3 and 3", saved.Created, saved.Occurrence)
+	}
+}
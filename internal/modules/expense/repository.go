@@ -2,6 +2,8 @@ package expense
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	"datastar-go/internal/shared/types"
 
@@ -26,7 +28,9 @@ func (r *Repository) Create(expense *types.Expense) error {
 	})
 }
 
-func (r *Repository) GetByID(id string) (*types.Expense, error) {
+// GetByID returns a workspace's expense. Another workspace's expense is
+// reported as badger.ErrKeyNotFound.
+func (r *Repository) GetByID(workspaceID, id string) (*types.Expense, error) {
 	var expense types.Expense
 	err := r.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get([]byte("expense:" + id))
@@ -37,10 +41,13 @@ func (r *Repository) GetByID(id string) (*types.Expense, error) {
 			return json.Unmarshal(val, &expense)
 		})
 	})
+	if err == nil && !types.InWorkspace(expense.WorkspaceID, expense.UserID, workspaceID) {
+		return nil, badger.ErrKeyNotFound
+	}
 	return &expense, err
 }
 
-func (r *Repository) GetByUserID(userID string) ([]*types.Expense, error) {
+func (r *Repository) GetByWorkspaceID(workspaceID string) ([]*types.Expense, error) {
 	var expenses []*types.Expense
 	err := r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
@@ -55,7 +62,7 @@ func (r *Repository) GetByUserID(userID string) ([]*types.Expense, error) {
 				if err := json.Unmarshal(val, &expense); err != nil {
 					return err
 				}
-				if expense.UserID == userID {
+				if types.InWorkspace(expense.WorkspaceID, expense.UserID, workspaceID) {
 					expenses = append(expenses, &expense)
 				}
 				return nil
@@ -69,7 +76,7 @@ func (r *Repository) GetByUserID(userID string) ([]*types.Expense, error) {
 	return expenses, err
 }
 
-func (r *Repository) GetByProjectID(projectID string) ([]*types.Expense, error) {
+func (r *Repository) GetByProjectID(workspaceID, projectID string) ([]*types.Expense, error) {
 	var expenses []*types.Expense
 	err := r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
@@ -84,7 +91,36 @@ func (r *Repository) GetByProjectID(projectID string) ([]*types.Expense, error)
 				if err := json.Unmarshal(val, &expense); err != nil {
 					return err
 				}
-				if expense.ProjectID == projectID {
+				if types.InWorkspace(expense.WorkspaceID, expense.UserID, workspaceID) && expense.ProjectID == projectID {
+					expenses = append(expenses, &expense)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return expenses, err
+}
+
+func (r *Repository) GetByReceiptHash(hash string) ([]*types.Expense, error) {
+	var expenses []*types.Expense
+	err := r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte("expense:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var expense types.Expense
+				if err := json.Unmarshal(val, &expense); err != nil {
+					return err
+				}
+				if expense.ReceiptFile != nil && expense.ReceiptFile.Hash == hash {
 					expenses = append(expenses, &expense)
 				}
 				return nil
@@ -107,3 +143,311 @@ func (r *Repository) Delete(id string) error {
 		return txn.Delete([]byte("expense:" + id))
 	})
 }
+
+func (r *Repository) SaveImport(batch *ImportBatch) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		return txn.Set([]byte("expense_import:"+batch.ID), data)
+	})
+}
+
+func (r *Repository) GetImport(id string) (*ImportBatch, error) {
+	var batch ImportBatch
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("expense_import:" + id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &batch)
+		})
+	})
+	return &batch, err
+}
+
+func (r *Repository) GetImportsByWorkspaceID(workspaceID string) ([]*ImportBatch, error) {
+	var batches []*ImportBatch
+	err := r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte("expense_import:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var batch ImportBatch
+				if err := json.Unmarshal(val, &batch); err != nil {
+					return err
+				}
+				if types.InWorkspace(batch.WorkspaceID, batch.UserID, workspaceID) {
+					batches = append(batches, &batch)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return batches, err
+}
+
+func (r *Repository) DeleteImport(id string) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte("expense_import:" + id))
+	})
+}
+
+func (r *Repository) SaveCSVProfile(userID string, profile *CSVProfile) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(profile)
+		if err != nil {
+			return err
+		}
+		return txn.Set([]byte("expense_csv_profile:"+userID+":"+profile.Name), data)
+	})
+}
+
+func (r *Repository) GetCSVProfile(userID, name string) (*CSVProfile, error) {
+	var profile CSVProfile
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("expense_csv_profile:" + userID + ":" + name))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &profile)
+		})
+	})
+	return &profile, err
+}
+
+func (r *Repository) GetCSVProfilesByUserID(userID string) ([]*CSVProfile, error) {
+	var profiles []*CSVProfile
+	err := r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte("expense_csv_profile:" + userID + ":")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var profile CSVProfile
+				if err := json.Unmarshal(val, &profile); err != nil {
+					return err
+				}
+				profiles = append(profiles, &profile)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return profiles, err
+}
+
+func (r *Repository) SaveCategory(category *Category) error {
+	return r.save("expense_category:"+category.UserID+":"+category.ID, category)
+}
+
+func (r *Repository) GetCategoriesByUserID(userID string) ([]*Category, error) {
+	var categories []*Category
+	err := r.scan("expense_category:"+userID+":", func(val []byte) error {
+		var category Category
+		if err := json.Unmarshal(val, &category); err != nil {
+			return err
+		}
+		categories = append(categories, &category)
+		return nil
+	})
+	return categories, err
+}
+
+func (r *Repository) DeleteCategory(userID, id string) error {
+	return r.delete("expense_category:" + userID + ":" + id)
+}
+
+func (r *Repository) SaveRule(rule *CategoryRule) error {
+	return r.save("expense_rule:"+rule.UserID+":"+rule.ID, rule)
+}
+
+func (r *Repository) GetRule(userID, id string) (*CategoryRule, error) {
+	var rule CategoryRule
+	err := r.load("expense_rule:"+userID+":"+id, &rule)
+	return &rule, err
+}
+
+func (r *Repository) GetRulesByUserID(userID string) ([]*CategoryRule, error) {
+	var rules []*CategoryRule
+	err := r.scan("expense_rule:"+userID+":", func(val []byte) error {
+		var rule CategoryRule
+		if err := json.Unmarshal(val, &rule); err != nil {
+			return err
+		}
+		if rule.PayeeRegex != "" {
+			// Compile once per load; Matches treats an invalid pattern as no match
+			rule.payeePattern()
+		}
+		rules = append(rules, &rule)
+		return nil
+	})
+	return rules, err
+}
+
+func (r *Repository) DeleteRule(userID, id string) error {
+	return r.delete("expense_rule:" + userID + ":" + id)
+}
+
+func (r *Repository) SaveCorrection(correction *CategoryCorrection) error {
+	return r.save("expense_correction:"+correction.UserID+":"+correction.ID, correction)
+}
+
+func (r *Repository) GetCorrectionsByUserID(userID string) ([]*CategoryCorrection, error) {
+	var corrections []*CategoryCorrection
+	err := r.scan("expense_correction:"+userID+":", func(val []byte) error {
+		var correction CategoryCorrection
+		if err := json.Unmarshal(val, &correction); err != nil {
+			return err
+		}
+		corrections = append(corrections, &correction)
+		return nil
+	})
+	return corrections, err
+}
+
+func (r *Repository) SaveCategorizationSettings(settings *CategorizationSettings) error {
+	return r.save("expense_categorization:"+settings.UserID, settings)
+}
+
+func (r *Repository) GetCategorizationSettings(userID string) (*CategorizationSettings, error) {
+	settings := &CategorizationSettings{UserID: userID}
+	err := r.load("expense_categorization:"+userID, settings)
+	if err == badger.ErrKeyNotFound {
+		return settings, nil
+	}
+	return settings, err
+}
+
+func (r *Repository) SaveMileageRate(rate *MileageRate) error {
+	key := fmt.Sprintf("expense_mileage_rate:%s:%s:%d:%s", rate.UserID, rate.Jurisdiction, rate.Year, rate.Vehicle)
+	return r.save(key, rate)
+}
+
+func (r *Repository) GetMileageRatesByUserID(userID string) ([]*MileageRate, error) {
+	var rates []*MileageRate
+	err := r.scan("expense_mileage_rate:"+userID+":", func(val []byte) error {
+		var rate MileageRate
+		if err := json.Unmarshal(val, &rate); err != nil {
+			return err
+		}
+		rates = append(rates, &rate)
+		return nil
+	})
+	return rates, err
+}
+
+func (r *Repository) SavePerDiemRate(rate *PerDiemRate) error {
+	key := fmt.Sprintf("expense_per_diem_rate:%s:%s:%d:%s", rate.UserID, rate.Jurisdiction, rate.Year, strings.ToLower(rate.Location))
+	return r.save(key, rate)
+}
+
+func (r *Repository) GetPerDiemRatesByUserID(userID string) ([]*PerDiemRate, error) {
+	var rates []*PerDiemRate
+	err := r.scan("expense_per_diem_rate:"+userID+":", func(val []byte) error {
+		var rate PerDiemRate
+		if err := json.Unmarshal(val, &rate); err != nil {
+			return err
+		}
+		rates = append(rates, &rate)
+		return nil
+	})
+	return rates, err
+}
+
+// SaveRecurring stores a template under its workspace
+func (r *Repository) SaveRecurring(template *RecurringExpense) error {
+	workspaceID := types.WorkspaceOf(template.WorkspaceID, template.UserID)
+	return r.save("expense_recurring:"+workspaceID+":"+template.ID, template)
+}
+
+func (r *Repository) GetRecurring(workspaceID, id string) (*RecurringExpense, error) {
+	var template RecurringExpense
+	err := r.load("expense_recurring:"+workspaceID+":"+id, &template)
+	return &template, err
+}
+
+func (r *Repository) GetRecurringByWorkspaceID(workspaceID string) ([]*RecurringExpense, error) {
+	return r.scanRecurring("expense_recurring:" + workspaceID + ":")
+}
+
+func (r *Repository) GetAllRecurring() ([]*RecurringExpense, error) {
+	return r.scanRecurring("expense_recurring:")
+}
+
+func (r *Repository) DeleteRecurring(workspaceID, id string) error {
+	return r.delete("expense_recurring:" + workspaceID + ":" + id)
+}
+
+func (r *Repository) scanRecurring(prefix string) ([]*RecurringExpense, error) {
+	var templates []*RecurringExpense
+	err := r.scan(prefix, func(val []byte) error {
+		var template RecurringExpense
+		if err := json.Unmarshal(val, &template); err != nil {
+			return err
+		}
+		templates = append(templates, &template)
+		return nil
+	})
+	return templates, err
+}
+
+func (r *Repository) save(key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), data)
+	})
+}
+
+func (r *Repository) load(key string, value any) error {
+	return r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, value)
+		})
+	})
+}
+
+func (r *Repository) delete(key string) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (r *Repository) scan(prefix string, fn func(val []byte) error) error {
+	return r.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		p := []byte(prefix)
+		for it.Seek(p); it.ValidForPrefix(p); it.Next() {
+			if err := it.Item().Value(fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package expense
+
+import (
+	"errors"
+	"testing"
+
+	"datastar-go/internal/shared/testutil"
+	"datastar-go/internal/shared/types"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+func TestRepositoryScopesExpensesToWorkspace(t *testing.T) {
+	repo := NewRepository(testutil.MemoryDB(t))
+	shared := &types.Expense{ID: "exp-1", UserID: "user-1", WorkspaceID: "ws-1", ProjectID: "proj-1"}
+	legacy := &types.Expense{ID: "exp-2", UserID: "user-1", ProjectID: "proj-1"} // stored before workspaces
+	for _, expense := range []*types.Expense{shared, legacy} {
+		if err := repo.Create(expense); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	if _, err := repo.GetByID("ws-1", shared.ID); err != nil {
+		t.Errorf("own workspace: %v", err)
+	}
+	if _, err := repo.GetByID("user-1", legacy.ID); err != nil {
+		t.Errorf("personal workspace: %v", err)
+	}
+	for _, lookup := range []struct{ workspaceID, id string }{
+		{"ws-2", shared.ID},
+		{"user-1", shared.ID},
+		{"ws-1", legacy.ID},
+	} {
+		if _, err := repo.GetByID(lookup.workspaceID, lookup.id); !errors.Is(err, badger.ErrKeyNotFound) {
+			t.Errorf("GetByID(%s, %s) err = %v, want not found", lookup.workspaceID, lookup.id, err)
+		}
+	}
+
+	expenses, err := repo.GetByProjectID("ws-2", "proj-1")
+	if err != nil || len(expenses) != 0 {
+		t.Errorf("GetByProjectID(ws-2) = %d expenses, %v; want none", len(expenses), err)
+	}
+	expenses, err = repo.GetByWorkspaceID("ws-1")
+	if err != nil || len(expenses) != 1 {
+		t.Errorf("GetByWorkspaceID(ws-1) = %d expenses, %v; want 1", len(expenses), err)
+	}
+}
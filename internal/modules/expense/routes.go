@@ -10,7 +10,37 @@ func (h *Handlers) SetupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/expense/list", h.handleGetExpenses)
 	mux.HandleFunc("GET /api/expense/project", h.handleGetProjectExpenses)
 	mux.HandleFunc("PUT /api/expense/update", h.handleUpdateExpense)
+	mux.HandleFunc("PATCH /api/expense/update", h.handlePatchExpense)
 	mux.HandleFunc("DELETE /api/expense/delete", h.handleDeleteExpense)
+	mux.HandleFunc("POST /api/expense/receipt", h.handleUploadReceipt)
+	mux.HandleFunc("GET /api/expense/receipt", h.handleGetReceipt)
+	mux.HandleFunc("DELETE /api/expense/receipt", h.handleDeleteReceipt)
+	mux.HandleFunc("POST /api/expense/import", h.handleStageImport)
+	mux.HandleFunc("GET /api/expense/import", h.handleGetImport)
+	mux.HandleFunc("DELETE /api/expense/import", h.handleDeleteImport)
+	mux.HandleFunc("GET /api/expense/import/list", h.handleGetImports)
+	mux.HandleFunc("PUT /api/expense/import/review", h.handleReviewImport)
+	mux.HandleFunc("POST /api/expense/import/commit", h.handleCommitImport)
+	mux.HandleFunc("GET /api/expense/import/profiles", h.handleGetCSVProfiles)
+	mux.HandleFunc("POST /api/expense/import/profiles", h.handleSaveCSVProfile)
+	mux.HandleFunc("POST /api/expense/category/create", h.handleCreateCategory)
+	mux.HandleFunc("GET /api/expense/category/list", h.handleGetCategories)
+	mux.HandleFunc("DELETE /api/expense/category/delete", h.handleDeleteCategory)
+	mux.HandleFunc("POST /api/expense/rule/create", h.handleCreateRule)
+	mux.HandleFunc("GET /api/expense/rule/list", h.handleGetRules)
+	mux.HandleFunc("POST /api/expense/rule/accept", h.handleAcceptRule)
+	mux.HandleFunc("DELETE /api/expense/rule/delete", h.handleDeleteRule)
+	mux.HandleFunc("GET /api/expense/categorization/settings", h.handleGetCategorizationSettings)
+	mux.HandleFunc("PUT /api/expense/categorization/settings", h.handleUpdateCategorizationSettings)
+	mux.HandleFunc("POST /api/expense/recategorize", h.handleRecategorize)
+	mux.HandleFunc("POST /api/expense/mileage", h.handleCreateMileage)
+	mux.HandleFunc("POST /api/expense/per-diem", h.handleCreatePerDiem)
+	mux.HandleFunc("GET /api/expense/rates", h.handleGetRates)
+	mux.HandleFunc("POST /api/expense/rates", h.handleSaveRate)
+	mux.HandleFunc("POST /api/expense/recurring/create", h.handleCreateRecurring)
+	mux.HandleFunc("GET /api/expense/recurring/list", h.handleGetRecurring)
+	mux.HandleFunc("PUT /api/expense/recurring/status", h.handleUpdateRecurringStatus)
+	mux.HandleFunc("DELETE /api/expense/recurring/delete", h.handleDeleteRecurring)
 	mux.HandleFunc("GET /api/expense/health", h.handleHealth)
 
 	log.Println("Expense API routes configured")
@@ -1,6 +1,7 @@
 package expense
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -10,21 +11,53 @@ import (
 	"github.com/dgraph-io/badger/v4"
 )
 
+var ErrProjectNotFound = errors.New("project not found")
+
+// ProjectSource resolves the workspace's projects expenses are recorded against
+type ProjectSource interface {
+	GetProject(actor types.Actor, projectID string) (*types.Project, error)
+}
+
 type Service struct {
 	eventBus types.EventBus
 	repo     *Repository
+	receipts *BlobStore
+	projects ProjectSource
 }
 
-func NewService(eventBus types.EventBus, db *badger.DB) *Service {
+func NewService(eventBus types.EventBus, db *badger.DB, receipts *BlobStore) *Service {
 	service := &Service{
 		eventBus: eventBus,
 		repo:     NewRepository(db),
+		receipts: receipts,
 	}
 
 	service.setupEventSubscriptions()
 	return service
 }
 
+// SetProjectSource wires the project lookup that keeps expenses on the actor's
+// own workspace projects. The client service depends on this one, so it is
+// set after both are built.
+func (s *Service) SetProjectSource(projects ProjectSource) {
+	s.projects = projects
+}
+
+// checkProject rejects a project that is not one of the actor's workspace
+// projects. An empty ID leaves the expense unassigned.
+func (s *Service) checkProject(actor types.Actor, projectID string) error {
+	if projectID == "" {
+		return nil
+	}
+	if s.projects == nil {
+		return fmt.Errorf("project source not configured")
+	}
+	if _, err := s.projects.GetProject(actor, projectID); err != nil {
+		return fmt.Errorf("%w: %s", ErrProjectNotFound, projectID)
+	}
+	return nil
+}
+
 func (s *Service) setupEventSubscriptions() {
 	s.eventBus.SubscribeQueue("client.project.started", "expense_service", s.handleProjectStarted)
 	s.eventBus.SubscribeQueue("time.entry.completed", "expense_service", s.handleTimeEntryCompleted)
@@ -32,60 +65,99 @@ func (s *Service) setupEventSubscriptions() {
 	log.Println("Expense service event subscriptions configured")
 }
 
-func (s *Service) CreateExpense(userID, projectID, category, description string, amount float64) (*types.Expense, error) {
+func (s *Service) CreateExpense(actor types.Actor, projectID, category, description string, amount float64, isBillable bool, date time.Time) (*types.Expense, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+	if err := s.checkProject(actor, projectID); err != nil {
+		return nil, err
+	}
+	if date.IsZero() {
+		date = time.Now()
+	}
+
 	expense := &types.Expense{
 		ID:          types.GenerateID(),
-		UserID:      userID,
+		UserID:      actor.UserID,
+		WorkspaceID: actor.WorkspaceID,
 		ProjectID:   projectID,
 		Category:    category,
 		Description: description,
 		Amount:      amount,
 		Currency:    "USD",
+		Date:        date,
+		IsBillable:  isBillable,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
+	s.applyCategorization(expense)
+
+	if err := s.addExpense(expense); err != nil {
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+// addExpense persists a fully built expense and announces it
+func (s *Service) addExpense(expense *types.Expense) error {
 	err := s.repo.Create(expense)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create expense: %w", err)
+		return fmt.Errorf("failed to create expense: %w", err)
 	}
 
 	event := types.NewEvent("expense_created", "expense_service", map[string]any{
-		"expense_id": expense.ID,
-		"user_id":    expense.UserID,
-		"project_id": expense.ProjectID,
-		"amount":     expense.Amount,
-		"category":   expense.Category,
+		"expense_id":   expense.ID,
+		"user_id":      expense.UserID,
+		"workspace_id": types.WorkspaceOf(expense.WorkspaceID, expense.UserID),
+		"project_id":   expense.ProjectID,
+		"amount":       expense.Amount,
+		"category":     expense.Category,
 	})
 
 	s.eventBus.Publish("expense.created", event)
 	log.Printf("💰 Expense created: $%.2f for %s", expense.Amount, expense.Description)
 
-	return expense, nil
+	return nil
 }
 
-func (s *Service) GetExpenses(userID string) ([]*types.Expense, error) {
-	return s.repo.GetByUserID(userID)
+func (s *Service) GetExpenses(actor types.Actor) ([]*types.Expense, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByWorkspaceID(actor.WorkspaceID)
 }
 
-func (s *Service) GetExpensesByProject(projectID string) ([]*types.Expense, error) {
-	return s.repo.GetByProjectID(projectID)
+func (s *Service) GetExpensesByProject(actor types.Actor, projectID string) ([]*types.Expense, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByProjectID(actor.WorkspaceID, projectID)
 }
 
-func (s *Service) UpdateExpense(expenseID string, updates map[string]any) (*types.Expense, error) {
-	expense, err := s.repo.GetByID(expenseID)
+// UpdateExpense applies a merge patch to one of the workspace's expenses and
+// publishes an event for every field that changed
+func (s *Service) UpdateExpense(actor types.Actor, expenseID string, patch ExpensePatch) (*types.Expense, error) {
+	original, err := s.getOwnedExpense(actor, expenseID)
 	if err != nil {
-		return nil, fmt.Errorf("expense not found: %w", err)
+		return nil, err
+	}
+	if err := actor.RequireEdit(original.UserID); err != nil {
+		return nil, err
 	}
 
-	if description, ok := updates["description"].(string); ok {
-		expense.Description = description
+	expense, changes, err := patch.apply(original)
+	if err != nil {
+		return nil, err
 	}
-	if amount, ok := updates["amount"].(float64); ok {
-		expense.Amount = amount
+	if len(changes) == 0 {
+		return original, nil
 	}
-	if category, ok := updates["category"].(string); ok {
-		expense.Category = category
+	if patch.ProjectID.Set {
+		if err := s.checkProject(actor, expense.ProjectID); err != nil {
+			return nil, err
+		}
 	}
 
 	expense.UpdatedAt = time.Now()
@@ -95,20 +167,51 @@ func (s *Service) UpdateExpense(expenseID string, updates map[string]any) (*type
 		return nil, fmt.Errorf("failed to update expense: %w", err)
 	}
 
+	workspaceID := types.WorkspaceOf(expense.WorkspaceID, expense.UserID)
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+
+		if change.Field == "category" {
+			s.recordCorrection(workspaceID, expensePayee(expense), expense.Category, expense.TaxCategory)
+		}
+
+		event := types.NewEvent("expense_field_changed", "expense_service", map[string]any{
+			"expense_id":   expense.ID,
+			"user_id":      expense.UserID,
+			"workspace_id": workspaceID,
+			"field":        change.Field,
+			"old_value":    change.Old,
+			"new_value":    change.New,
+		})
+
+		s.eventBus.Publish("expense.field_changed", event)
+	}
+
 	event := types.NewEvent("expense_updated", "expense_service", map[string]any{
-		"expense_id": expense.ID,
-		"user_id":    expense.UserID,
-		"updates":    updates,
+		"expense_id":   expense.ID,
+		"user_id":      expense.UserID,
+		"workspace_id": workspaceID,
+		"project_id":   expense.ProjectID,
+		"fields":       fields,
+		"changes":      changes,
 	})
 
 	s.eventBus.Publish("expense.updated", event)
 	return expense, nil
 }
 
-func (s *Service) DeleteExpense(expenseID string) error {
-	expense, err := s.repo.GetByID(expenseID)
+func (s *Service) DeleteExpense(actor types.Actor, expenseID string) error {
+	expense, err := s.getOwnedExpense(actor, expenseID)
 	if err != nil {
-		return fmt.Errorf("expense not found: %w", err)
+		return err
+	}
+	if err := actor.RequireEdit(expense.UserID); err != nil {
+		return err
+	}
+
+	if expense.IsBilled {
+		return fmt.Errorf("%w: remove it from invoice %s first", ErrExpenseBilled, expense.InvoiceID)
 	}
 
 	err = s.repo.Delete(expenseID)
@@ -116,10 +219,15 @@ func (s *Service) DeleteExpense(expenseID string) error {
 		return fmt.Errorf("failed to delete expense: %w", err)
 	}
 
+	if expense.ReceiptFile != nil {
+		s.releaseReceipt(expense.ReceiptFile, expense.ID)
+	}
+
 	event := types.NewEvent("expense_deleted", "expense_service", map[string]any{
-		"expense_id": expense.ID,
-		"user_id":    expense.UserID,
-		"project_id": expense.ProjectID,
+		"expense_id":   expense.ID,
+		"user_id":      expense.UserID,
+		"workspace_id": types.WorkspaceOf(expense.WorkspaceID, expense.UserID),
+		"project_id":   expense.ProjectID,
 	})
 
 	s.eventBus.Publish("expense.deleted", event)
@@ -0,0 +1,548 @@
+package expense
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported bank statement formats
+const (
+	FormatCSV     = "csv"
+	FormatOFX     = "ofx"
+	FormatCAMT053 = "camt053"
+)
+
+// StatementLine is a single transaction parsed from a bank statement.
+// Amount is negative for money leaving the account.
+type StatementLine struct {
+	Date        time.Time `json:"date"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	Payee       string    `json:"payee"`
+	Description string    `json:"description"`
+	Reference   string    `json:"reference"`
+	Account     string    `json:"account"`
+}
+
+// CSVProfile maps the columns of a bank's CSV export onto statement fields.
+// Columns are referenced by their header name (case-insensitive).
+type CSVProfile struct {
+	Name              string `json:"name"`
+	Delimiter         string `json:"delimiter"`
+	SkipRows          int    `json:"skip_rows"`
+	DateColumn        string `json:"date_column"`
+	DateFormat        string `json:"date_format"` // Go reference layout, e.g. 2006-01-02
+	AmountColumn      string `json:"amount_column,omitempty"`
+	DebitColumn       string `json:"debit_column,omitempty"`
+	CreditColumn      string `json:"credit_column,omitempty"`
+	PayeeColumn       string `json:"payee_column,omitempty"`
+	DescriptionColumn string `json:"description_column,omitempty"`
+	ReferenceColumn   string `json:"reference_column,omitempty"`
+	CurrencyColumn    string `json:"currency_column,omitempty"`
+	Currency          string `json:"currency,omitempty"`      // used when there is no currency column
+	DecimalComma      bool   `json:"decimal_comma,omitempty"` // 1.234,56 instead of 1,234.56
+	DebitsPositive    bool   `json:"debits_positive,omitempty"`
+}
+
+// builtinCSVProfiles cover the common export layouts out of the box
+var builtinCSVProfiles = map[string]CSVProfile{
+	"generic": {
+		Name:              "generic",
+		Delimiter:         ",",
+		DateColumn:        "date",
+		DateFormat:        "2006-01-02",
+		AmountColumn:      "amount",
+		PayeeColumn:       "payee",
+		DescriptionColumn: "description",
+		ReferenceColumn:   "reference",
+		CurrencyColumn:    "currency",
+	},
+	"generic_eu": {
+		Name:              "generic_eu",
+		Delimiter:         ";",
+		DateColumn:        "date",
+		DateFormat:        "02.01.2006",
+		AmountColumn:      "amount",
+		PayeeColumn:       "payee",
+		DescriptionColumn: "description",
+		ReferenceColumn:   "reference",
+		CurrencyColumn:    "currency",
+		DecimalComma:      true,
+	},
+	"debit_credit": {
+		Name:              "debit_credit",
+		Delimiter:         ",",
+		DateColumn:        "date",
+		DateFormat:        "01/02/2006",
+		DebitColumn:       "debit",
+		CreditColumn:      "credit",
+		DescriptionColumn: "description",
+		ReferenceColumn:   "reference",
+		Currency:          "USD",
+	},
+}
+
+// Validate checks that the profile names enough columns to build a statement line
+func (p *CSVProfile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	if p.DateColumn == "" || p.DateFormat == "" {
+		return fmt.Errorf("date column and date format are required")
+	}
+	if p.AmountColumn == "" && p.DebitColumn == "" {
+		return fmt.Errorf("either an amount column or a debit column is required")
+	}
+	if p.Delimiter != "" && len([]rune(p.Delimiter)) != 1 {
+		return fmt.Errorf("delimiter must be a single character")
+	}
+	return nil
+}
+
+// DetectStatementFormat guesses the statement format from its content
+func DetectStatementFormat(data []byte) string {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	upper := bytes.ToUpper(head)
+
+	switch {
+	case bytes.Contains(head, []byte("camt.053")) || bytes.Contains(head, []byte("BkToCstmrStmt")):
+		return FormatCAMT053
+	case bytes.Contains(upper, []byte("OFXHEADER")) || bytes.Contains(upper, []byte("<OFX>")):
+		return FormatOFX
+	default:
+		return FormatCSV
+	}
+}
+
+// ParseCSVStatement parses a CSV export using the given column profile
+func ParseCSVStatement(data []byte, profile CSVProfile) ([]StatementLine, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if profile.Delimiter != "" {
+		reader.Comma = []rune(profile.Delimiter)[0]
+	}
+
+	for i := 0; i < profile.SkipRows; i++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, fmt.Errorf("failed to skip row %d: %w", i+1, err)
+		}
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	// Text columns are optional so one profile fits exports with fewer columns
+	var idx struct{ date, amount, debit, credit, payee, description, reference, currency int }
+	for _, c := range []struct {
+		name     string
+		dst      *int
+		required bool
+	}{
+		{profile.DateColumn, &idx.date, true},
+		{profile.AmountColumn, &idx.amount, true},
+		{profile.DebitColumn, &idx.debit, true},
+		{profile.CreditColumn, &idx.credit, false},
+		{profile.PayeeColumn, &idx.payee, false},
+		{profile.DescriptionColumn, &idx.description, false},
+		{profile.ReferenceColumn, &idx.reference, false},
+		{profile.CurrencyColumn, &idx.currency, false},
+	} {
+		*c.dst = -1
+		if c.name == "" {
+			continue
+		}
+		i, ok := columns[strings.ToLower(c.name)]
+		if !ok && c.required {
+			return nil, fmt.Errorf("column %q not found in CSV header", c.name)
+		}
+		if ok {
+			*c.dst = i
+		}
+	}
+
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var lines []StatementLine
+	for row := profile.SkipRows + 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		date, err := time.Parse(profile.DateFormat, field(record, idx.date))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid date %q", row, field(record, idx.date))
+		}
+
+		var amount float64
+		if idx.amount >= 0 {
+			if amount, err = parseAmount(field(record, idx.amount), profile.DecimalComma); err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+			if profile.DebitsPositive {
+				amount = -amount
+			}
+		} else {
+			debit, err := parseOptionalAmount(field(record, idx.debit), profile.DecimalComma)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+			credit, err := parseOptionalAmount(field(record, idx.credit), profile.DecimalComma)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+			amount = abs(credit) - abs(debit)
+		}
+
+		currency := strings.ToUpper(field(record, idx.currency))
+		if currency == "" {
+			currency = profile.Currency
+		}
+
+		lines = append(lines, StatementLine{
+			Date:        date,
+			Amount:      amount,
+			Currency:    currency,
+			Payee:       field(record, idx.payee),
+			Description: field(record, idx.description),
+			Reference:   field(record, idx.reference),
+		})
+	}
+
+	return lines, nil
+}
+
+var ofxFieldPattern = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+
+// ParseOFXStatement parses OFX 1.x (SGML) and 2.x (XML) statements, including QFX
+func ParseOFXStatement(data []byte) ([]StatementLine, error) {
+	text := string(data)
+	header := ofxFields(text)
+	currency := strings.ToUpper(header["CURDEF"])
+	account := header["ACCTID"]
+
+	if !strings.Contains(strings.ToUpper(text), "<OFX>") {
+		return nil, fmt.Errorf("not an OFX statement")
+	}
+
+	blocks := ofxTransactions(text)
+	lines := make([]StatementLine, 0, len(blocks))
+	for i, block := range blocks {
+		fields := ofxFields(block)
+
+		date, err := parseOFXDate(fields["DTPOSTED"])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		amount, err := parseAmount(fields["TRNAMT"], false)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+
+		reference := fields["FITID"]
+		if reference == "" {
+			reference = fields["CHECKNUM"]
+		}
+		lineCurrency := currency
+		if c := fields["CURSYM"]; c != "" {
+			lineCurrency = strings.ToUpper(c)
+		}
+
+		lines = append(lines, StatementLine{
+			Date:        date,
+			Amount:      amount,
+			Currency:    lineCurrency,
+			Payee:       fields["NAME"],
+			Description: fields["MEMO"],
+			Reference:   reference,
+			Account:     account,
+		})
+	}
+
+	return lines, nil
+}
+
+// ofxTransactions splits out the body of each STMTTRN aggregate. SGML files
+// may omit closing tags, so a block also ends at the next STMTTRN.
+func ofxTransactions(text string) []string {
+	upper := strings.ToUpper(text)
+	var blocks []string
+
+	for {
+		start := strings.Index(upper, "<STMTTRN>")
+		if start < 0 {
+			return blocks
+		}
+		upper = upper[start+len("<STMTTRN>"):]
+		text = text[start+len("<STMTTRN>"):]
+
+		end := len(upper)
+		for _, terminator := range []string{"</STMTTRN>", "<STMTTRN>", "</BANKTRANLIST>"} {
+			if i := strings.Index(upper, terminator); i >= 0 && i < end {
+				end = i
+			}
+		}
+		blocks = append(blocks, text[:end])
+	}
+}
+
+// ofxFields returns the first value of each leaf element, keyed by upper-case tag
+func ofxFields(text string) map[string]string {
+	fields := make(map[string]string)
+	for _, m := range ofxFieldPattern.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToUpper(m[1])
+		value := strings.TrimSpace(m[2])
+		if _, seen := fields[tag]; !seen && value != "" {
+			fields[tag] = unescapeOFX(value)
+		}
+	}
+	return fields
+}
+
+func unescapeOFX(value string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'").Replace(value)
+}
+
+// parseOFXDate handles YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]]
+func parseOFXDate(value string) (time.Time, error) {
+	if i := strings.IndexAny(value, "[."); i >= 0 {
+		value = value[:i]
+	}
+	switch len(value) {
+	case 8:
+		return time.Parse("20060102", value)
+	case 12:
+		return time.Parse("200601021504", value)
+	case 14:
+		return time.Parse("20060102150405", value)
+	default:
+		return time.Time{}, fmt.Errorf("invalid OFX date %q", value)
+	}
+}
+
+// camtDocument is the subset of ISO 20022 camt.053 needed for expenses.
+// Element names are matched without namespaces so all schema versions parse.
+type camtDocument struct {
+	Statements []struct {
+		Account struct {
+			IBAN     string `xml:"Id>IBAN"`
+			Other    string `xml:"Id>Othr>Id"`
+			Currency string `xml:"Ccy"`
+		} `xml:"Acct"`
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	Indicator string `xml:"CdtDbtInd"`
+	// Sts is plain text up to camt.053.001.07 and wraps a Cd element afterwards
+	Status struct {
+		Text string `xml:",chardata"`
+		Code string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate      camtDate `xml:"BookgDt"`
+	ValueDate        camtDate `xml:"ValDt"`
+	ServicerRef      string   `xml:"AcctSvcrRef"`
+	EntryRef         string   `xml:"NtryRef"`
+	AdditionalInfo   string   `xml:"AddtlNtryInf"`
+	TransactionInfos []struct {
+		EndToEndID  string   `xml:"Refs>EndToEndId"`
+		ServicerRef string   `xml:"Refs>AcctSvcrRef"`
+		Creditor    string   `xml:"RltdPties>Cdtr>Nm"`
+		CreditorPty string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+		Unstructure []string `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) parse() (time.Time, bool) {
+	if d.Date != "" {
+		t, err := time.Parse("2006-01-02", d.Date)
+		return t, err == nil
+	}
+	if d.DateTime != "" {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04:05.999999999"} {
+			if t, err := time.Parse(layout, d.DateTime); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseCAMT053Statement parses an ISO 20022 camt.053 bank-to-customer statement
+func ParseCAMT053Statement(data []byte) ([]StatementLine, error) {
+	var doc camtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid camt.053 document: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, fmt.Errorf("camt.053 document contains no statements")
+	}
+
+	var lines []StatementLine
+	for _, stmt := range doc.Statements {
+		account := stmt.Account.IBAN
+		if account == "" {
+			account = stmt.Account.Other
+		}
+
+		for i, entry := range stmt.Entries {
+			// Pending and informational entries are not final bookings
+			status := entry.Status.Code
+			if status == "" {
+				status = strings.TrimSpace(entry.Status.Text)
+			}
+			if status != "" && status != "BOOK" {
+				continue
+			}
+
+			amount, err := strconv.ParseFloat(strings.TrimSpace(entry.Amount.Value), 64)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: invalid amount %q", i+1, entry.Amount.Value)
+			}
+			if entry.Indicator == "DBIT" {
+				amount = -amount
+			}
+
+			date, ok := entry.BookingDate.parse()
+			if !ok {
+				if date, ok = entry.ValueDate.parse(); !ok {
+					return nil, fmt.Errorf("entry %d: missing booking date", i+1)
+				}
+			}
+
+			currency := entry.Amount.Currency
+			if currency == "" {
+				currency = stmt.Account.Currency
+			}
+
+			line := StatementLine{
+				Date:        date,
+				Amount:      amount,
+				Currency:    strings.ToUpper(currency),
+				Description: entry.AdditionalInfo,
+				Reference:   entry.ServicerRef,
+				Account:     account,
+			}
+			if line.Reference == "" {
+				line.Reference = entry.EntryRef
+			}
+
+			if len(entry.TransactionInfos) > 0 {
+				tx := entry.TransactionInfos[0]
+				line.Payee = tx.Creditor
+				if line.Payee == "" {
+					line.Payee = tx.CreditorPty
+				}
+				if len(tx.Unstructure) > 0 {
+					line.Description = strings.Join(tx.Unstructure, " ")
+				}
+				if line.Reference == "" {
+					line.Reference = tx.ServicerRef
+				}
+				if line.Reference == "" && tx.EndToEndID != "NOTPROVIDED" {
+					line.Reference = tx.EndToEndID
+				}
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, nil
+}
+
+// parseAmount accepts values such as "-1,234.56", "(12.00)", "12.00-" and "€ 1.234,56"
+func parseAmount(value string, decimalComma bool) (float64, error) {
+	original := value
+	value = strings.TrimSpace(value)
+	negative := false
+
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+	if strings.HasSuffix(value, "-") {
+		negative = true
+		value = strings.TrimSuffix(value, "-")
+	}
+
+	value = strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' || r == '-' || r == '+' {
+			return r
+		}
+		return -1
+	}, value)
+
+	if decimalComma {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.ReplaceAll(value, ",", ".")
+	} else {
+		value = strings.ReplaceAll(value, ",", "")
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", original)
+	}
+	if negative {
+		amount = -abs(amount)
+	}
+	return amount, nil
+}
+
+func parseOptionalAmount(value string, decimalComma bool) (float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	return parseAmount(value, decimalComma)
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,161 @@
+package invoice
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+// AgingInvoice is an outstanding invoice on the aging report
+type AgingInvoice struct {
+	InvoiceID   string    `json:"invoice_id"`
+	Number      string    `json:"number"`
+	IssueDate   time.Time `json:"issue_date"`
+	DueDate     time.Time `json:"due_date"`
+	Amount      float64   `json:"amount"`
+	DaysPastDue int       `json:"days_past_due"`
+	Bucket      string    `json:"bucket"`
+}
+
+// ClientAging is one client's outstanding balance split into aging buckets
+type ClientAging struct {
+	ClientID   string         `json:"client_id"`
+	ClientName string         `json:"client_name"`
+	Buckets    AgingBuckets   `json:"buckets"`
+	Invoices   []AgingInvoice `json:"invoices"`
+}
+
+// AgingReport is the accounts receivable aging of all clients, largest
+// balances first
+type AgingReport struct {
+	AsOf        time.Time     `json:"as_of"`
+	Clients     []ClientAging `json:"clients"`
+	Totals      AgingBuckets  `json:"totals"`
+	GeneratedAt time.Time     `json:"generated_at"`
+}
+
+// Aging bucket names
+const (
+	BucketCurrent    = "current"
+	BucketDays1To30  = "1-30"
+	BucketDays31To60 = "31-60"
+	BucketDays61To90 = "61-90"
+	BucketOver90     = "90+"
+)
+
+// GetAgingReport groups the workspace's unpaid issued invoices by client into aging
+// buckets as of the given time. Credit notes reduce the client's current
+// bucket.
+func (s *Service) GetAgingReport(actor types.Actor, asOf time.Time) (*AgingReport, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	invoices, err := s.repo.GetByWorkspaceID(actor.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+
+	byClient := map[string][]*types.Invoice{}
+	for _, invoice := range invoices {
+		byClient[invoice.ClientID] = append(byClient[invoice.ClientID], invoice)
+	}
+
+	report := &AgingReport{
+		AsOf:        asOf,
+		Clients:     []ClientAging{},
+		GeneratedAt: time.Now(),
+	}
+
+	for clientID, clientInvoices := range byClient {
+		aging := ClientAging{
+			ClientID:   clientID,
+			ClientName: clientID,
+			Buckets:    agingAt(clientInvoices, asOf),
+			Invoices:   []AgingInvoice{},
+		}
+		if aging.Buckets.Total == 0 {
+			continue
+		}
+		if client, err := s.clients.GetClient(actor, clientID); err == nil {
+			aging.ClientName = client.Name
+			if client.Company != "" {
+				aging.ClientName = client.Company
+			}
+		}
+
+		for _, invoice := range clientInvoices {
+			if !isOutstanding(invoice, asOf) {
+				continue
+			}
+			issued, due := invoiceDates(invoice)
+			days := daysPastDue(due, asOf)
+			bucket := BucketCurrent
+			if invoice.TotalAmount > 0 {
+				bucket = bucketName(days)
+			}
+			aging.Invoices = append(aging.Invoices, AgingInvoice{
+				InvoiceID:   invoice.ID,
+				Number:      invoice.Number,
+				IssueDate:   issued,
+				DueDate:     due,
+				Amount:      round2(invoice.TotalAmount),
+				DaysPastDue: max(days, 0),
+				Bucket:      bucket,
+			})
+		}
+		slices.SortFunc(aging.Invoices, func(a, b AgingInvoice) int {
+			return b.DaysPastDue - a.DaysPastDue
+		})
+
+		report.Totals.Current += aging.Buckets.Current
+		report.Totals.Days1To30 += aging.Buckets.Days1To30
+		report.Totals.Days31To60 += aging.Buckets.Days31To60
+		report.Totals.Days61To90 += aging.Buckets.Days61To90
+		report.Totals.Over90 += aging.Buckets.Over90
+		report.Clients = append(report.Clients, aging)
+	}
+
+	slices.SortFunc(report.Clients, func(a, b ClientAging) int {
+		if a.Buckets.Total != b.Buckets.Total {
+			if a.Buckets.Total > b.Buckets.Total {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.ClientName, b.ClientName)
+	})
+
+	report.Totals.round()
+	return report, nil
+}
+
+// isOutstanding reports whether an invoice was issued and still unpaid at the
+// given time
+func isOutstanding(invoice *types.Invoice, at time.Time) bool {
+	if !isIssued(invoice) {
+		return false
+	}
+	issued, _ := invoiceDates(invoice)
+	return issued.Before(at) && (invoice.PaidAt == nil || !invoice.PaidAt.Before(at))
+}
+
+func daysPastDue(due, at time.Time) int {
+	return int(at.Sub(due).Hours() / 24)
+}
+
+func bucketName(daysPastDue int) string {
+	switch {
+	case daysPastDue <= 0:
+		return BucketCurrent
+	case daysPastDue <= 30:
+		return BucketDays1To30
+	case daysPastDue <= 60:
+		return BucketDays31To60
+	case daysPastDue <= 90:
+		return BucketDays61To90
+	}
+	return BucketOver90
+}
@@ -0,0 +1,205 @@
+package invoice
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+// billingResult is the services part of an invoice under a project's billing
+// model
+type billingResult struct {
+	items    []types.InvoiceItem
+	writeOff *types.WriteOff
+}
+
+// applyBillingModel turns the hourly lines of completed time entries into the
+// lines the project's billing model charges. Milestone fees are priced on
+// their own and don't count toward a fixed fee or cap.
+func (s *Service) applyBillingModel(project *types.Project, hourly []types.InvoiceItem, hourlyRate float64, now time.Time) (*billingResult, error) {
+	billing := project.Billing
+	if billing == nil || billing.Model == types.BillingModelHourly {
+		return &billingResult{items: hourly}, nil
+	}
+
+	previous, err := s.projectInvoices(project)
+	if err != nil {
+		return nil, err
+	}
+
+	switch billing.Model {
+	case types.BillingModelFixedFee:
+		return fixedFeeItems(project, hourly, previous), nil
+	case types.BillingModelCappedHourly:
+		return cappedItems(billing.Cap, hourly, previous), nil
+	case types.BillingModelRetainer:
+		return retainerItems(billing, hourly, hourlyRate, previous, now), nil
+	}
+	return nil, fmt.Errorf("unknown billing model %q", billing.Model)
+}
+
+// fixedFeeItems bills the share of the fee earned by percent complete that
+// was not invoiced before. Tracked hours are listed at no charge.
+func fixedFeeItems(project *types.Project, hourly []types.InvoiceItem, previous []*types.Invoice) *billingResult {
+	billing := project.Billing
+	earned := round2(billing.FixedFee * billing.PercentComplete / 100)
+	due := round2(earned - servicesBilled(previous))
+
+	result := &billingResult{}
+	if due > 0 {
+		result.items = append(result.items, types.InvoiceItem{
+			Section:     types.InvoiceSectionServices,
+			Description: fmt.Sprintf("%s: %.0f%% complete of %.2f fixed fee", project.Name, billing.PercentComplete, billing.FixedFee),
+			Quantity:    1,
+			Rate:        due,
+			Amount:      due,
+		})
+	}
+	for _, item := range hourly {
+		item.Rate, item.Amount = 0, 0
+		item.Description += " (fixed fee)"
+		result.items = append(result.items, item)
+	}
+	return result
+}
+
+// cappedItems bills hours until the not-to-exceed cap is reached and writes
+// off the rest. Written-off hours stay on the invoice at no charge so their
+// entries are billed and not written off again.
+func cappedItems(limit float64, hourly []types.InvoiceItem, previous []*types.Invoice) *billingResult {
+	remaining := round2(limit - servicesBilled(previous))
+	result := &billingResult{}
+	writeOff := &types.WriteOff{Reason: fmt.Sprintf("not-to-exceed cap of %.2f reached", limit)}
+
+	for _, item := range hourly {
+		if item.Amount <= remaining {
+			remaining = round2(remaining - item.Amount)
+			result.items = append(result.items, item)
+			continue
+		}
+
+		billable := 0.0
+		if remaining > 0 && item.Rate > 0 {
+			billable = remaining / item.Rate
+		}
+		writeOff.Hours += item.Quantity - billable
+		writeOff.Amount += item.Amount - math.Max(remaining, 0)
+
+		if billable > 0 {
+			item.Quantity = billable
+			item.Amount = remaining
+			item.Description += " (capped)"
+		} else {
+			item.Rate, item.Amount = 0, 0
+			item.Description += " (written off)"
+		}
+		result.items = append(result.items, item)
+		remaining = 0
+	}
+
+	if writeOff.Hours > 0 {
+		writeOff.Hours = round2(writeOff.Hours)
+		writeOff.Amount = round2(writeOff.Amount)
+		result.writeOff = writeOff
+	}
+	return result
+}
+
+// retainerItems charges the monthly fee once per month and bills hours beyond
+// the month's included hours at the hourly rate
+func retainerItems(billing *types.ProjectBilling, hourly []types.InvoiceItem, hourlyRate float64, previous []*types.Invoice, now time.Time) *billingResult {
+	period := now.UTC().Format("2006-01")
+	result := &billingResult{}
+
+	feeCharged, usedHours := false, 0.0
+	for _, invoice := range previous {
+		for _, item := range invoice.Items {
+			if item.Period != period {
+				continue
+			}
+			if item.Section == types.InvoiceSectionRetainer {
+				feeCharged = true
+			} else {
+				usedHours += item.Quantity
+			}
+		}
+	}
+
+	if !feeCharged {
+		month, _ := time.Parse("2006-01", period)
+		result.items = append(result.items, types.InvoiceItem{
+			Section:     types.InvoiceSectionRetainer,
+			Description: fmt.Sprintf("Retainer %s (%.1f hours included)", month.Format("January 2006"), billing.RetainerHours),
+			Quantity:    1,
+			Rate:        billing.RetainerFee,
+			Amount:      billing.RetainerFee,
+			Period:      period,
+		})
+	}
+
+	included := math.Max(billing.RetainerHours-usedHours, 0)
+	for _, item := range hourly {
+		item.Period = period
+		if item.Quantity <= included {
+			included -= item.Quantity
+			item.Rate, item.Amount = 0, 0
+			item.Description += " (included)"
+			result.items = append(result.items, item)
+			continue
+		}
+
+		if included > 0 {
+			covered := item
+			covered.Quantity = included
+			covered.Rate, covered.Amount = 0, 0
+			covered.Description += " (included)"
+			result.items = append(result.items, covered)
+		}
+
+		item.Quantity -= included
+		item.Rate = hourlyRate
+		item.Amount = item.Quantity * hourlyRate
+		item.Description += " (overage)"
+		result.items = append(result.items, item)
+		included = 0
+	}
+	return result
+}
+
+// projectInvoices returns the project's existing invoices, leaving out
+// cancelled and voided ones whose hours were released
+func (s *Service) projectInvoices(project *types.Project) ([]*types.Invoice, error) {
+	invoices, err := s.repo.GetByClientID(types.WorkspaceOf(project.WorkspaceID, project.UserID), project.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+
+	var matched []*types.Invoice
+	for _, invoice := range invoices {
+		released := invoice.Status == types.InvoiceStatusCancelled || invoice.Status == types.InvoiceStatusVoid
+		if invoice.ProjectID == project.ID && !released {
+			matched = append(matched, invoice)
+		}
+	}
+	return matched, nil
+}
+
+// servicesBilled sums the services lines of invoices, leaving out milestone
+// fees
+func servicesBilled(invoices []*types.Invoice) float64 {
+	total := 0.0
+	for _, invoice := range invoices {
+		for _, item := range invoice.Items {
+			if item.Section == types.InvoiceSectionServices && item.MilestoneID == "" {
+				total += item.Amount
+			}
+		}
+	}
+	return round2(total)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,95 @@
+package invoice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+// billAs puts the project on a billing model
+func (env *testEnv) billAs(t *testing.T, billing *types.ProjectBilling) {
+	t.Helper()
+	project, err := env.clients.SetProjectBilling(env.actor, env.project.ID, billing)
+	if err != nil {
+		t.Fatalf("SetProjectBilling: %v", err)
+	}
+	env.project = project
+}
+
+func TestCappedHourlyWritesOffHoursOnce(t *testing.T) {
+	env := newTestEnv(t)
+	env.billAs(t, &types.ProjectBilling{Model: types.BillingModelCappedHourly, Cap: 200})
+	// Whichever entry comes first, the other is written off entirely
+	env.trackedEntry(t, env.actor.WorkspaceID, env.project.ID, 2)
+	env.trackedEntry(t, env.actor.WorkspaceID, env.project.ID, 3)
+
+	invoice, err := env.generate()
+	if err != nil {
+		t.Fatalf("GenerateFromTimeEntries: %v", err)
+	}
+	if invoice.TotalAmount != 200 {
+		t.Errorf("total = %v, want 200", invoice.TotalAmount)
+	}
+	if invoice.WriteOff == nil || invoice.WriteOff.Hours != 3 || invoice.WriteOff.Amount != 300 {
+		t.Fatalf("write-off = %+v, want 3 hours / 300", invoice.WriteOff)
+	}
+
+	entries, err := env.times.GetProjectTimeEntries(env.actor, env.project.ID, time.Time{}, time.Now())
+	if err != nil {
+		t.Fatalf("GetProjectTimeEntries: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.InvoiceID != invoice.ID {
+			t.Errorf("%.0f hour entry is on invoice %q, want %s", entry.EndTime.Sub(entry.StartTime).Hours(), entry.InvoiceID, invoice.ID)
+		}
+	}
+
+	if _, err := env.generate(); err == nil || !strings.Contains(err.Error(), "nothing to bill") {
+		t.Fatalf("second run: err = %v, want nothing to bill", err)
+	}
+
+	invoices, err := env.invoices.GetInvoices(env.actor)
+	if err != nil {
+		t.Fatalf("GetInvoices: %v", err)
+	}
+	writeOffs := 0
+	for _, invoice := range invoices {
+		if invoice.WriteOff != nil {
+			writeOffs++
+		}
+	}
+	if len(invoices) != 1 || writeOffs != 1 {
+		t.Errorf("got %d invoices with %d write-offs, want 1 and 1", len(invoices), writeOffs)
+	}
+}
+
+func TestRetainerCountsIncludedHoursOnce(t *testing.T) {
+	env := newTestEnv(t)
+	env.billAs(t, &types.ProjectBilling{Model: types.BillingModelRetainer, RetainerFee: 1000, RetainerHours: 10})
+	env.trackedEntry(t, env.actor.WorkspaceID, env.project.ID, 8)
+
+	invoice, err := env.generate()
+	if err != nil {
+		t.Fatalf("GenerateFromTimeEntries: %v", err)
+	}
+	// The fee, with all eight hours included
+	if invoice.TotalAmount != 1000 {
+		t.Errorf("total = %v, want 1000", invoice.TotalAmount)
+	}
+
+	if _, err := env.generate(); err == nil || !strings.Contains(err.Error(), "nothing to bill") {
+		t.Fatalf("second run: err = %v, want nothing to bill", err)
+	}
+
+	// Two of the four new hours are still included this month
+	env.trackedEntry(t, env.actor.WorkspaceID, env.project.ID, 4)
+	overage, err := env.generate()
+	if err != nil {
+		t.Fatalf("GenerateFromTimeEntries: %v", err)
+	}
+	if overage.TotalAmount != 200 {
+		t.Errorf("overage total = %v, want 200", overage.TotalAmount)
+	}
+}
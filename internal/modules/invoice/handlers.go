@@ -2,9 +2,11 @@ package invoice
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
+	"datastar-go/internal/modules/auth"
 	"datastar-go/internal/shared/types"
 )
 
@@ -17,8 +19,13 @@ func NewHandlers(service *Service) *Handlers {
 }
 
 func (h *Handlers) handleCreateInvoice(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
-		UserID    string              `json:"user_id"`
 		ClientID  string              `json:"client_id"`
 		ProjectID string              `json:"project_id"`
 		Items     []types.InvoiceItem `json:"items"`
@@ -29,14 +36,14 @@ func (h *Handlers) handleCreateInvoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.UserID == "" || req.ClientID == "" || len(req.Items) == 0 {
+	if req.ClientID == "" || len(req.Items) == 0 {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
 
-	invoice, err := h.service.CreateInvoice(req.UserID, req.ClientID, req.ProjectID, req.Items)
+	invoice, err := h.service.CreateInvoice(actor, req.ClientID, req.ProjectID, req.Items)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), invoiceErrorStatus(err))
 		return
 	}
 
@@ -50,12 +57,21 @@ func (h *Handlers) handleCreateInvoice(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) handleGenerateFromTimeEntries(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Time entries are loaded from the project for the optional
+	// YYYY-MM-DD period, never taken from the request
 	var req struct {
-		UserID      string             `json:"user_id"`
-		ClientID    string             `json:"client_id"`
-		ProjectID   string             `json:"project_id"`
-		HourlyRate  float64            `json:"hourly_rate"`
-		TimeEntries []*types.TimeEntry `json:"time_entries"`
+		ClientID     string  `json:"client_id"`
+		ProjectID    string  `json:"project_id"`
+		HourlyRate   float64 `json:"hourly_rate"`
+		From         string  `json:"from"`
+		To           string  `json:"to"`
+		SkipExpenses bool    `json:"skip_expenses"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -63,14 +79,69 @@ func (h *Handlers) handleGenerateFromTimeEntries(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if req.UserID == "" || req.ClientID == "" || req.ProjectID == "" || req.HourlyRate <= 0 || len(req.TimeEntries) == 0 {
+	if req.ClientID == "" || req.ProjectID == "" || req.HourlyRate < 0 {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
 
-	invoice, err := h.service.GenerateFromTimeEntries(req.UserID, req.ClientID, req.ProjectID, req.HourlyRate, req.TimeEntries)
+	var from time.Time
+	to := time.Now()
+	if req.From != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.From, time.Local)
+		if err != nil {
+			http.Error(w, "Invalid from date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = date
+	}
+	if req.To != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.To, time.Local)
+		if err != nil {
+			http.Error(w, "Invalid to date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = date.AddDate(0, 0, 1) // include the whole day
+	}
+
+	invoice, err := h.service.GenerateFromTimeEntries(actor, req.ClientID, req.ProjectID, req.HourlyRate, from, to, req.SkipExpenses)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), invoiceErrorStatus(err))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    invoice,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGenerateFromMilestone(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		MilestoneID string `json:"milestone_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.MilestoneID == "" {
+		http.Error(w, "milestone_id required", http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := h.service.GenerateFromMilestone(actor, req.MilestoneID)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -84,15 +155,15 @@ func (h *Handlers) handleGenerateFromTimeEntries(w http.ResponseWriter, r *http.
 }
 
 func (h *Handlers) handleGetInvoices(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "user_id required", http.StatusBadRequest)
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	invoices, err := h.service.GetInvoices(userID)
+	invoices, err := h.service.GetInvoices(actor)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -106,15 +177,21 @@ func (h *Handlers) handleGetInvoices(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) handleGetClientInvoices(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	clientID := r.URL.Query().Get("client_id")
 	if clientID == "" {
 		http.Error(w, "client_id required", http.StatusBadRequest)
 		return
 	}
 
-	invoices, err := h.service.GetInvoicesByClient(clientID)
+	invoices, err := h.service.GetInvoicesByClient(actor, clientID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -128,6 +205,12 @@ func (h *Handlers) handleGetClientInvoices(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *Handlers) handleUpdateStatus(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
 		InvoiceID string `json:"invoice_id"`
 		Status    string `json:"status"`
@@ -143,9 +226,9 @@ func (h *Handlers) handleUpdateStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	invoice, err := h.service.UpdateInvoiceStatus(req.InvoiceID, req.Status)
+	invoice, err := h.service.UpdateInvoiceStatus(actor, req.InvoiceID, req.Status)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), invoiceErrorStatus(err))
 		return
 	}
 
@@ -158,16 +241,139 @@ func (h *Handlers) handleUpdateStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *Handlers) handleGetStatement(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	clientID := query.Get("client_id")
+	if clientID == "" {
+		http.Error(w, "client_id required", http.StatusBadRequest)
+		return
+	}
+
+	// Default to the year to date
+	to := time.Now()
+	if value := query.Get("to"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			http.Error(w, "Invalid to date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = date
+	}
+	from := time.Date(to.Year(), 1, 1, 0, 0, 0, 0, time.Local)
+	if value := query.Get("from"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			http.Error(w, "Invalid from date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = date
+	}
+
+	statement, err := h.service.GenerateStatement(actor, clientID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	if query.Get("format") == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := WriteStatementHTML(w, statement); err != nil {
+			http.Error(w, "Failed to render statement", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    statement,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetAgingReport(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	asOf := time.Now()
+	if value := r.URL.Query().Get("as_of"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			http.Error(w, "Invalid as_of date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		asOf = date.AddDate(0, 0, 1) // through the end of that day
+	}
+
+	report, err := h.service.GetAgingReport(actor, asOf)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    report,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) handleGetRecipients(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	invoiceID := r.URL.Query().Get("invoice_id")
+	if invoiceID == "" {
+		http.Error(w, "invoice_id required", http.StatusBadRequest)
+		return
+	}
+
+	recipients, err := h.service.GetRecipients(actor, invoiceID)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusNotFound))
+		return
+	}
+
+	response := types.APIResponse{
+		Success: true,
+		Data:    recipients,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func (h *Handlers) handleDeleteInvoice(w http.ResponseWriter, r *http.Request) {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	invoiceID := r.URL.Query().Get("invoice_id")
 	if invoiceID == "" {
 		http.Error(w, "invoice_id required", http.StatusBadRequest)
 		return
 	}
 
-	err := h.service.DeleteInvoice(invoiceID)
+	err := h.service.DeleteInvoice(actor, invoiceID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), invoiceErrorStatus(err))
 		return
 	}
 
@@ -180,6 +386,19 @@ func (h *Handlers) handleDeleteInvoice(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func invoiceErrorStatus(err error) int {
+	if errors.Is(err, ErrInvoiceNotFound) || errors.Is(err, ErrClientNotFound) || errors.Is(err, ErrProjectNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, ErrInvalidTransition) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, ErrCurrencyMismatch) {
+		return http.StatusBadRequest
+	}
+	return auth.ErrorStatus(err, http.StatusInternalServerError)
+}
+
 func (h *Handlers) handleHealth(w http.ResponseWriter, r *http.Request) {
 	response := map[string]any{
 		"status":    "healthy",
@@ -0,0 +1,67 @@
+package invoice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"datastar-go/internal/modules/auth"
+	"datastar-go/internal/shared/types"
+)
+
+func serveAs(h http.HandlerFunc, actor types.Actor, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), auth.ActorKey, actor))
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestHandlersRejectCrossTenantAccess(t *testing.T) {
+	env := newTestEnv(t)
+	h := NewHandlers(env.invoices)
+	invoice, err := env.invoices.CreateInvoice(env.actor, env.client.ID, env.project.ID, []types.InvoiceItem{
+		{Description: "Work", Quantity: 1, Rate: 100, Amount: 100},
+	})
+	if err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+
+	stranger := types.PersonalActor("user-2")
+	accountant := types.Actor{UserID: "user-3", WorkspaceID: env.actor.WorkspaceID, Role: types.RoleAccountant}
+	status := `{"invoice_id":"` + invoice.ID + `","status":"sent"}`
+	generate := `{"client_id":"` + env.client.ID + `","project_id":"` + env.project.ID + `","hourly_rate":100}`
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		actor   types.Actor
+		method  string
+		target  string
+		body    string
+		want    int
+	}{
+		{"status of another workspace's invoice", h.handleUpdateStatus, stranger, http.MethodPut, "/api/invoice/status", status, http.StatusNotFound},
+		{"delete another workspace's invoice", h.handleDeleteInvoice, stranger, http.MethodDelete, "/api/invoice/delete?invoice_id=" + invoice.ID, "", http.StatusNotFound},
+		{"recipients of another workspace's invoice", h.handleGetRecipients, stranger, http.MethodGet, "/api/invoice/recipients?invoice_id=" + invoice.ID, "", http.StatusNotFound},
+		{"generate for another workspace's project", h.handleGenerateFromTimeEntries, stranger, http.MethodPost, "/api/invoice/generate", generate, http.StatusNotFound},
+		{"invoice another workspace's client", h.handleCreateInvoice, stranger, http.MethodPost, "/api/invoice/create", `{"client_id":"` + env.client.ID + `","items":[{"description":"x","amount":1}]}`, http.StatusNotFound},
+		{"accountant updates status", h.handleUpdateStatus, accountant, http.MethodPut, "/api/invoice/status", status, http.StatusForbidden},
+		{"accountant generates", h.handleGenerateFromTimeEntries, accountant, http.MethodPost, "/api/invoice/generate", generate, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serveAs(tc.handler, tc.actor, tc.method, tc.target, tc.body)
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+
+	w := serveAs(h.handleGetInvoices, stranger, http.MethodGet, "/api/invoice/list", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), invoice.ID) {
+		t.Errorf("another workspace's list = %d %s", w.Code, w.Body.String())
+	}
+}
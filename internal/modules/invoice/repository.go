@@ -26,7 +26,9 @@ func (r *Repository) Create(invoice *types.Invoice) error {
 	})
 }
 
-func (r *Repository) GetByID(id string) (*types.Invoice, error) {
+// GetByID returns a workspace's invoice. Another workspace's invoice is
+// reported as badger.ErrKeyNotFound.
+func (r *Repository) GetByID(workspaceID, id string) (*types.Invoice, error) {
 	var invoice types.Invoice
 	err := r.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get([]byte("invoice:" + id))
@@ -37,10 +39,13 @@ func (r *Repository) GetByID(id string) (*types.Invoice, error) {
 			return json.Unmarshal(val, &invoice)
 		})
 	})
+	if err == nil && !types.InWorkspace(invoice.WorkspaceID, invoice.UserID, workspaceID) {
+		return nil, badger.ErrKeyNotFound
+	}
 	return &invoice, err
 }
 
-func (r *Repository) GetByUserID(userID string) ([]*types.Invoice, error) {
+func (r *Repository) GetByWorkspaceID(workspaceID string) ([]*types.Invoice, error) {
 	var invoices []*types.Invoice
 	err := r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
@@ -55,7 +60,7 @@ func (r *Repository) GetByUserID(userID string) ([]*types.Invoice, error) {
 				if err := json.Unmarshal(val, &invoice); err != nil {
 					return err
 				}
-				if invoice.UserID == userID {
+				if types.InWorkspace(invoice.WorkspaceID, invoice.UserID, workspaceID) {
 					invoices = append(invoices, &invoice)
 				}
 				return nil
@@ -69,7 +74,7 @@ func (r *Repository) GetByUserID(userID string) ([]*types.Invoice, error) {
 	return invoices, err
 }
 
-func (r *Repository) GetByClientID(clientID string) ([]*types.Invoice, error) {
+func (r *Repository) GetByClientID(workspaceID, clientID string) ([]*types.Invoice, error) {
 	var invoices []*types.Invoice
 	err := r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
@@ -84,7 +89,7 @@ func (r *Repository) GetByClientID(clientID string) ([]*types.Invoice, error) {
 				if err := json.Unmarshal(val, &invoice); err != nil {
 					return err
 				}
-				if invoice.ClientID == clientID {
+				if types.InWorkspace(invoice.WorkspaceID, invoice.UserID, workspaceID) && invoice.ClientID == clientID {
 					invoices = append(invoices, &invoice)
 				}
 				return nil
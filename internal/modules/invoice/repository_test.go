@@ -0,0 +1,52 @@
+package invoice
+
+import (
+	"errors"
+	"testing"
+
+	"datastar-go/internal/shared/testutil"
+	"datastar-go/internal/shared/types"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+func TestRepositoryScopesInvoicesToWorkspace(t *testing.T) {
+	repo := NewRepository(testutil.MemoryDB(t))
+	shared := &types.Invoice{ID: "inv-1", UserID: "user-1", WorkspaceID: "ws-1", ClientID: "client-1"}
+	legacy := &types.Invoice{ID: "inv-2", UserID: "user-1", ClientID: "client-1"} // stored before workspaces
+	for _, invoice := range []*types.Invoice{shared, legacy} {
+		if err := repo.Create(invoice); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	if _, err := repo.GetByID("ws-1", shared.ID); err != nil {
+		t.Errorf("own workspace: %v", err)
+	}
+	if _, err := repo.GetByID("user-1", legacy.ID); err != nil {
+		t.Errorf("personal workspace: %v", err)
+	}
+	for _, lookup := range []struct{ workspaceID, id string }{
+		{"ws-2", shared.ID},
+		{"user-1", shared.ID},
+		{"ws-1", legacy.ID},
+		{"user-2", legacy.ID},
+	} {
+		if _, err := repo.GetByID(lookup.workspaceID, lookup.id); !errors.Is(err, badger.ErrKeyNotFound) {
+			t.Errorf("GetByID(%s, %s) err = %v, want not found", lookup.workspaceID, lookup.id, err)
+		}
+	}
+
+	invoices, err := repo.GetByWorkspaceID("ws-2")
+	if err != nil || len(invoices) != 0 {
+		t.Errorf("GetByWorkspaceID(ws-2) = %d invoices, %v; want none", len(invoices), err)
+	}
+	invoices, err = repo.GetByClientID("ws-2", "client-1")
+	if err != nil || len(invoices) != 0 {
+		t.Errorf("GetByClientID(ws-2) = %d invoices, %v; want none", len(invoices), err)
+	}
+	invoices, err = repo.GetByClientID("ws-1", "client-1")
+	if err != nil || len(invoices) != 1 {
+		t.Errorf("GetByClientID(ws-1) = %d invoices, %v; want 1", len(invoices), err)
+	}
+}
@@ -8,9 +8,13 @@ import (
 func (h *Handlers) SetupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/invoice/create", h.handleCreateInvoice)
 	mux.HandleFunc("POST /api/invoice/generate", h.handleGenerateFromTimeEntries)
+	mux.HandleFunc("POST /api/invoice/milestone", h.handleGenerateFromMilestone)
 	mux.HandleFunc("GET /api/invoice/list", h.handleGetInvoices)
 	mux.HandleFunc("GET /api/invoice/client", h.handleGetClientInvoices)
 	mux.HandleFunc("PUT /api/invoice/status", h.handleUpdateStatus)
+	mux.HandleFunc("GET /api/invoice/aging", h.handleGetAgingReport)
+	mux.HandleFunc("GET /api/invoice/statement", h.handleGetStatement)
+	mux.HandleFunc("GET /api/invoice/recipients", h.handleGetRecipients)
 	mux.HandleFunc("DELETE /api/invoice/delete", h.handleDeleteInvoice)
 	mux.HandleFunc("GET /api/invoice/health", h.handleHealth)
 
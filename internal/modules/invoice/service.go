@@ -1,8 +1,12 @@
 package invoice
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"slices"
+	"strings"
 	"time"
 
 	"datastar-go/internal/shared/types"
@@ -10,15 +14,72 @@ import (
 	"github.com/dgraph-io/badger/v4"
 )
 
+var (
+	ErrInvoiceNotFound   = errors.New("invoice not found")
+	ErrClientNotFound    = errors.New("client not found")
+	ErrProjectNotFound   = errors.New("project not found for client")
+	ErrInvalidTransition = errors.New("invalid invoice status transition")
+	ErrCurrencyMismatch  = errors.New("currency does not match the invoice")
+)
+
+// defaultCurrency bills clients and projects that have no currency set
+const defaultCurrency = "USD"
+
+// invoiceTransitions lists the statuses an invoice may move to from each
+// status. Paid, cancelled and void invoices are final.
+var invoiceTransitions = map[string][]string{
+	types.InvoiceStatusDraft:     {types.InvoiceStatusSent, types.InvoiceStatusPaid, types.InvoiceStatusCancelled},
+	types.InvoiceStatusSent:      {types.InvoiceStatusOverdue, types.InvoiceStatusPaid, types.InvoiceStatusVoid},
+	types.InvoiceStatusOverdue:   {types.InvoiceStatusPaid, types.InvoiceStatusVoid},
+	types.InvoiceStatusPaid:      {},
+	types.InvoiceStatusCancelled: {},
+	types.InvoiceStatusVoid:      {},
+}
+
 type Service struct {
 	eventBus types.EventBus
 	repo     *Repository
+	expenses ExpenseSource
+	clients  ClientSource
+	times    TimeSource
+}
+
+// ExpenseSource provides billable expenses and tracks which invoice bills them
+type ExpenseSource interface {
+	GetExpense(actor types.Actor, expenseID string) (*types.Expense, error)
+	GetBillableExpenses(actor types.Actor, projectID string) ([]*types.Expense, error)
+	MarkExpensesBilled(actor types.Actor, invoiceID string, expenseIDs []string) error
+	MarkExpensesUnbilled(actor types.Actor, invoiceID string, expenseIDs []string) error
+}
+
+// ClientSource looks up the workspace's client an invoice is addressed to, who
+// receives it and how the project is billed, and tracks which invoice bills a
+// project milestone
+type ClientSource interface {
+	GetClient(actor types.Actor, clientID string) (*types.Client, error)
+	GetProject(actor types.Actor, projectID string) (*types.Project, error)
+	GetBusinessProfile(actor types.Actor) (*types.BusinessProfile, error)
+	GetInvoiceRecipients(actor types.Actor, clientID string) (*types.InvoiceRecipients, error)
+	GetMilestone(actor types.Actor, milestoneID string) (*types.Milestone, error)
+	MarkMilestoneInvoiced(actor types.Actor, milestoneID, invoiceID string) error
+	MarkMilestoneUninvoiced(actor types.Actor, milestoneID, invoiceID string) error
+}
+
+// TimeSource provides the tracked time a project is billed for and tracks
+// which invoice bills it
+type TimeSource interface {
+	GetProjectTimeEntries(actor types.Actor, projectID string, from, to time.Time) ([]*types.TimeEntry, error)
+	MarkTimeEntriesBilled(actor types.Actor, invoiceID string, timeEntryIDs []string) error
+	ReleaseTimeEntries(actor types.Actor, invoiceID string, timeEntryIDs []string) error
 }
 
-func NewService(eventBus types.EventBus, db *badger.DB) *Service {
+func NewService(eventBus types.EventBus, db *badger.DB, expenses ExpenseSource, clients ClientSource, times TimeSource) *Service {
 	service := &Service{
 		eventBus: eventBus,
 		repo:     NewRepository(db),
+		expenses: expenses,
+		clients:  clients,
+		times:    times,
 	}
 
 	service.setupEventSubscriptions()
@@ -32,33 +93,128 @@ func (s *Service) setupEventSubscriptions() {
 	log.Println("Invoice service event subscriptions configured")
 }
 
-func (s *Service) CreateInvoice(userID, clientID, projectID string, items []types.InvoiceItem) (*types.Invoice, error) {
+func (s *Service) CreateInvoice(actor types.Actor, clientID, projectID string, items []types.InvoiceItem) (*types.Invoice, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	client, err := s.getClient(actor, clientID)
+	if err != nil {
+		return nil, err
+	}
+	var project *types.Project
+	if projectID != "" {
+		if project, err = s.clientProject(actor, clientID, projectID); err != nil {
+			return nil, err
+		}
+	}
+	currency := invoiceCurrency(client, project)
+
+	// Expense and milestone lines are priced from what is stored, not from
+	// the amounts the caller sent
 	var totalAmount float64
-	for _, item := range items {
+	var expenseIDs, milestoneIDs []string
+	for i := range items {
+		item := &items[i]
+		switch {
+		case item.ExpenseID != "" && item.MilestoneID != "":
+			return nil, fmt.Errorf("invoice item %q bills both an expense and a milestone", item.Description)
+		case item.ExpenseID != "":
+			expense, err := s.expenses.GetExpense(actor, item.ExpenseID)
+			if err != nil {
+				return nil, err
+			}
+			if projectID != "" && expense.ProjectID != projectID {
+				return nil, fmt.Errorf("expense %s is not on project %s", expense.ID, projectID)
+			}
+			if !sameCurrency(expense.Currency, currency) {
+				return nil, fmt.Errorf("%w: expense %s is in %s, the invoice in %s", ErrCurrencyMismatch, expense.ID, expense.Currency, currency)
+			}
+			priced := expenseItem(expense, client.ExpenseMarkup)
+			if item.Description != "" {
+				priced.Description = item.Description
+			}
+			*item = priced
+			expenseIDs = append(expenseIDs, item.ExpenseID)
+		case item.MilestoneID != "":
+			milestone, err := s.clients.GetMilestone(actor, item.MilestoneID)
+			if err != nil {
+				return nil, err
+			}
+			if milestone.ClientID != clientID {
+				return nil, fmt.Errorf("milestone %s is not for client %s", milestone.Name, clientID)
+			}
+			// The fee is in the currency of the milestone's project
+			feeCurrency := currency
+			if milestone.ProjectID != projectID {
+				milestoneProject, err := s.clientProject(actor, clientID, milestone.ProjectID)
+				if err != nil {
+					return nil, err
+				}
+				feeCurrency = invoiceCurrency(client, milestoneProject)
+			}
+			if feeCurrency != currency {
+				return nil, fmt.Errorf("%w: milestone %s is in %s, the invoice in %s", ErrCurrencyMismatch, milestone.Name, feeCurrency, currency)
+			}
+			item.Quantity = 1
+			item.Rate = milestone.Amount
+			item.Amount = milestone.Amount
+			milestoneIDs = append(milestoneIDs, item.MilestoneID)
+		}
+		if item.Section == "" {
+			item.Section = types.InvoiceSectionServices
+		}
 		totalAmount += item.Amount
 	}
 
 	invoice := &types.Invoice{
 		ID:          types.GenerateID(),
-		UserID:      userID,
+		UserID:      actor.UserID,
+		WorkspaceID: actor.WorkspaceID,
 		ClientID:    clientID,
 		ProjectID:   projectID,
 		Number:      s.generateInvoiceNumber(),
 		Items:       items,
 		TotalAmount: totalAmount,
-		Currency:    "USD",
-		Status:      "draft",
+		Currency:    currency,
+		Status:      types.InvoiceStatusDraft,
+		IssueDate:   time.Now(),
+		DueDate:     time.Now().Add(defaultPaymentTerms),
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	err := s.repo.Create(invoice)
+	// Claim the expenses and time entries first so two invoices can't bill
+	// the same expense or hours
+	if len(expenseIDs) > 0 {
+		if err := s.expenses.MarkExpensesBilled(actor, invoice.ID, expenseIDs); err != nil {
+			return nil, fmt.Errorf("failed to bill expenses: %w", err)
+		}
+	}
+
+	timeEntryIDs := invoiceTimeEntryIDs(invoice)
+	if len(timeEntryIDs) > 0 {
+		if err := s.times.MarkTimeEntriesBilled(actor, invoice.ID, timeEntryIDs); err != nil {
+			s.releaseBilled(actor, invoice, expenseIDs, nil, nil)
+			return nil, fmt.Errorf("failed to bill time entries: %w", err)
+		}
+	}
+
+	for i, milestoneID := range milestoneIDs {
+		if err := s.clients.MarkMilestoneInvoiced(actor, milestoneID, invoice.ID); err != nil {
+			s.releaseBilled(actor, invoice, expenseIDs, timeEntryIDs, milestoneIDs[:i])
+			return nil, fmt.Errorf("failed to bill milestone: %w", err)
+		}
+	}
+
+	err = s.repo.Create(invoice)
 	if err != nil {
+		s.releaseBilled(actor, invoice, expenseIDs, timeEntryIDs, milestoneIDs)
 		return nil, fmt.Errorf("failed to create invoice: %w", err)
 	}
 
 	event := types.NewEvent("invoice_created", "invoice_service", map[string]any{
 		"invoice_id":   invoice.ID,
+		"workspace_id": invoice.WorkspaceID,
 		"client_id":    invoice.ClientID,
 		"project_id":   invoice.ProjectID,
 		"total_amount": invoice.TotalAmount,
@@ -66,46 +222,126 @@ func (s *Service) CreateInvoice(userID, clientID, projectID string, items []type
 	})
 
 	s.eventBus.Publish("invoice.created", event)
-	log.Printf("📄 Invoice created: %s ($%.2f)", invoice.Number, invoice.TotalAmount)
+	log.Printf("📄 Invoice created: %s (%.2f %s)", invoice.Number, invoice.TotalAmount, invoice.Currency)
 
 	return invoice, nil
 }
 
-func (s *Service) GenerateFromTimeEntries(userID, clientID, projectID string, hourlyRate float64, timeEntries []*types.TimeEntry) (*types.Invoice, error) {
+// GenerateFromTimeEntries bills the project's completed, unbilled time entries
+// started within [from, to) under the project's billing model and, unless
+// skipExpenses is set, the project's unbilled billable expenses in their own
+// section. A zero hourlyRate bills at the project's rate.
+func (s *Service) GenerateFromTimeEntries(actor types.Actor, clientID, projectID string, hourlyRate float64, from, to time.Time, skipExpenses bool) (*types.Invoice, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	project, err := s.clientProject(actor, clientID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if hourlyRate <= 0 {
+		hourlyRate = project.HourlyRate
+	}
+
+	entries, err := s.times.GetProjectTimeEntries(actor, projectID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time entries: %w", err)
+	}
+	var timeEntries []*types.TimeEntry
+	for _, entry := range entries {
+		if !entry.IsBilled {
+			timeEntries = append(timeEntries, entry)
+		}
+	}
+	if len(timeEntries) > 0 && hourlyRate <= 0 {
+		return nil, fmt.Errorf("hourly rate is required: project %s has none", project.Name)
+	}
 
-	var items []types.InvoiceItem
+	var hourly []types.InvoiceItem
 	var totalHours float64
+	periodDate := time.Now()
+	var latest time.Time
 
 	for _, entry := range timeEntries {
 		if entry.EndTime != nil {
 			hours := entry.EndTime.Sub(entry.StartTime).Hours()
 			totalHours += hours
+			if entry.StartTime.After(latest) {
+				latest = entry.StartTime
+			}
 
-			items = append(items, types.InvoiceItem{
+			hourly = append(hourly, types.InvoiceItem{
+				Section:     types.InvoiceSectionServices,
 				Description: entry.Description,
 				Quantity:    hours,
 				Rate:        hourlyRate,
 				Amount:      hours * hourlyRate,
+				TimeEntryID: entry.ID,
 			})
 		}
 	}
+	if !latest.IsZero() {
+		periodDate = latest // bill a retainer for the month the work was done
+	}
+
+	billed, err := s.applyBillingModel(project, hourly, hourlyRate, periodDate)
+	if err != nil {
+		return nil, err
+	}
+	items := billed.items
+
+	var expenseItems []types.InvoiceItem
+	if !skipExpenses {
+		expenseItems, err = s.billableExpenseItems(actor, clientID, project)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, expenseItems...)
+	}
 
-	if len(items) == 0 {
-		return nil, fmt.Errorf("no completed time entries found for the specified period")
+	if invoiceTotal(items) <= 0 {
+		switch {
+		case billed.writeOff != nil:
+			return nil, fmt.Errorf("nothing to bill: %s", billed.writeOff.Reason)
+		case len(items) == 0:
+			return nil, fmt.Errorf("nothing to bill: no unbilled time entries or billable expenses found for the specified period")
+		}
+		return nil, fmt.Errorf("nothing to bill under the project's %s billing", billingModel(project))
 	}
 
-	invoice, err := s.CreateInvoice(userID, clientID, projectID, items)
+	invoice, err := s.CreateInvoice(actor, clientID, projectID, items)
 	if err != nil {
 		return nil, err
 	}
 
+	if billed.writeOff != nil {
+		invoice.WriteOff = billed.writeOff
+		if err := s.repo.Update(invoice); err != nil {
+			return nil, fmt.Errorf("failed to record write-off: %w", err)
+		}
+
+		event := types.NewEvent("invoice_hours_written_off", "invoice_service", map[string]any{
+			"invoice_id": invoice.ID,
+			"client_id":  invoice.ClientID,
+			"project_id": invoice.ProjectID,
+			"hours":      billed.writeOff.Hours,
+			"amount":     billed.writeOff.Amount,
+			"reason":     billed.writeOff.Reason,
+		})
+
+		s.eventBus.Publish("invoice.hours_written_off", event)
+		log.Printf("✂️ Wrote off %.2f hours ($%.2f) on %s: %s", billed.writeOff.Hours, billed.writeOff.Amount, invoice.Number, billed.writeOff.Reason)
+	}
+
 	event := types.NewEvent("invoice_generated", "invoice_service", map[string]any{
-		"invoice_id":   invoice.ID,
-		"client_id":    invoice.ClientID,
-		"project_id":   invoice.ProjectID,
-		"total_hours":  totalHours,
-		"total_amount": invoice.TotalAmount,
-		"entry_count":  len(timeEntries),
+		"invoice_id":    invoice.ID,
+		"client_id":     invoice.ClientID,
+		"project_id":    invoice.ProjectID,
+		"total_hours":   totalHours,
+		"total_amount":  invoice.TotalAmount,
+		"billing_model": billingModel(project),
+		"entry_count":   len(timeEntries),
+		"expense_count": len(expenseItems),
 	})
 
 	s.eventBus.Publish("invoice.generated", event)
@@ -114,30 +350,56 @@ func (s *Service) GenerateFromTimeEntries(userID, clientID, projectID string, ho
 	return invoice, nil
 }
 
-func (s *Service) GetInvoices(userID string) ([]*types.Invoice, error) {
-	return s.repo.GetByUserID(userID)
+func (s *Service) GetInvoices(actor types.Actor) ([]*types.Invoice, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByWorkspaceID(actor.WorkspaceID)
 }
 
-func (s *Service) GetInvoicesByClient(clientID string) ([]*types.Invoice, error) {
-	return s.repo.GetByClientID(clientID)
+func (s *Service) GetInvoicesByClient(actor types.Actor, clientID string) ([]*types.Invoice, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByClientID(actor.WorkspaceID, clientID)
 }
 
-func (s *Service) UpdateInvoiceStatus(invoiceID, status string) (*types.Invoice, error) {
-	invoice, err := s.repo.GetByID(invoiceID)
+func (s *Service) UpdateInvoiceStatus(actor types.Actor, invoiceID, status string) (*types.Invoice, error) {
+	if err := actor.RequireManage(); err != nil {
+		return nil, err
+	}
+	invoice, err := s.getInvoice(actor, invoiceID)
 	if err != nil {
-		return nil, fmt.Errorf("invoice not found: %w", err)
+		return nil, err
 	}
 
 	oldStatus := invoice.Status
+	if oldStatus == "" {
+		oldStatus = types.InvoiceStatusDraft
+	}
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status == oldStatus {
+		return invoice, nil
+	}
+	allowed, known := invoiceTransitions[oldStatus]
+	if _, valid := invoiceTransitions[status]; !valid || !known || !slices.Contains(allowed, status) {
+		return nil, fmt.Errorf("%w: %s to %q", ErrInvalidTransition, oldStatus, status)
+	}
+
 	invoice.Status = status
 	invoice.UpdatedAt = time.Now()
 
-	if status == "sent" && invoice.SentAt == nil {
+	if status == types.InvoiceStatusSent && invoice.SentAt == nil {
+		recipients, err := s.clients.GetInvoiceRecipients(actor, invoice.ClientID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve invoice recipients: %w", err)
+		}
 		now := time.Now()
 		invoice.SentAt = &now
+		invoice.SentTo = recipients
 	}
 
-	if status == "paid" && invoice.PaidAt == nil {
+	if status == types.InvoiceStatusPaid && invoice.PaidAt == nil {
 		now := time.Now()
 		invoice.PaidAt = &now
 	}
@@ -147,25 +409,45 @@ func (s *Service) UpdateInvoiceStatus(invoiceID, status string) (*types.Invoice,
 		return nil, fmt.Errorf("failed to update invoice: %w", err)
 	}
 
+	if status == types.InvoiceStatusCancelled || status == types.InvoiceStatusVoid {
+		s.releaseBilled(actor, invoice, invoiceExpenseIDs(invoice), invoiceTimeEntryIDs(invoice), invoiceMilestoneIDs(invoice))
+	}
+
 	event := types.NewEvent("invoice_status_updated", "invoice_service", map[string]any{
 		"invoice_id": invoice.ID,
 		"old_status": oldStatus,
 		"new_status": status,
 		"client_id":  invoice.ClientID,
 		"project_id": invoice.ProjectID,
+		"recipients": invoice.SentTo,
 	})
 
 	s.eventBus.Publish("invoice.status_updated", event)
 	return invoice, nil
 }
 
-func (s *Service) DeleteInvoice(invoiceID string) error {
-	invoice, err := s.repo.GetByID(invoiceID)
+// GetRecipients returns who an invoice was sent to, or who it would be sent to now
+func (s *Service) GetRecipients(actor types.Actor, invoiceID string) (*types.InvoiceRecipients, error) {
+	invoice, err := s.getInvoice(actor, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.SentTo != nil {
+		return invoice.SentTo, nil
+	}
+	return s.clients.GetInvoiceRecipients(actor, invoice.ClientID)
+}
+
+func (s *Service) DeleteInvoice(actor types.Actor, invoiceID string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+	invoice, err := s.getInvoice(actor, invoiceID)
 	if err != nil {
-		return fmt.Errorf("invoice not found: %w", err)
+		return err
 	}
 
-	if invoice.Status == "paid" {
+	if invoice.Status == types.InvoiceStatusPaid {
 		return fmt.Errorf("cannot delete paid invoice")
 	}
 
@@ -174,6 +456,8 @@ func (s *Service) DeleteInvoice(invoiceID string) error {
 		return fmt.Errorf("failed to delete invoice: %w", err)
 	}
 
+	s.releaseBilled(actor, invoice, invoiceExpenseIDs(invoice), invoiceTimeEntryIDs(invoice), invoiceMilestoneIDs(invoice))
+
 	event := types.NewEvent("invoice_deleted", "invoice_service", map[string]any{
 		"invoice_id": invoice.ID,
 		"client_id":  invoice.ClientID,
@@ -184,6 +468,203 @@ func (s *Service) DeleteInvoice(invoiceID string) error {
 	return nil
 }
 
+// billableExpenseItems turns the project's unbilled billable expenses into
+// invoice lines, applying the client's markup. Expenses in another currency
+// than the invoice's are left for the user to bill separately.
+func (s *Service) billableExpenseItems(actor types.Actor, clientID string, project *types.Project) ([]types.InvoiceItem, error) {
+	expenses, err := s.expenses.GetBillableExpenses(actor, project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get billable expenses: %w", err)
+	}
+	if len(expenses) == 0 {
+		return nil, nil
+	}
+
+	client, err := s.clients.GetClient(actor, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := invoiceCurrency(client, project)
+	items := make([]types.InvoiceItem, 0, len(expenses))
+	for _, expense := range expenses {
+		if !sameCurrency(expense.Currency, currency) {
+			log.Printf("Leaving expense %s off the invoice: it is in %s, the invoice in %s", expense.ID, expense.Currency, currency)
+			continue
+		}
+		items = append(items, expenseItem(expense, client.ExpenseMarkup))
+	}
+	return items, nil
+}
+
+// invoiceCurrency is the project's currency, or the client's for invoices
+// without a project or projects without a currency
+func invoiceCurrency(client *types.Client, project *types.Project) string {
+	switch {
+	case project != nil && project.Currency != "":
+		return strings.ToUpper(project.Currency)
+	case client.Currency != "":
+		return strings.ToUpper(client.Currency)
+	}
+	return defaultCurrency
+}
+
+// sameCurrency compares an amount's currency with the invoice's. Records
+// stored without a currency predate currencies and are taken as the invoice's.
+func sameCurrency(currency, invoiceCurrency string) bool {
+	return currency == "" || strings.EqualFold(currency, invoiceCurrency)
+}
+
+// expenseItem bills an expense at its recorded amount plus the markup percentage
+func expenseItem(expense *types.Expense, markup float64) types.InvoiceItem {
+	rate := math.Round(expense.Amount*(100+markup)) / 100
+	return types.InvoiceItem{
+		Section:     types.InvoiceSectionExpenses,
+		Description: expense.Description,
+		Quantity:    1,
+		Rate:        rate,
+		Amount:      rate,
+		ExpenseID:   expense.ID,
+		Markup:      markup,
+		Receipt:     expense.Receipt,
+	}
+}
+
+// GenerateFromMilestone invoices a milestone's fixed fee on its own, in the
+// currency of its project
+func (s *Service) GenerateFromMilestone(actor types.Actor, milestoneID string) (*types.Invoice, error) {
+	milestone, err := s.clients.GetMilestone(actor, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if milestone.Amount <= 0 {
+		return nil, fmt.Errorf("milestone %s has no fee to invoice", milestone.Name)
+	}
+	if milestone.InvoiceID != "" {
+		return nil, fmt.Errorf("milestone %s is already on invoice %s", milestone.Name, milestone.InvoiceID)
+	}
+
+	items := []types.InvoiceItem{{
+		Section:     types.InvoiceSectionServices,
+		Description: "Milestone: " + milestone.Name,
+		Quantity:    1,
+		Rate:        milestone.Amount,
+		Amount:      milestone.Amount,
+		MilestoneID: milestone.ID,
+	}}
+
+	return s.CreateInvoice(actor, milestone.ClientID, milestone.ProjectID, items)
+}
+
+// releaseBilled frees the expenses, time entries and milestones of an invoice
+// that was deleted, cancelled, voided or never saved
+func (s *Service) releaseBilled(actor types.Actor, invoice *types.Invoice, expenseIDs, timeEntryIDs, milestoneIDs []string) {
+	if len(expenseIDs) > 0 {
+		if err := s.expenses.MarkExpensesUnbilled(actor, invoice.ID, expenseIDs); err != nil {
+			log.Printf("Failed to release expenses of invoice %s: %v", invoice.ID, err)
+		}
+	}
+	if len(timeEntryIDs) > 0 {
+		if err := s.times.ReleaseTimeEntries(actor, invoice.ID, timeEntryIDs); err != nil {
+			log.Printf("Failed to release time entries of invoice %s: %v", invoice.ID, err)
+		}
+	}
+	for _, milestoneID := range milestoneIDs {
+		if err := s.clients.MarkMilestoneUninvoiced(actor, milestoneID, invoice.ID); err != nil {
+			log.Printf("Failed to release milestone %s of invoice %s: %v", milestoneID, invoice.ID, err)
+		}
+	}
+}
+
+// getClient returns one of the workspace's clients
+func (s *Service) getClient(actor types.Actor, clientID string) (*types.Client, error) {
+	client, err := s.clients.GetClient(actor, clientID)
+	if errors.Is(err, types.ErrForbidden) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrClientNotFound, clientID)
+	}
+	return client, nil
+}
+
+// clientProject returns the workspace project, rejecting one that belongs to
+// another client
+func (s *Service) clientProject(actor types.Actor, clientID, projectID string) (*types.Project, error) {
+	project, err := s.clients.GetProject(actor, projectID)
+	if errors.Is(err, types.ErrForbidden) {
+		return nil, err
+	}
+	if err != nil || project.ClientID != clientID {
+		return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, projectID)
+	}
+	return project, nil
+}
+
+// getInvoice returns one of the workspace's invoices
+func (s *Service) getInvoice(actor types.Actor, invoiceID string) (*types.Invoice, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	invoice, err := s.repo.GetByID(actor.WorkspaceID, invoiceID)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrInvoiceNotFound, invoiceID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	return invoice, nil
+}
+
+func invoiceTotal(items []types.InvoiceItem) float64 {
+	total := 0.0
+	for _, item := range items {
+		total += item.Amount
+	}
+	return total
+}
+
+func billingModel(project *types.Project) string {
+	if project.Billing == nil {
+		return types.BillingModelHourly
+	}
+	return project.Billing.Model
+}
+
+func invoiceMilestoneIDs(invoice *types.Invoice) []string {
+	var ids []string
+	for _, item := range invoice.Items {
+		if item.MilestoneID != "" {
+			ids = append(ids, item.MilestoneID)
+		}
+	}
+	return ids
+}
+
+func invoiceExpenseIDs(invoice *types.Invoice) []string {
+	var ids []string
+	for _, item := range invoice.Items {
+		if item.ExpenseID != "" {
+			ids = append(ids, item.ExpenseID)
+		}
+	}
+	return ids
+}
+
+// invoiceTimeEntryIDs returns the time entries an invoice bills. A retainer
+// splits an entry into included and overage lines, so IDs can repeat.
+func invoiceTimeEntryIDs(invoice *types.Invoice) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, item := range invoice.Items {
+		if item.TimeEntryID != "" && !seen[item.TimeEntryID] {
+			seen[item.TimeEntryID] = true
+			ids = append(ids, item.TimeEntryID)
+		}
+	}
+	return ids
+}
+
 func (s *Service) generateInvoiceNumber() string {
 	return fmt.Sprintf("INV-%d", time.Now().Unix())
 }
@@ -0,0 +1,423 @@
+package invoice
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"datastar-go/internal/modules/client"
+	"datastar-go/internal/modules/expense"
+	timemodule "datastar-go/internal/modules/time"
+	"datastar-go/internal/shared/testutil"
+	"datastar-go/internal/shared/types"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+type testEnv struct {
+	invoices *Service
+	expenses *expense.Service
+	clients  *client.Service
+	times    *timemodule.Service
+	db       *badger.DB
+	actor    types.Actor
+	client   *types.Client
+	project  *types.Project
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	bus := &testutil.EventBus{}
+	db := testutil.MemoryDB(t)
+
+	expenses := expense.NewService(bus, db, nil)
+	times := timemodule.NewService(bus, db)
+	clients := client.NewService(bus, db, times, expenses)
+	expenses.SetProjectSource(clients)
+	times.SetProjectSource(clients)
+	env := &testEnv{
+		invoices: NewService(bus, db, expenses, clients, times),
+		expenses: expenses,
+		clients:  clients,
+		times:    times,
+		db:       db,
+		actor:    types.PersonalActor("user-1"),
+	}
+
+	var err error
+	env.client, err = clients.CreateClient(env.actor, "Acme", "billing@acme.test", "Acme Ltd")
+	if err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+	env.project, err = clients.CreateProject(env.actor, env.client.ID, "Site", "", 100, nil)
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	return env
+}
+
+func (env *testEnv) billableExpense(t *testing.T, amount float64) *types.Expense {
+	t.Helper()
+	e, err := env.expenses.CreateExpense(env.actor, env.project.ID, "travel", "Train", amount, true, time.Now())
+	if err != nil {
+		t.Fatalf("CreateExpense: %v", err)
+	}
+	return e
+}
+
+func TestCreateInvoicePricesBilledItemsFromStore(t *testing.T) {
+	env := newTestEnv(t)
+	e := env.billableExpense(t, 40)
+	milestone, err := env.clients.CreateMilestone(env.actor, env.project.ID, types.Milestone{Name: "Launch", Amount: 500})
+	if err != nil {
+		t.Fatalf("CreateMilestone: %v", err)
+	}
+
+	invoice, err := env.invoices.CreateInvoice(env.actor, env.client.ID, env.project.ID, []types.InvoiceItem{
+		{Description: "Train", Quantity: 1, Rate: 1, Amount: 1, ExpenseID: e.ID},
+		{Description: "Launch", Quantity: 1, Rate: 1, Amount: 1, MilestoneID: milestone.ID},
+	})
+	if err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	if invoice.Items[0].Amount != 40 || invoice.Items[1].Amount != 500 {
+		t.Errorf("item amounts = %v, %v; want 40, 500", invoice.Items[0].Amount, invoice.Items[1].Amount)
+	}
+	if invoice.TotalAmount != 540 {
+		t.Errorf("total = %v, want 540", invoice.TotalAmount)
+	}
+}
+
+func TestUpdateInvoiceStatusTransitions(t *testing.T) {
+	env := newTestEnv(t)
+	invoice, err := env.invoices.CreateInvoice(env.actor, env.client.ID, env.project.ID, []types.InvoiceItem{
+		{Description: "Work", Quantity: 1, Rate: 100, Amount: 100},
+	})
+	if err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+
+	steps := []struct {
+		status string
+		ok     bool
+	}{
+		{"void", false},
+		{"bogus", false},
+		{"sent", true},
+		{"draft", false},
+		{"overdue", true},
+		{"paid", true},
+		{"draft", false},
+		{"void", false},
+	}
+	for _, step := range steps {
+		_, err := env.invoices.UpdateInvoiceStatus(env.actor, invoice.ID, step.status)
+		if step.ok && err != nil {
+			t.Errorf("to %s: %v", step.status, err)
+		}
+		if !step.ok && !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("to %s: err = %v, want ErrInvalidTransition", step.status, err)
+		}
+	}
+}
+
+func TestVoidingReleasesBilledItems(t *testing.T) {
+	for _, status := range []string{types.InvoiceStatusCancelled, types.InvoiceStatusVoid} {
+		t.Run(status, func(t *testing.T) {
+			env := newTestEnv(t)
+			e := env.billableExpense(t, 40)
+			milestone, err := env.clients.CreateMilestone(env.actor, env.project.ID, types.Milestone{Name: "Launch", Amount: 500})
+			if err != nil {
+				t.Fatalf("CreateMilestone: %v", err)
+			}
+
+			invoice, err := env.invoices.CreateInvoice(env.actor, env.client.ID, env.project.ID, []types.InvoiceItem{
+				{ExpenseID: e.ID},
+				{Description: "Launch", MilestoneID: milestone.ID},
+			})
+			if err != nil {
+				t.Fatalf("CreateInvoice: %v", err)
+			}
+			if status == types.InvoiceStatusVoid {
+				if _, err := env.invoices.UpdateInvoiceStatus(env.actor, invoice.ID, types.InvoiceStatusSent); err != nil {
+					t.Fatalf("send: %v", err)
+				}
+			}
+			if _, err := env.invoices.UpdateInvoiceStatus(env.actor, invoice.ID, status); err != nil {
+				t.Fatalf("%s: %v", status, err)
+			}
+
+			billable, err := env.expenses.GetBillableExpenses(env.actor, env.project.ID)
+			if err != nil {
+				t.Fatalf("GetBillableExpenses: %v", err)
+			}
+			if len(billable) != 1 || billable[0].ID != e.ID {
+				t.Errorf("expense was not released: %+v", billable)
+			}
+			released, err := env.clients.GetMilestone(env.actor, milestone.ID)
+			if err != nil {
+				t.Fatalf("GetMilestone: %v", err)
+			}
+			if released.InvoiceID != "" {
+				t.Errorf("milestone still on invoice %s", released.InvoiceID)
+			}
+		})
+	}
+}
+
+func TestGenerateStatementSkipsVoidedInvoices(t *testing.T) {
+	env := newTestEnv(t)
+	issue := func(amount float64, statuses ...string) *types.Invoice {
+		t.Helper()
+		invoice, err := env.invoices.CreateInvoice(env.actor, env.client.ID, env.project.ID, []types.InvoiceItem{
+			{Description: "Work", Quantity: 1, Rate: amount, Amount: amount},
+		})
+		if err != nil {
+			t.Fatalf("CreateInvoice: %v", err)
+		}
+		for _, status := range statuses {
+			if _, err := env.invoices.UpdateInvoiceStatus(env.actor, invoice.ID, status); err != nil {
+				t.Fatalf("to %s: %v", status, err)
+			}
+		}
+		return invoice
+	}
+	issue(100, types.InvoiceStatusSent)
+	issue(250, types.InvoiceStatusSent, types.InvoiceStatusVoid)
+	issue(75, types.InvoiceStatusCancelled)
+
+	now := time.Now()
+	statement, err := env.invoices.GenerateStatement(env.actor, env.client.ID, now.AddDate(0, -1, 0), now)
+	if err != nil {
+		t.Fatalf("GenerateStatement: %v", err)
+	}
+	if len(statement.Lines) != 1 || statement.TotalInvoiced != 100 {
+		t.Errorf("statement has %d lines totalling %v, want only the sent invoice", len(statement.Lines), statement.TotalInvoiced)
+	}
+	if statement.ClosingBalance != 100 {
+		t.Errorf("closing balance = %v, want 100", statement.ClosingBalance)
+	}
+}
+
+func TestAgingReportSkipsVoidedInvoices(t *testing.T) {
+	env := newTestEnv(t)
+	for _, statuses := range [][]string{
+		{types.InvoiceStatusSent},
+		{types.InvoiceStatusSent, types.InvoiceStatusVoid},
+		{types.InvoiceStatusCancelled},
+	} {
+		invoice, err := env.invoices.CreateInvoice(env.actor, env.client.ID, env.project.ID, []types.InvoiceItem{
+			{Description: "Work", Quantity: 1, Rate: 100, Amount: 100},
+		})
+		if err != nil {
+			t.Fatalf("CreateInvoice: %v", err)
+		}
+		for _, status := range statuses {
+			if _, err := env.invoices.UpdateInvoiceStatus(env.actor, invoice.ID, status); err != nil {
+				t.Fatalf("to %s: %v", status, err)
+			}
+		}
+	}
+
+	report, err := env.invoices.GetAgingReport(env.actor, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetAgingReport: %v", err)
+	}
+	if len(report.Clients) != 1 || len(report.Clients[0].Invoices) != 1 {
+		t.Fatalf("report = %+v, want the one sent invoice", report.Clients)
+	}
+	if report.Totals.Current != 100 {
+		t.Errorf("current = %v, want 100", report.Totals.Current)
+	}
+}
+
+// trackedEntry stores a completed entry of the given length, bypassing the timer
+func (env *testEnv) trackedEntry(t *testing.T, workspaceID, projectID string, hours float64) {
+	t.Helper()
+	start := time.Now().Add(-48 * time.Hour)
+	end := start.Add(time.Duration(hours * float64(time.Hour)))
+	entry := types.NewTimeEntry(env.actor.UserID, projectID, "Work")
+	entry.WorkspaceID = workspaceID
+	entry.StartTime = start
+	entry.EndTime = &end
+	entry.IsRunning = false
+	if err := timemodule.NewRepository(env.db).Save(entry); err != nil {
+		t.Fatalf("save time entry: %v", err)
+	}
+}
+
+// generate bills the project's tracked time without expenses
+func (env *testEnv) generate() (*types.Invoice, error) {
+	return env.invoices.GenerateFromTimeEntries(env.actor, env.client.ID, env.project.ID, 0, time.Time{}, time.Now(), true)
+}
+
+func TestGenerateFromTimeEntriesBillsHoursOnce(t *testing.T) {
+	for _, status := range []string{types.InvoiceStatusCancelled, types.InvoiceStatusVoid} {
+		t.Run(status, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.trackedEntry(t, env.actor.WorkspaceID, env.project.ID, 2)
+			env.trackedEntry(t, "other-workspace", env.project.ID, 5)
+
+			invoice, err := env.generate()
+			if err != nil {
+				t.Fatalf("GenerateFromTimeEntries: %v", err)
+			}
+			// Only the two hours of this workspace, at the project's rate
+			if invoice.TotalAmount != 200 {
+				t.Errorf("total = %v, want 200", invoice.TotalAmount)
+			}
+
+			if _, err := env.generate(); err == nil || !strings.Contains(err.Error(), "nothing to bill") {
+				t.Fatalf("second run: err = %v, want nothing to bill", err)
+			}
+
+			if status == types.InvoiceStatusVoid {
+				if _, err := env.invoices.UpdateInvoiceStatus(env.actor, invoice.ID, types.InvoiceStatusSent); err != nil {
+					t.Fatalf("send: %v", err)
+				}
+			}
+			if _, err := env.invoices.UpdateInvoiceStatus(env.actor, invoice.ID, status); err != nil {
+				t.Fatalf("%s: %v", status, err)
+			}
+
+			rebilled, err := env.generate()
+			if err != nil {
+				t.Fatalf("after %s: %v", status, err)
+			}
+			if rebilled.TotalAmount != 200 {
+				t.Errorf("rebilled total = %v, want 200", rebilled.TotalAmount)
+			}
+		})
+	}
+}
+
+func TestGenerateFromTimeEntriesRejectsForeignProject(t *testing.T) {
+	env := newTestEnv(t)
+	other, err := env.clients.CreateClient(env.actor, "Globex", "ap@globex.test", "Globex")
+	if err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+	otherProject, err := env.clients.CreateProject(env.actor, other.ID, "Portal", "", 100, nil)
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	stranger := types.PersonalActor("user-2")
+
+	cases := []struct {
+		name      string
+		actor     types.Actor
+		clientID  string
+		projectID string
+	}{
+		{"another client's project", env.actor, env.client.ID, otherProject.ID},
+		{"another workspace", stranger, env.client.ID, env.project.ID},
+		{"unknown project", env.actor, env.client.ID, "missing"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := env.invoices.GenerateFromTimeEntries(tc.actor, tc.clientID, tc.projectID, 100, time.Time{}, time.Now(), false)
+			if !errors.Is(err, ErrProjectNotFound) {
+				t.Errorf("err = %v, want ErrProjectNotFound", err)
+			}
+		})
+	}
+}
+
+// billIn moves the project to currency
+func (env *testEnv) billIn(t *testing.T, currency string) {
+	t.Helper()
+	if _, err := env.clients.UpdateProject(env.actor, env.project.ID, map[string]any{"currency": currency}); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+}
+
+// expenseIn returns a billable expense in currency
+func (env *testEnv) expenseIn(t *testing.T, amount float64, currency string) *types.Expense {
+	t.Helper()
+	e := env.billableExpense(t, amount)
+	updated, err := env.expenses.UpdateExpense(env.actor, e.ID, expense.ExpensePatch{
+		Currency: types.Optional[string]{Set: true, Value: currency},
+	})
+	if err != nil {
+		t.Fatalf("UpdateExpense: %v", err)
+	}
+	return updated
+}
+
+func TestCreateInvoiceUsesProjectCurrency(t *testing.T) {
+	env := newTestEnv(t)
+	env.billIn(t, "eur")
+	euros := env.expenseIn(t, 100, "EUR")
+	dollars := env.expenseIn(t, 100, "USD")
+
+	invoice, err := env.invoices.CreateInvoice(env.actor, env.client.ID, env.project.ID, []types.InvoiceItem{{ExpenseID: euros.ID}})
+	if err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	if invoice.Currency != "EUR" {
+		t.Errorf("currency = %s, want EUR", invoice.Currency)
+	}
+
+	_, err = env.invoices.CreateInvoice(env.actor, env.client.ID, env.project.ID, []types.InvoiceItem{{ExpenseID: dollars.ID}})
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("dollar expense on a euro invoice: err = %v, want ErrCurrencyMismatch", err)
+	}
+
+	// Without a project the client's currency applies
+	invoice, err = env.invoices.CreateInvoice(env.actor, env.client.ID, "", []types.InvoiceItem{
+		{Description: "Consulting", Quantity: 1, Rate: 50, Amount: 50},
+	})
+	if err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	if invoice.Currency != "USD" {
+		t.Errorf("currency without a project = %s, want USD", invoice.Currency)
+	}
+}
+
+func TestGenerateLeavesOutExpensesInOtherCurrencies(t *testing.T) {
+	env := newTestEnv(t)
+	env.billIn(t, "EUR")
+	euros := env.expenseIn(t, 40, "EUR")
+	dollars := env.expenseIn(t, 60, "USD")
+
+	invoice, err := env.invoices.GenerateFromTimeEntries(env.actor, env.client.ID, env.project.ID, 0, time.Time{}, time.Now(), false)
+	if err != nil {
+		t.Fatalf("GenerateFromTimeEntries: %v", err)
+	}
+	if invoice.Currency != "EUR" || len(invoice.Items) != 1 || invoice.Items[0].ExpenseID != euros.ID {
+		t.Errorf("invoice %s %+v, want only the euro expense", invoice.Currency, invoice.Items)
+	}
+
+	billable, err := env.expenses.GetBillableExpenses(env.actor, env.project.ID)
+	if err != nil {
+		t.Fatalf("GetBillableExpenses: %v", err)
+	}
+	if len(billable) != 1 || billable[0].ID != dollars.ID {
+		t.Errorf("billable after generating = %+v, want the dollar expense left over", billable)
+	}
+}
+
+func TestMilestoneFeeIsInvoicedInProjectCurrency(t *testing.T) {
+	env := newTestEnv(t)
+	env.billIn(t, "EUR")
+	milestone, err := env.clients.CreateMilestone(env.actor, env.project.ID, types.Milestone{Name: "Launch", Amount: 500})
+	if err != nil {
+		t.Fatalf("CreateMilestone: %v", err)
+	}
+
+	// Billed on an invoice without a project, in the client's dollars
+	_, err = env.invoices.CreateInvoice(env.actor, env.client.ID, "", []types.InvoiceItem{{MilestoneID: milestone.ID}})
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("euro milestone on a dollar invoice: err = %v, want ErrCurrencyMismatch", err)
+	}
+
+	invoice, err := env.invoices.GenerateFromMilestone(env.actor, milestone.ID)
+	if err != nil {
+		t.Fatalf("GenerateFromMilestone: %v", err)
+	}
+	if invoice.Currency != "EUR" || invoice.TotalAmount != 500 {
+		t.Errorf("invoice = %.2f %s, want 500.00 EUR", invoice.TotalAmount, invoice.Currency)
+	}
+}
@@ -0,0 +1,325 @@
+package invoice
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+// defaultPaymentTerms is how long clients have to pay an invoice
+const defaultPaymentTerms = 30 * 24 * time.Hour
+
+// Statement line types
+const (
+	LineInvoice = "invoice"
+	LinePayment = "payment"
+	LineCredit  = "credit"
+)
+
+// StatementLine is one invoice, payment or credit on a statement of account.
+// Debits raise what the client owes, credits lower it.
+type StatementLine struct {
+	Date        time.Time  `json:"date"`
+	Type        string     `json:"type"`
+	InvoiceID   string     `json:"invoice_id"`
+	Reference   string     `json:"reference"`
+	Description string     `json:"description"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Debit       float64    `json:"debit"`
+	Credit      float64    `json:"credit"`
+	Balance     float64    `json:"balance"`
+}
+
+// AgingBuckets split an outstanding balance by how long it is past due.
+// Unapplied credits count as current.
+type AgingBuckets struct {
+	Current    float64 `json:"current"`
+	Days1To30  float64 `json:"days_1_30"`
+	Days31To60 float64 `json:"days_31_60"`
+	Days61To90 float64 `json:"days_61_90"`
+	Over90     float64 `json:"over_90"`
+	Total      float64 `json:"total"`
+}
+
+// Statement is a client's statement of account for a date range
+type Statement struct {
+	Client         *types.Client          `json:"client"`
+	Business       *types.BusinessProfile `json:"business,omitempty"`
+	From           time.Time              `json:"from"`
+	To             time.Time              `json:"to"`
+	Currency       string                 `json:"currency"`
+	OpeningBalance float64                `json:"opening_balance"`
+	Lines          []StatementLine        `json:"lines"`
+	TotalInvoiced  float64                `json:"total_invoiced"`
+	TotalPaid      float64                `json:"total_paid"`
+	TotalCredited  float64                `json:"total_credited"`
+	ClosingBalance float64                `json:"closing_balance"`
+	Aging          AgingBuckets           `json:"aging"`
+	GeneratedAt    time.Time              `json:"generated_at"`
+}
+
+// GenerateStatement lists a client's issued invoices, payments and credit
+// notes between from and to (inclusive days) with a running balance. Drafts
+// are not on statements; invoices with a negative total are credit notes.
+func (s *Service) GenerateStatement(actor types.Actor, clientID string, from, to time.Time) (*Statement, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("statement end is before its start")
+	}
+
+	client, err := s.clients.GetClient(actor, clientID)
+	if err != nil {
+		return nil, err
+	}
+	business, err := s.clients.GetBusinessProfile(actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get business profile: %w", err)
+	}
+
+	invoices, err := s.repo.GetByClientID(actor.WorkspaceID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+
+	from = startOfDay(from)
+	end := startOfDay(to).AddDate(0, 0, 1) // exclusive
+
+	statement := &Statement{
+		Client:      client,
+		Business:    business,
+		From:        from,
+		To:          startOfDay(to),
+		Currency:    client.Currency,
+		Lines:       []StatementLine{},
+		GeneratedAt: time.Now(),
+	}
+
+	var lines []StatementLine
+	for _, invoice := range invoices {
+		if !isIssued(invoice) {
+			continue
+		}
+		if statement.Currency == "" {
+			statement.Currency = invoice.Currency
+		}
+		lines = append(lines, invoiceLines(invoice)...)
+	}
+
+	slices.SortStableFunc(lines, func(a, b StatementLine) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	balance := 0.0
+	for _, line := range lines {
+		if !line.Date.Before(end) {
+			break
+		}
+		balance = round2(balance + line.Debit - line.Credit)
+		if line.Date.Before(from) {
+			statement.OpeningBalance = balance
+			continue
+		}
+
+		line.Balance = balance
+		statement.Lines = append(statement.Lines, line)
+		statement.TotalInvoiced += line.Debit
+		if line.Type == LinePayment {
+			statement.TotalPaid += line.Credit
+		} else {
+			statement.TotalCredited += line.Credit
+		}
+	}
+
+	statement.TotalInvoiced = round2(statement.TotalInvoiced)
+	statement.TotalPaid = round2(statement.TotalPaid)
+	statement.TotalCredited = round2(statement.TotalCredited)
+	statement.ClosingBalance = balance
+	statement.Aging = agingAt(invoices, end)
+
+	return statement, nil
+}
+
+// isIssued reports whether an invoice was sent to the client and still stands,
+// i.e. it is neither a draft nor cancelled or voided
+func isIssued(invoice *types.Invoice) bool {
+	switch invoice.Status {
+	case "", types.InvoiceStatusDraft, types.InvoiceStatusCancelled, types.InvoiceStatusVoid:
+		return false
+	}
+	return true
+}
+
+// invoiceLines turns an issued invoice into its statement lines: the charge or
+// credit note, and the payment if it was paid
+func invoiceLines(invoice *types.Invoice) []StatementLine {
+	issued, due := invoiceDates(invoice)
+	line := StatementLine{
+		Date:        issued,
+		InvoiceID:   invoice.ID,
+		Reference:   invoice.Number,
+		Description: invoice.Title,
+	}
+
+	if invoice.TotalAmount < 0 {
+		line.Type = LineCredit
+		line.Credit = round2(-invoice.TotalAmount)
+		if line.Description == "" {
+			line.Description = "Credit note"
+		}
+		return []StatementLine{line}
+	}
+
+	line.Type = LineInvoice
+	line.Debit = round2(invoice.TotalAmount)
+	line.DueDate = &due
+	if line.Description == "" {
+		line.Description = "Invoice"
+	}
+	lines := []StatementLine{line}
+
+	if invoice.PaidAt != nil {
+		lines = append(lines, StatementLine{
+			Date:        *invoice.PaidAt,
+			Type:        LinePayment,
+			InvoiceID:   invoice.ID,
+			Reference:   invoice.Number,
+			Description: "Payment received, thank you",
+			Credit:      line.Debit,
+		})
+	}
+	return lines
+}
+
+// agingAt buckets what was still owed at the given time by days past due
+func agingAt(invoices []*types.Invoice, at time.Time) AgingBuckets {
+	var aging AgingBuckets
+	for _, invoice := range invoices {
+		if !isOutstanding(invoice, at) {
+			continue
+		}
+		if invoice.TotalAmount < 0 {
+			aging.Current += invoice.TotalAmount
+			continue
+		}
+		_, due := invoiceDates(invoice)
+		aging.add(bucketName(daysPastDue(due, at)), invoice.TotalAmount)
+	}
+	aging.round()
+	return aging
+}
+
+// add puts an amount into the named bucket
+func (a *AgingBuckets) add(bucket string, amount float64) {
+	switch bucket {
+	case BucketCurrent:
+		a.Current += amount
+	case BucketDays1To30:
+		a.Days1To30 += amount
+	case BucketDays31To60:
+		a.Days31To60 += amount
+	case BucketDays61To90:
+		a.Days61To90 += amount
+	default:
+		a.Over90 += amount
+	}
+}
+
+func (a *AgingBuckets) round() {
+	a.Current = round2(a.Current)
+	a.Days1To30 = round2(a.Days1To30)
+	a.Days31To60 = round2(a.Days31To60)
+	a.Days61To90 = round2(a.Days61To90)
+	a.Over90 = round2(a.Over90)
+	a.Total = round2(a.Current + a.Days1To30 + a.Days31To60 + a.Days61To90 + a.Over90)
+}
+
+// invoiceDates returns when an invoice was issued and is due, falling back
+// to the creation date and default terms for invoices stored without them
+func invoiceDates(invoice *types.Invoice) (issued, due time.Time) {
+	issued = invoice.IssueDate
+	if issued.IsZero() {
+		issued = invoice.CreatedAt
+	}
+	due = invoice.DueDate
+	if due.IsZero() {
+		due = issued.Add(defaultPaymentTerms)
+	}
+	return issued, due
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// WriteStatementHTML renders a statement as a printable HTML page
+func WriteStatementHTML(w io.Writer, statement *Statement) error {
+	return statementTemplate.Execute(w, statement)
+}
+
+var statementTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2 Jan 2006") },
+	"money": func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", v)
+	},
+	"amount": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"addf":   func(a, b float64) float64 { return a + b },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Statement of account: {{.Client.Name}}</title>
+<style>
+body { font-family: system-ui, sans-serif; color: #111; max-width: 56rem; margin: 2rem auto; }
+header { display: flex; justify-content: space-between; margin-bottom: 2rem; }
+table { width: 100%; border-collapse: collapse; margin-bottom: 1.5rem; }
+th, td { padding: .4rem .5rem; border-bottom: 1px solid #ddd; text-align: left; }
+td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+tfoot td { font-weight: 600; border-top: 2px solid #111; }
+</style>
+</head>
+<body>
+<header>
+  <div>
+    {{with .Business}}<strong>{{.LegalName}}</strong><br>{{.Address.Format}}{{with .Identifiers.VATID}}<br>VAT {{.}}{{end}}{{end}}
+  </div>
+  <div>
+    <h1>Statement of account</h1>
+    {{date .From}} to {{date .To}}<br>Currency: {{.Currency}}
+  </div>
+</header>
+<p>
+  <strong>{{with .Client.Company}}{{.}}{{else}}{{.Client.Name}}{{end}}</strong><br>
+  {{.Client.Address}}{{with .Client.Identifiers}}{{with .VATID}}<br>VAT {{.}}{{end}}{{end}}
+</p>
+<table>
+  <thead>
+    <tr><th>Date</th><th>Reference</th><th>Description</th><th>Due</th><th class="num">Debit</th><th class="num">Credit</th><th class="num">Balance</th></tr>
+  </thead>
+  <tbody>
+    <tr><td>{{date .From}}</td><td></td><td>Opening balance</td><td></td><td class="num"></td><td class="num"></td><td class="num">{{amount .OpeningBalance}}</td></tr>
+    {{range .Lines}}
+    <tr><td>{{date .Date}}</td><td>{{.Reference}}</td><td>{{.Description}}</td><td>{{with .DueDate}}{{date .}}{{end}}</td><td class="num">{{money .Debit}}</td><td class="num">{{money .Credit}}</td><td class="num">{{amount .Balance}}</td></tr>
+    {{end}}
+  </tbody>
+  <tfoot>
+    <tr><td colspan="4">Closing balance</td><td class="num">{{amount .TotalInvoiced}}</td><td class="num">{{amount (addf .TotalPaid .TotalCredited)}}</td><td class="num">{{amount .ClosingBalance}}</td></tr>
+  </tfoot>
+</table>
+<table>
+  <thead>
+    <tr><th class="num">Current</th><th class="num">1–30 days</th><th class="num">31–60 days</th><th class="num">61–90 days</th><th class="num">Over 90 days</th><th class="num">Total due</th></tr>
+  </thead>
+  <tbody>
+    <tr><td class="num">{{amount .Aging.Current}}</td><td class="num">{{amount .Aging.Days1To30}}</td><td class="num">{{amount .Aging.Days31To60}}</td><td class="num">{{amount .Aging.Days61To90}}</td><td class="num">{{amount .Aging.Over90}}</td><td class="num">{{amount .Aging.Total}}</td></tr>
+  </tbody>
+</table>
+<p><small>Generated {{date .GeneratedAt}}</small></p>
+</body>
+</html>
+`))
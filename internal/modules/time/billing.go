@@ -0,0 +1,92 @@
+package time
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"datastar-go/internal/shared/types"
+)
+
+var ErrTimeEntryBilled = errors.New("time entry is already billed")
+
+// MarkTimeEntriesBilled flags completed time entries as billed on an invoice.
+// Nothing is changed unless every entry is completed and not billed elsewhere.
+func (s *Service) MarkTimeEntriesBilled(actor types.Actor, invoiceID string, timeEntryIDs []string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+	entries := make([]*types.TimeEntry, 0, len(timeEntryIDs))
+	for _, id := range timeEntryIDs {
+		entry, err := s.repo.Get(actor.WorkspaceID, id)
+		if err != nil {
+			return fmt.Errorf("failed to get time entry: %w", err)
+		}
+		if entry == nil {
+			return fmt.Errorf("time entry not found: %s", id)
+		}
+		if entry.IsRunning || entry.EndTime == nil {
+			return fmt.Errorf("time entry %s is still running", id)
+		}
+		if entry.IsBilled && entry.InvoiceID != invoiceID {
+			return fmt.Errorf("%w: %s", ErrTimeEntryBilled, id)
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, entry := range entries {
+		entry.IsBilled = true
+		entry.InvoiceID = invoiceID
+		entry.UpdatedAt = time.Now()
+
+		if err := s.repo.Save(entry); err != nil {
+			return fmt.Errorf("failed to update time entry: %w", err)
+		}
+	}
+
+	event := types.NewEvent("time_entries_billed", "time_service", map[string]any{
+		"invoice_id":     invoiceID,
+		"user_id":        actor.UserID,
+		"workspace_id":   actor.WorkspaceID,
+		"time_entry_ids": timeEntryIDs,
+	})
+
+	s.eventBus.Publish("time.entries.billed", event)
+	return nil
+}
+
+// ReleaseTimeEntries frees time entries from an invoice so they can be billed again
+func (s *Service) ReleaseTimeEntries(actor types.Actor, invoiceID string, timeEntryIDs []string) error {
+	if err := actor.RequireManage(); err != nil {
+		return err
+	}
+	var released []string
+	for _, id := range timeEntryIDs {
+		entry, err := s.repo.Get(actor.WorkspaceID, id)
+		if err != nil {
+			return fmt.Errorf("failed to get time entry: %w", err)
+		}
+		if entry == nil || entry.InvoiceID != invoiceID {
+			continue // deleted or billed elsewhere since; nothing to release
+		}
+
+		entry.IsBilled = false
+		entry.InvoiceID = ""
+		entry.UpdatedAt = time.Now()
+
+		if err := s.repo.Save(entry); err != nil {
+			return fmt.Errorf("failed to update time entry: %w", err)
+		}
+		released = append(released, id)
+	}
+
+	event := types.NewEvent("time_entries_released", "time_service", map[string]any{
+		"invoice_id":     invoiceID,
+		"user_id":        actor.UserID,
+		"workspace_id":   actor.WorkspaceID,
+		"time_entry_ids": released,
+	})
+
+	s.eventBus.Publish("time.entries.released", event)
+	return nil
+}
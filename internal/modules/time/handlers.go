@@ -2,10 +2,12 @@ package time
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
+	"datastar-go/internal/modules/auth"
 	"datastar-go/internal/shared/types"
 )
 
@@ -23,7 +25,6 @@ func NewHandlers(service *Service) *Handlers {
 
 // Request/Response types
 type StartTimerRequest struct {
-	UserID      string `json:"user_id"`
 	ProjectID   string `json:"project_id"`
 	Description string `json:"description"`
 }
@@ -37,7 +38,6 @@ type TimerResponse struct {
 }
 
 type UpdateTimerRequest struct {
-	UserID      string   `json:"user_id"`
 	TimeEntryID string   `json:"time_entry_id"`
 	Description string   `json:"description"`
 	Tags        []string `json:"tags"`
@@ -51,6 +51,12 @@ func (h *Handlers) handleStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		h.sendError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req StartTimerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.sendError(w, "Invalid request body", http.StatusBadRequest)
@@ -58,14 +64,18 @@ func (h *Handlers) handleStart(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate required fields
-	if req.UserID == "" || req.ProjectID == "" {
-		h.sendError(w, "user_id and project_id are required", http.StatusBadRequest)
+	if req.ProjectID == "" {
+		h.sendError(w, "project_id is required", http.StatusBadRequest)
 		return
 	}
 
-	entry, err := h.service.StartTimer(req.UserID, req.ProjectID, req.Description)
+	entry, err := h.service.StartTimer(actor, req.ProjectID, req.Description)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrProjectNotFound) {
+			status = http.StatusNotFound
+		}
+		h.sendError(w, err.Error(), auth.ErrorStatus(err, status))
 		return
 	}
 
@@ -78,15 +88,15 @@ func (h *Handlers) handleStop(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		h.sendError(w, "user_id is required", http.StatusBadRequest)
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		h.sendError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	entry, err := h.service.StopTimer(userID)
+	entry, err := h.service.StopTimer(actor)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		h.sendError(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -99,15 +109,15 @@ func (h *Handlers) handlePause(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		h.sendError(w, "user_id is required", http.StatusBadRequest)
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		h.sendError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	entry, err := h.service.PauseTimer(userID)
+	entry, err := h.service.PauseTimer(actor)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		h.sendError(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -120,15 +130,15 @@ func (h *Handlers) handleResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		h.sendError(w, "user_id is required", http.StatusBadRequest)
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		h.sendError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	entry, err := h.service.ResumeTimer(userID)
+	entry, err := h.service.ResumeTimer(actor)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		h.sendError(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -141,13 +151,13 @@ func (h *Handlers) handleGetCurrent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		h.sendError(w, "user_id is required", http.StatusBadRequest)
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		h.sendError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	entry := h.service.GetActiveTimer(userID)
+	entry := h.service.GetActiveTimer(actor)
 	if entry == nil {
 		h.sendResponse(w, TimerResponse{
 			Success: true,
@@ -158,7 +168,7 @@ func (h *Handlers) handleGetCurrent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current duration
-	duration := h.service.GetCurrentDuration(userID)
+	duration := h.service.GetCurrentDuration(actor)
 
 	response := TimerResponse{
 		Success:  true,
@@ -176,20 +186,26 @@ func (h *Handlers) handleUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		h.sendError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req UpdateTimerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.sendError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if req.UserID == "" || req.TimeEntryID == "" {
-		h.sendError(w, "user_id and time_entry_id are required", http.StatusBadRequest)
+	if req.TimeEntryID == "" {
+		h.sendError(w, "time_entry_id is required", http.StatusBadRequest)
 		return
 	}
 
-	err := h.service.UpdateTimeEntry(req.UserID, req.TimeEntryID, req.Description, req.Tags)
+	err := h.service.UpdateTimeEntry(actor, req.TimeEntryID, req.Description, req.Tags)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		h.sendError(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -0,0 +1,56 @@
+package time
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"datastar-go/internal/modules/auth"
+	"datastar-go/internal/shared/testutil"
+	"datastar-go/internal/shared/types"
+)
+
+// workspaceProjects maps project IDs to the workspace that owns them
+type workspaceProjects map[string]string
+
+func (p workspaceProjects) GetProject(actor types.Actor, projectID string) (*types.Project, error) {
+	if p[projectID] != actor.WorkspaceID {
+		return nil, errors.New("project not found")
+	}
+	return &types.Project{ID: projectID, WorkspaceID: actor.WorkspaceID}, nil
+}
+
+func TestStartRejectsCrossTenantProjects(t *testing.T) {
+	s := NewService(&testutil.EventBus{}, testutil.MemoryDB(t))
+	s.SetProjectSource(workspaceProjects{"proj-1": "user-1", "proj-2": "user-2"})
+	h := NewHandlers(s)
+
+	owner := types.PersonalActor("user-1")
+	accountant := types.Actor{UserID: "user-3", WorkspaceID: owner.WorkspaceID, Role: types.RoleAccountant}
+
+	cases := []struct {
+		name      string
+		actor     types.Actor
+		projectID string
+		want      int
+	}{
+		{"another workspace's project", owner, "proj-2", http.StatusNotFound},
+		{"unknown project", owner, "missing", http.StatusNotFound},
+		{"accountant", accountant, "proj-1", http.StatusForbidden},
+		{"own project", owner, "proj-1", http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/time/start", strings.NewReader(`{"project_id":"`+tc.projectID+`"}`))
+			r = r.WithContext(context.WithValue(r.Context(), auth.ActorKey, tc.actor))
+			w := httptest.NewRecorder()
+			h.handleStart(w, r)
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+}
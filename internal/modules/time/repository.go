@@ -30,7 +30,9 @@ func (r *Repository) Save(entry *types.TimeEntry) error {
 	})
 }
 
-func (r *Repository) Get(id string) (*types.TimeEntry, error) {
+// Get returns a workspace's time entry, or nil if it doesn't exist or belongs
+// to another workspace
+func (r *Repository) Get(workspaceID, id string) (*types.TimeEntry, error) {
 	key := fmt.Sprintf("time_entry:%s", id)
 	var entry types.TimeEntry
 
@@ -51,6 +53,9 @@ func (r *Repository) Get(id string) (*types.TimeEntry, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !types.InWorkspace(entry.WorkspaceID, entry.UserID, workspaceID) {
+		return nil, nil
+	}
 
 	return &entry, nil
 }
@@ -110,7 +115,7 @@ func (r *Repository) Delete(id string) error {
 	})
 }
 
-func (r *Repository) GetByProjectIDAndDateRange(projectID string, startDate, endDate time.Time) ([]*types.TimeEntry, error) {
+func (r *Repository) GetByProjectIDAndDateRange(workspaceID, projectID string, startDate, endDate time.Time) ([]*types.TimeEntry, error) {
 	var entries []*types.TimeEntry
 	err := r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
@@ -125,7 +130,7 @@ func (r *Repository) GetByProjectIDAndDateRange(projectID string, startDate, end
 				if err := json.Unmarshal(val, &entry); err != nil {
 					return err
 				}
-				if entry.ProjectID == projectID &&
+				if types.InWorkspace(entry.WorkspaceID, entry.UserID, workspaceID) && entry.ProjectID == projectID &&
 					entry.StartTime.After(startDate) &&
 					entry.StartTime.Before(endDate) {
 					entries = append(entries, &entry)
@@ -0,0 +1,50 @@
+package time
+
+import (
+	"testing"
+	"time"
+
+	"datastar-go/internal/shared/testutil"
+	"datastar-go/internal/shared/types"
+)
+
+func TestRepositoryScopesEntriesToWorkspace(t *testing.T) {
+	repo := NewRepository(testutil.MemoryDB(t))
+	shared := types.NewTimeEntry("user-1", "proj-1", "Design")
+	shared.WorkspaceID = "ws-1"
+	legacy := types.NewTimeEntry("user-1", "proj-1", "Build") // stored before workspaces
+	for _, entry := range []*types.TimeEntry{shared, legacy} {
+		if err := repo.Save(entry); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	for _, lookup := range []struct {
+		workspaceID, id string
+		found           bool
+	}{
+		{"ws-1", shared.ID, true},
+		{"user-1", legacy.ID, true},
+		{"ws-2", shared.ID, false},
+		{"user-1", shared.ID, false},
+		{"ws-1", legacy.ID, false},
+	} {
+		entry, err := repo.Get(lookup.workspaceID, lookup.id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if (entry != nil) != lookup.found {
+			t.Errorf("Get(%s, %s) found = %v, want %v", lookup.workspaceID, lookup.id, entry != nil, lookup.found)
+		}
+	}
+
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	entries, err := repo.GetByProjectIDAndDateRange("ws-2", "proj-1", from, to)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("GetByProjectIDAndDateRange(ws-2) = %d entries, %v; want none", len(entries), err)
+	}
+	entries, err = repo.GetByProjectIDAndDateRange("ws-1", "proj-1", from, to)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("GetByProjectIDAndDateRange(ws-1) = %d entries, %v; want 1", len(entries), err)
+	}
+}
@@ -1,6 +1,7 @@
 package time
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -10,10 +11,18 @@ import (
 	"github.com/dgraph-io/badger/v4"
 )
 
+var ErrProjectNotFound = errors.New("project not found")
+
+// ProjectSource resolves the workspace's projects time is tracked against
+type ProjectSource interface {
+	GetProject(actor types.Actor, projectID string) (*types.Project, error)
+}
+
 // Service handles time tracking business logic
 type Service struct {
 	eventBus types.EventBus
 	repo     *Repository
+	projects ProjectSource
 }
 
 // NewService creates a new time tracking service
@@ -29,22 +38,40 @@ func NewService(eventBus types.EventBus, db *badger.DB) *Service {
 	return service
 }
 
+// SetProjectSource wires the project lookup that keeps timers on the actor's
+// own workspace projects. The client service depends on this one, so it is
+// set after both are built.
+func (s *Service) SetProjectSource(projects ProjectSource) {
+	s.projects = projects
+}
+
 // setupEventSubscriptions configures event handlers
 func (s *Service) setupEventSubscriptions() {
 	// Listen for client project events
 	s.eventBus.SubscribeQueue("client.project.started", "time_service", s.handleProjectStarted)
 
-	// Listen for invoice events
-	s.eventBus.SubscribeQueue("invoice.generated", "time_service", s.handleInvoiceGenerated)
-
 	// Listen for system events
 	s.eventBus.SubscribeQueue("system.user.logout", "time_service", s.handleUserLogout)
 
 	log.Println("Time service event subscriptions configured")
 }
 
-// StartTimer starts a new time tracking session
-func (s *Service) StartTimer(userID, projectID, description string) (*types.TimeEntry, error) {
+// StartTimer starts a new time tracking session in the actor's workspace.
+// Timers are personal: a user has at most one running, in any workspace.
+func (s *Service) StartTimer(actor types.Actor, projectID, description string) (*types.TimeEntry, error) {
+	if err := actor.RequireWrite(); err != nil {
+		return nil, err
+	}
+	if projectID != "" {
+		if s.projects == nil {
+			return nil, fmt.Errorf("project source not configured")
+		}
+		if _, err := s.projects.GetProject(actor, projectID); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, projectID)
+		}
+	}
+	userID := actor.UserID
+
 	// Stop any existing timer for this user
 	err := s.stopActiveTimer(userID)
 	if err != nil {
@@ -53,6 +80,7 @@ func (s *Service) StartTimer(userID, projectID, description string) (*types.Time
 
 	// Create new time entry
 	entry := types.NewTimeEntry(userID, projectID, description)
+	entry.WorkspaceID = actor.WorkspaceID
 
 	// Save to Badger
 	err = s.repo.Save(entry)
@@ -64,6 +92,7 @@ func (s *Service) StartTimer(userID, projectID, description string) (*types.Time
 	event := types.NewEvent("session_started", "time_service", map[string]any{
 		"time_entry_id": entry.ID,
 		"user_id":       userID,
+		"workspace_id":  entry.WorkspaceID,
 		"project_id":    projectID,
 		"description":   description,
 		"start_time":    entry.StartTime,
@@ -78,8 +107,12 @@ func (s *Service) StartTimer(userID, projectID, description string) (*types.Time
 	return entry, nil
 }
 
-// StopTimer stops the active timer for a user
-func (s *Service) StopTimer(userID string) (*types.TimeEntry, error) {
+// StopTimer stops the user's active timer
+func (s *Service) StopTimer(actor types.Actor) (*types.TimeEntry, error) {
+	return s.stopTimer(actor.UserID)
+}
+
+func (s *Service) stopTimer(userID string) (*types.TimeEntry, error) {
 	entry, err := s.repo.GetActiveTimer(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active timer: %w", err)
@@ -105,6 +138,7 @@ func (s *Service) StopTimer(userID string) (*types.TimeEntry, error) {
 	event := types.NewEvent("session_stopped", "time_service", map[string]any{
 		"time_entry_id": entry.ID,
 		"user_id":       userID,
+		"workspace_id":  types.WorkspaceOf(entry.WorkspaceID, entry.UserID),
 		"project_id":    entry.ProjectID,
 		"duration":      entry.Duration,
 		"amount":        amount,
@@ -124,7 +158,8 @@ func (s *Service) StopTimer(userID string) (*types.TimeEntry, error) {
 }
 
 // PauseTimer pauses the active timer
-func (s *Service) PauseTimer(userID string) (*types.TimeEntry, error) {
+func (s *Service) PauseTimer(actor types.Actor) (*types.TimeEntry, error) {
+	userID := actor.UserID
 	entry, err := s.repo.GetActiveTimer(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active timer: %w", err)
@@ -168,7 +203,8 @@ func (s *Service) PauseTimer(userID string) (*types.TimeEntry, error) {
 }
 
 // ResumeTimer resumes a paused timer
-func (s *Service) ResumeTimer(userID string) (*types.TimeEntry, error) {
+func (s *Service) ResumeTimer(actor types.Actor) (*types.TimeEntry, error) {
+	userID := actor.UserID
 	entry, err := s.repo.GetActiveTimer(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active timer: %w", err)
@@ -208,9 +244,9 @@ func (s *Service) ResumeTimer(userID string) (*types.TimeEntry, error) {
 	return entry, nil
 }
 
-// GetActiveTimer returns the active timer for a user
-func (s *Service) GetActiveTimer(userID string) *types.TimeEntry {
-	entry, err := s.repo.GetActiveTimer(userID)
+// GetActiveTimer returns the user's active timer
+func (s *Service) GetActiveTimer(actor types.Actor) *types.TimeEntry {
+	entry, err := s.repo.GetActiveTimer(actor.UserID)
 	if err != nil {
 		log.Printf("Error getting active timer: %v", err)
 		return nil
@@ -218,9 +254,18 @@ func (s *Service) GetActiveTimer(userID string) *types.TimeEntry {
 	return entry
 }
 
-// GetCurrentDuration returns the current duration of an active timer
-func (s *Service) GetCurrentDuration(userID string) int64 {
-	entry, err := s.repo.GetActiveTimer(userID)
+// GetProjectTimeEntries returns the time entries of every workspace member on
+// a project started within [from, to)
+func (s *Service) GetProjectTimeEntries(actor types.Actor, projectID string, from, to time.Time) ([]*types.TimeEntry, error) {
+	if err := actor.RequireRead(); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByProjectIDAndDateRange(actor.WorkspaceID, projectID, from.Add(-time.Nanosecond), to)
+}
+
+// GetCurrentDuration returns the current duration of the user's active timer
+func (s *Service) GetCurrentDuration(actor types.Actor) int64 {
+	entry, err := s.repo.GetActiveTimer(actor.UserID)
 	if err != nil || entry == nil || !entry.IsRunning {
 		return 0
 	}
@@ -236,7 +281,7 @@ func (s *Service) stopActiveTimer(userID string) error {
 		return err
 	}
 	if entry != nil && entry.IsRunning {
-		_, err = s.StopTimer(userID)
+		_, err = s.stopTimer(userID)
 		return err
 	}
 	return nil
@@ -250,6 +295,11 @@ func (s *Service) handleProjectStarted(event *types.Event) error {
 
 	log.Printf("🎯 Project started event received: %s for user %s", projectID, userID)
 
+	// Planned projects start later; there's nothing to track yet
+	if status, _ := event.Data["status"].(string); status == types.ProjectStatusPlanned {
+		return nil
+	}
+
 	// Auto-start timer suggestion event
 	suggestionEvent := types.NewEvent("timer_suggestion", "time_service", map[string]any{
 		"user_id":    userID,
@@ -261,22 +311,6 @@ func (s *Service) handleProjectStarted(event *types.Event) error {
 	return s.eventBus.Publish("time.suggestion.start", suggestionEvent)
 }
 
-func (s *Service) handleInvoiceGenerated(event *types.Event) error {
-	invoiceID, _ := event.Data["invoice_id"].(string)
-	userID, _ := event.Data["user_id"].(string)
-
-	log.Printf("🧾 Invoice generated: %s - marking time entries as billed", invoiceID)
-
-	// Mark relevant time entries as billed
-	billedEvent := types.NewEvent("time_entries_billed", "time_service", map[string]any{
-		"invoice_id": invoiceID,
-		"user_id":    userID,
-		"billed_at":  time.Now(),
-	})
-
-	return s.eventBus.Publish("time.entries.billed", billedEvent)
-}
-
 func (s *Service) handleUserLogout(event *types.Event) error {
 	userID, _ := event.Data["user_id"].(string)
 
@@ -289,7 +323,7 @@ func (s *Service) handleUserLogout(event *types.Event) error {
 		return nil
 	}
 	if entry != nil {
-		_, err := s.StopTimer(userID)
+		_, err := s.stopTimer(userID)
 		if err != nil {
 			log.Printf("Error stopping timer on logout: %v", err)
 		}
@@ -298,15 +332,22 @@ func (s *Service) handleUserLogout(event *types.Event) error {
 	return nil
 }
 
-// UpdateTimeEntry updates a time entry description or tags
-func (s *Service) UpdateTimeEntry(userID, timeEntryID, description string, tags []string) error {
-	entry, err := s.repo.Get(timeEntryID)
+// UpdateTimeEntry updates a time entry description or tags. Members may only
+// update their own entries.
+func (s *Service) UpdateTimeEntry(actor types.Actor, timeEntryID, description string, tags []string) error {
+	if err := actor.RequireRead(); err != nil {
+		return err
+	}
+	entry, err := s.repo.Get(actor.WorkspaceID, timeEntryID)
 	if err != nil {
 		return fmt.Errorf("failed to get time entry: %w", err)
 	}
-	if entry == nil || entry.UserID != userID {
+	if entry == nil {
 		return fmt.Errorf("time entry not found")
 	}
+	if err := actor.RequireEdit(entry.UserID); err != nil {
+		return err
+	}
 
 	entry.Description = description
 	entry.Tags = tags
@@ -321,7 +362,8 @@ func (s *Service) UpdateTimeEntry(userID, timeEntryID, description string, tags
 	// Publish update event
 	event := types.NewEvent("session_updated", "time_service", map[string]any{
 		"time_entry_id": timeEntryID,
-		"user_id":       userID,
+		"user_id":       actor.UserID,
+		"workspace_id":  actor.WorkspaceID,
 		"description":   description,
 		"tags":          tags,
 		"updated_at":    entry.UpdatedAt,
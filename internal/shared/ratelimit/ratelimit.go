@@ -0,0 +1,115 @@
+// Package ratelimit throttles HTTP requests per client IP with token buckets.
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Config describes one route group's limit: Requests per Per on average,
+// with bursts of up to Burst requests
+type Config struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// Limiter keeps a token bucket per client IP. Buckets idle for longer than
+// it takes to refill are dropped.
+type Limiter struct {
+	config    Config
+	limit     rate.Limit
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func New(config Config) *Limiter {
+	if config.Burst <= 0 {
+		config.Burst = config.Requests
+	}
+	return &Limiter{
+		config:    config,
+		limit:     rate.Limit(float64(config.Requests) / config.Per.Seconds()),
+		clients:   make(map[string]*client),
+		lastSweep: time.Now(),
+	}
+}
+
+// Wrap rejects requests over the limit with 429 and a Retry-After header
+func (l *Limiter) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed, retryAfter := l.Allow(clientIP(r))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// Allow takes a token for key, or reports how long until one is available
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	l.sweep(now)
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(l.limit, l.config.Burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	l.mu.Unlock()
+
+	reservation := c.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, l.config.Per
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep drops idle buckets at most once a minute; l.mu must be held
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	// A bucket idle this long has refilled, so dropping it loses nothing
+	idle := time.Duration(float64(l.config.Burst) / float64(l.limit) * float64(time.Second))
+	if idle < time.Minute {
+		idle = time.Minute
+	}
+	for key, c := range l.clients {
+		if now.Sub(c.lastSeen) > idle {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// clientIP is the connection's remote address. Forwarding headers are not
+// trusted since any client can set them.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllowPermitsBurstThenRejects(t *testing.T) {
+	l := New(Config{Requests: 1, Per: time.Hour, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("10.0.0.1"); !ok {
+			t.Fatalf("request %d of the burst was rejected", i+1)
+		}
+	}
+	ok, retryAfter := l.Allow("10.0.0.1")
+	if ok {
+		t.Fatal("request over the burst was allowed")
+	}
+	// One token an hour: the wait is close to the full hour
+	if retryAfter <= 59*time.Minute || retryAfter > time.Hour {
+		t.Errorf("retry after = %v, want about an hour", retryAfter)
+	}
+}
+
+func TestAllowRefills(t *testing.T) {
+	l := New(Config{Requests: 100, Per: time.Second, Burst: 1})
+
+	if ok, _ := l.Allow("10.0.0.1"); !ok {
+		t.Fatal("first request was rejected")
+	}
+	if ok, _ := l.Allow("10.0.0.1"); ok {
+		t.Fatal("second request was allowed before the bucket refilled")
+	}
+
+	// A token is added every 10ms
+	time.Sleep(30 * time.Millisecond)
+	if ok, _ := l.Allow("10.0.0.1"); !ok {
+		t.Error("request after the refill was rejected")
+	}
+}
+
+func TestAllowKeepsABucketPerIP(t *testing.T) {
+	l := New(Config{Requests: 1, Per: time.Hour})
+
+	if ok, _ := l.Allow("10.0.0.1"); !ok {
+		t.Fatal("first client was rejected")
+	}
+	if ok, _ := l.Allow("10.0.0.1"); ok {
+		t.Fatal("first client was allowed over its limit")
+	}
+	if ok, _ := l.Allow("10.0.0.2"); !ok {
+		t.Error("second client was rejected by the first client's bucket")
+	}
+}
+
+func TestWrapRejectsWith429AndRetryAfter(t *testing.T) {
+	l := New(Config{Requests: 1, Per: time.Minute})
+	handler := l.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := request("192.0.2.1:1234"); rec.Code != http.StatusNoContent {
+		t.Fatalf("first request: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	// Another port on the same host shares the bucket
+	rec := request("192.0.2.1:5678")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+
+	if rec := request("192.0.2.2:1234"); rec.Code != http.StatusNoContent {
+		t.Errorf("other IP: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
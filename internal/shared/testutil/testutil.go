@@ -0,0 +1,87 @@
+// Package testutil provides in-memory stand-ins for the database and event
+// bus so module services can be tested without disk or NATS.
+package testutil
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"datastar-go/internal/shared/types"
+)
+
+// MemoryDB opens an in-memory Badger database closed when the test ends
+func MemoryDB(t testing.TB) *badger.DB {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open in-memory database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// EventBus records published events instead of delivering them
+type EventBus struct {
+	mu       sync.Mutex
+	subjects []string
+}
+
+func (b *EventBus) Publish(subject string, event *types.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subjects = append(b.subjects, subject)
+	return nil
+}
+
+func (b *EventBus) Subscribe(subject string, handler types.EventHandler) error {
+	return nil
+}
+
+func (b *EventBus) SubscribeQueue(subject, queue string, handler types.EventHandler) error {
+	return nil
+}
+
+func (b *EventBus) Close() error {
+	return nil
+}
+
+// Published reports how many events were published to subject
+func (b *EventBus) Published(subject string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := 0
+	for _, published := range b.subjects {
+		if published == subject {
+			count++
+		}
+	}
+	return count
+}
+
+// Publisher records events published by the auth module, which publishes
+// plain payloads rather than types.Event
+type Publisher struct {
+	mu     sync.Mutex
+	events map[string][]any
+}
+
+func (p *Publisher) Publish(event string, data any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.events == nil {
+		p.events = make(map[string][]any)
+	}
+	p.events[event] = append(p.events[event], data)
+	return nil
+}
+
+// Published returns the payloads published as event
+func (p *Publisher) Published(event string) []any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.events[event]
+}
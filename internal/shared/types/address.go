@@ -0,0 +1,224 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidAddress = errors.New("invalid postal address")
+	ErrInvalidVATID   = errors.New("invalid VAT ID")
+)
+
+// PostalAddress is a structured address usable for e-invoicing
+type PostalAddress struct {
+	Street     string `json:"street"`
+	Street2    string `json:"street2,omitempty"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Region     string `json:"region,omitempty"` // state, province or county
+	Country    string `json:"country"`          // ISO 3166-1 alpha-2
+}
+
+// BusinessIdentifiers are the registration and tax numbers of a business
+type BusinessIdentifiers struct {
+	VATID              string `json:"vat_id,omitempty"`              // with country prefix, e.g. DE123456789
+	RegistrationNumber string `json:"registration_number,omitempty"` // company register, e.g. HRB 12345
+	TaxID              string `json:"tax_id,omitempty"`              // national tax number, e.g. US EIN
+}
+
+// Normalize trims fields and upper-cases the country code
+func (a *PostalAddress) Normalize() {
+	a.Street = strings.TrimSpace(a.Street)
+	a.Street2 = strings.TrimSpace(a.Street2)
+	a.City = strings.TrimSpace(a.City)
+	a.PostalCode = strings.TrimSpace(a.PostalCode)
+	a.Region = strings.TrimSpace(a.Region)
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+}
+
+// Validate checks the required fields and the country code
+func (a *PostalAddress) Validate() error {
+	if a.Street == "" || a.City == "" {
+		return fmt.Errorf("%w: street and city are required", ErrInvalidAddress)
+	}
+	if !IsCountryCode(a.Country) {
+		return fmt.Errorf("%w: %q is not an ISO 3166-1 alpha-2 country code", ErrInvalidAddress, a.Country)
+	}
+	return nil
+}
+
+// Format renders the address on one line
+func (a *PostalAddress) Format() string {
+	cityLine := strings.TrimSpace(a.PostalCode + " " + a.City)
+	parts := []string{a.Street, a.Street2, cityLine, a.Region, a.Country}
+
+	var out []string
+	for _, part := range parts {
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// Normalize strips the separators people type into identifiers
+func (b *BusinessIdentifiers) Normalize() {
+	b.VATID = NormalizeVATID(b.VATID)
+	b.RegistrationNumber = strings.TrimSpace(b.RegistrationNumber)
+	b.TaxID = strings.TrimSpace(b.TaxID)
+}
+
+// Validate checks the VAT ID format for the given country of establishment.
+// Without a country, the country is taken from the VAT ID prefix.
+func (b *BusinessIdentifiers) Validate(country string) error {
+	if b.VATID == "" {
+		return nil
+	}
+	if country == "" {
+		country = vatCountry(b.VATID)
+	}
+	return ValidateVATID(b.VATID, country)
+}
+
+// vatCountry derives the ISO country code from a VAT ID prefix
+func vatCountry(vatID string) string {
+	if len(vatID) < 2 {
+		return ""
+	}
+	switch prefix := vatID[:2]; prefix {
+	case "EL":
+		return "GR"
+	case "XI":
+		return "GB"
+	default:
+		return prefix
+	}
+}
+
+// vatPatterns match the part of a VAT ID after the prefix, keyed by the
+// prefix. Greece uses EL rather than its ISO code.
+var vatPatterns = map[string]*regexp.Regexp{
+	"AT":  regexp.MustCompile(`^U\d{8}$`),
+	"BE":  regexp.MustCompile(`^[01]\d{9}$`),
+	"BG":  regexp.MustCompile(`^\d{9,10}$`),
+	"CY":  regexp.MustCompile(`^\d{8}[A-Z]$`),
+	"CZ":  regexp.MustCompile(`^\d{8,10}$`),
+	"DE":  regexp.MustCompile(`^\d{9}$`),
+	"DK":  regexp.MustCompile(`^\d{8}$`),
+	"EE":  regexp.MustCompile(`^\d{9}$`),
+	"EL":  regexp.MustCompile(`^\d{9}$`),
+	"ES":  regexp.MustCompile(`^[A-Z0-9]\d{7}[A-Z0-9]$`),
+	"FI":  regexp.MustCompile(`^\d{8}$`),
+	"FR":  regexp.MustCompile(`^[A-HJ-NP-Z0-9]{2}\d{9}$`),
+	"HR":  regexp.MustCompile(`^\d{11}$`),
+	"HU":  regexp.MustCompile(`^\d{8}$`),
+	"IE":  regexp.MustCompile(`^(\d{7}[A-W][A-I]?|\d[A-Z+*]\d{5}[A-W])$`),
+	"IT":  regexp.MustCompile(`^\d{11}$`),
+	"LT":  regexp.MustCompile(`^(\d{9}|\d{12})$`),
+	"LU":  regexp.MustCompile(`^\d{8}$`),
+	"LV":  regexp.MustCompile(`^\d{11}$`),
+	"MT":  regexp.MustCompile(`^\d{8}$`),
+	"NL":  regexp.MustCompile(`^\d{9}B\d{2}$`),
+	"PL":  regexp.MustCompile(`^\d{10}$`),
+	"PT":  regexp.MustCompile(`^\d{9}$`),
+	"RO":  regexp.MustCompile(`^[1-9]\d{1,9}$`),
+	"SE":  regexp.MustCompile(`^\d{10}01$`),
+	"SI":  regexp.MustCompile(`^\d{8}$`),
+	"SK":  regexp.MustCompile(`^\d{10}$`),
+	"XI":  regexp.MustCompile(`^(\d{9}|\d{12}|GD\d{3}|HA\d{3})$`),
+	"GB":  regexp.MustCompile(`^(\d{9}|\d{12}|GD\d{3}|HA\d{3})$`),
+	"CHE": regexp.MustCompile(`^\d{9}(MWST|TVA|IVA)?$`),
+	"NO":  regexp.MustCompile(`^\d{9}(MVA)?$`),
+}
+
+// vatPrefixes maps countries whose VAT prefix differs from their ISO code
+var vatPrefixes = map[string]string{
+	"GR": "EL",
+	"CH": "CHE",
+}
+
+// NormalizeVATID upper-cases a VAT ID and removes spaces, dots and dashes
+func NormalizeVATID(vatID string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '.', '-', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(vatID)))
+}
+
+// ValidateVATID checks a VAT ID's prefix and format for the given country.
+// Countries without a known format only need a matching prefix.
+func ValidateVATID(vatID, country string) error {
+	vatID = NormalizeVATID(vatID)
+	country = strings.ToUpper(country)
+	if !IsCountryCode(country) {
+		return fmt.Errorf("%w: unknown country %q", ErrInvalidVATID, country)
+	}
+
+	prefix := country
+	if p, ok := vatPrefixes[country]; ok {
+		prefix = p
+	}
+	if country == "GB" && strings.HasPrefix(vatID, "XI") {
+		prefix = "XI" // Northern Ireland
+	}
+
+	if !strings.HasPrefix(vatID, prefix) {
+		return fmt.Errorf("%w: %s VAT IDs start with %s", ErrInvalidVATID, country, prefix)
+	}
+
+	pattern, ok := vatPatterns[prefix]
+	if !ok {
+		if len(vatID) <= len(prefix) {
+			return fmt.Errorf("%w: number missing after %s", ErrInvalidVATID, prefix)
+		}
+		return nil
+	}
+	if !pattern.MatchString(vatID[len(prefix):]) {
+		return fmt.Errorf("%w: %s does not match the %s format", ErrInvalidVATID, vatID, country)
+	}
+	return nil
+}
+
+// IsCountryCode reports whether code is an assigned ISO 3166-1 alpha-2 code
+func IsCountryCode(code string) bool {
+	return countryCodes[code]
+}
+
+var countryCodes = func() map[string]bool {
+	codes := map[string]bool{}
+	for _, code := range strings.Fields(`
+		AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ
+		BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+		CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ
+		DE DJ DK DM DO DZ
+		EC EE EG EH ER ES ET
+		FI FJ FK FM FO FR
+		GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY
+		HK HM HN HR HT HU
+		ID IE IL IM IN IO IQ IR IS IT
+		JE JM JO JP
+		KE KG KH KI KM KN KP KR KW KY KZ
+		LA LB LC LI LK LR LS LT LU LV LY
+		MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ
+		NA NC NE NF NG NI NL NO NP NR NU NZ
+		OM
+		PA PE PF PG PH PK PL PM PN PR PS PT PW PY
+		QA
+		RE RO RS RU RW
+		SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ
+		TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ
+		UA UG UM US UY UZ
+		VA VC VE VG VI VN VU
+		WF WS
+		YE YT
+		ZA ZM ZW`) {
+		codes[code] = true
+	}
+	return codes
+}()
@@ -62,3 +62,14 @@ type EventBus interface {
 	SubscribeQueue(subject, queue string, handler EventHandler) error
 	Close() error
 }
+
+// WorkspaceID returns the workspace the event's data belongs to. Events
+// published before workspaces existed only carry the user ID, which is the
+// ID of the user's personal workspace.
+func (e *Event) WorkspaceID() string {
+	if workspaceID, ok := e.Data["workspace_id"].(string); ok && workspaceID != "" {
+		return workspaceID
+	}
+	userID, _ := e.Data["user_id"].(string)
+	return userID
+}
@@ -18,41 +18,182 @@ type User struct {
 
 // Client represents a client/customer
 type Client struct {
-	ID         string    `json:"id"`
-	UserID     string    `json:"user_id"`
-	Name       string    `json:"name"`
-	Email      string    `json:"email"`
-	Company    string    `json:"company"`
-	Phone      string    `json:"phone"`
-	HourlyRate float64   `json:"hourly_rate"`
-	Currency   string    `json:"currency"`
-	Address    string    `json:"address"`
-	Notes      string    `json:"notes"`
-	IsActive   bool      `json:"is_active"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID            string               `json:"id"`
+	UserID        string               `json:"user_id"`
+	WorkspaceID   string               `json:"workspace_id,omitempty"`
+	Name          string               `json:"name"`
+	Email         string               `json:"email"`
+	Company       string               `json:"company"`
+	Phone         string               `json:"phone"`
+	HourlyRate    float64              `json:"hourly_rate"`
+	ExpenseMarkup float64              `json:"expense_markup"` // percent added to rebilled expenses
+	Currency      string               `json:"currency"`
+	Address       string               `json:"address"` // one-line form of PostalAddress when set
+	PostalAddress *PostalAddress       `json:"postal_address,omitempty"`
+	Identifiers   *BusinessIdentifiers `json:"identifiers,omitempty"`
+	Notes         string               `json:"notes"`
+	IsActive      bool                 `json:"is_active"`
+	ArchivedAt    *time.Time           `json:"archived_at,omitempty"`
+	DeletedAt     *time.Time           `json:"deleted_at,omitempty"` // soft-deleted, hidden everywhere
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// IsArchived reports whether the client is archived or soft-deleted
+func (c *Client) IsArchived() bool {
+	return c.ArchivedAt != nil || c.DeletedAt != nil
+}
+
+// BusinessProfile describes the user's own business as it appears on invoices
+type BusinessProfile struct {
+	UserID      string              `json:"user_id"`
+	WorkspaceID string              `json:"workspace_id,omitempty"`
+	LegalName   string              `json:"legal_name"`
+	TradingName string              `json:"trading_name,omitempty"`
+	Email       string              `json:"email,omitempty"`
+	Phone       string              `json:"phone,omitempty"`
+	Address     PostalAddress       `json:"address"`
+	Identifiers BusinessIdentifiers `json:"identifiers"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// Contact is a person at a client, e.g. accounts payable or the project lead
+type Contact struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	WorkspaceID string    `json:"workspace_id,omitempty"`
+	ClientID    string    `json:"client_id"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	Phone       string    `json:"phone,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Roles       []string  `json:"roles"` // primary, billing, project_lead, cc
+	Notes       string    `json:"notes,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Contact roles
+const (
+	ContactRolePrimary     = "primary"
+	ContactRoleBilling     = "billing"
+	ContactRoleProjectLead = "project_lead"
+	ContactRoleCC          = "cc"
+)
+
+// HasRole reports whether the contact has the given role
+func (c *Contact) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// InvoiceRecipients are the addresses an invoice is delivered to
+type InvoiceRecipients struct {
+	To []string `json:"to"`
+	CC []string `json:"cc,omitempty"`
 }
 
 // Project represents a project for a client
 type Project struct {
+	ID          string          `json:"id"`
+	UserID      string          `json:"user_id"`
+	WorkspaceID string          `json:"workspace_id,omitempty"`
+	ClientID    string          `json:"client_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	HourlyRate  float64         `json:"hourly_rate"`
+	Currency    string          `json:"currency"`
+	Status      string          `json:"status"` // planned, active, paused, completed, cancelled
+	StartDate   time.Time       `json:"start_date"`
+	EndDate     *time.Time      `json:"end_date,omitempty"`
+	Budget      *ProjectBudget  `json:"budget,omitempty"`
+	Billing     *ProjectBilling `json:"billing,omitempty"` // nil bills hourly
+	ArchivedAt  *time.Time      `json:"archived_at,omitempty"`
+	DeletedAt   *time.Time      `json:"deleted_at,omitempty"` // soft-deleted, hidden everywhere
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// IsArchived reports whether the project is archived or soft-deleted
+func (p *Project) IsArchived() bool {
+	return p.ArchivedAt != nil || p.DeletedAt != nil
+}
+
+// Project lifecycle statuses
+const (
+	ProjectStatusPlanned   = "planned"
+	ProjectStatusActive    = "active"
+	ProjectStatusPaused    = "paused"
+	ProjectStatusCompleted = "completed"
+	ProjectStatusCancelled = "cancelled"
+)
+
+// Milestone is a deliverable of a project with an optional fixed fee that is
+// invoiced on its own
+type Milestone struct {
 	ID          string     `json:"id"`
 	UserID      string     `json:"user_id"`
+	WorkspaceID string     `json:"workspace_id,omitempty"`
 	ClientID    string     `json:"client_id"`
+	ProjectID   string     `json:"project_id"`
 	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	HourlyRate  float64    `json:"hourly_rate"`
-	Currency    string     `json:"currency"`
-	Status      string     `json:"status"` // active, paused, completed
-	StartDate   time.Time  `json:"start_date"`
-	EndDate     *time.Time `json:"end_date,omitempty"`
+	Description string     `json:"description,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Amount      float64    `json:"amount"` // fixed fee, 0 if not billed separately
+	Status      string     `json:"status"` // pending, completed
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	InvoiceID   string     `json:"invoice_id,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
+// Milestone statuses
+const (
+	MilestoneStatusPending   = "pending"
+	MilestoneStatusCompleted = "completed"
+)
+
+// ProjectBudget caps the hours and/or money a project may burn. A zero limit
+// is not tracked.
+type ProjectBudget struct {
+	Hours  float64 `json:"hours,omitempty"`
+	Amount float64 `json:"amount,omitempty"` // time value plus expenses, in the project currency
+	Period string  `json:"period"`           // total, monthly
+}
+
+// ProjectBilling is how a project's work is charged
+type ProjectBilling struct {
+	Model           string  `json:"model"`                      // hourly, fixed_fee, capped_hourly, retainer
+	FixedFee        float64 `json:"fixed_fee,omitempty"`        // fixed_fee: the contract price
+	PercentComplete float64 `json:"percent_complete,omitempty"` // fixed_fee: share of the fee earned so far, 0-100
+	Cap             float64 `json:"cap,omitempty"`              // capped_hourly: not-to-exceed amount
+	RetainerFee     float64 `json:"retainer_fee,omitempty"`     // retainer: charged once per month
+	RetainerHours   float64 `json:"retainer_hours,omitempty"`   // retainer: hours included each month
+}
+
+// Billing models
+const (
+	BillingModelHourly       = "hourly"
+	BillingModelFixedFee     = "fixed_fee"
+	BillingModelCappedHourly = "capped_hourly"
+	BillingModelRetainer     = "retainer"
+)
+
+// Budget periods
+const (
+	BudgetPeriodTotal   = "total"
+	BudgetPeriodMonthly = "monthly"
+)
+
 // TimeEntry represents a time tracking entry
 type TimeEntry struct {
 	ID          string     `json:"id"`
 	UserID      string     `json:"user_id"`
+	WorkspaceID string     `json:"workspace_id,omitempty"`
 	ProjectID   string     `json:"project_id"`
 	Description string     `json:"description"`
 	StartTime   time.Time  `json:"start_time"`
@@ -60,6 +201,7 @@ type TimeEntry struct {
 	Duration    int64      `json:"duration"` // seconds
 	IsRunning   bool       `json:"is_running"`
 	IsBilled    bool       `json:"is_billed"`
+	InvoiceID   string     `json:"invoice_id,omitempty"` // set while IsBilled
 	HourlyRate  float64    `json:"hourly_rate"`
 	Tags        []string   `json:"tags"`
 	CreatedAt   time.Time  `json:"created_at"`
@@ -68,55 +210,147 @@ type TimeEntry struct {
 
 // Expense represents an expense entry
 type Expense struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	ProjectID   string    `json:"project_id,omitempty"`
-	Amount      float64   `json:"amount"`
-	Currency    string    `json:"currency"`
-	Category    string    `json:"category"`
-	Description string    `json:"description"`
-	Date        time.Time `json:"date"`
-	Receipt     string    `json:"receipt,omitempty"` // file path/URL
-	IsBillable  bool      `json:"is_billable"`
-	IsBilled    bool      `json:"is_billed"`
-	TaxCategory string    `json:"tax_category"`
-	Notes       string    `json:"notes"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string       `json:"id"`
+	UserID      string       `json:"user_id"`
+	WorkspaceID string       `json:"workspace_id,omitempty"`
+	Type        ExpenseType  `json:"type,omitempty"` // empty means standard
+	ProjectID   string       `json:"project_id,omitempty"`
+	Amount      float64      `json:"amount"`
+	Currency    string       `json:"currency"`
+	Category    string       `json:"category"`
+	Description string       `json:"description"`
+	Payee       string       `json:"payee,omitempty"`
+	Account     string       `json:"account,omitempty"`
+	Reference   string       `json:"reference,omitempty"` // bank transaction reference
+	Date        time.Time    `json:"date"`
+	Receipt     string       `json:"receipt,omitempty"` // file path/URL
+	ReceiptFile *ReceiptFile `json:"receipt_file,omitempty"`
+	IsBillable  bool         `json:"is_billable"`
+	IsBilled    bool         `json:"is_billed"`
+	InvoiceID   string       `json:"invoice_id,omitempty"` // set while IsBilled
+	RecurringID string       `json:"recurring_id,omitempty"`
+	TaxCategory string       `json:"tax_category"`
+	Notes       string       `json:"notes"`
+	Mileage     *Mileage     `json:"mileage,omitempty"`
+	PerDiem     *PerDiem     `json:"per_diem,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// ExpenseType distinguishes manually entered expenses from ones computed from rate tables
+type ExpenseType string
+
+const (
+	ExpenseTypeStandard ExpenseType = "standard"
+	ExpenseTypeMileage  ExpenseType = "mileage"
+	ExpenseTypePerDiem  ExpenseType = "per_diem"
+)
+
+// Mileage holds the trip details of a mileage expense; Amount = Distance * Rate
+type Mileage struct {
+	Distance     float64 `json:"distance"`
+	Unit         string  `json:"unit"` // km, mi
+	Vehicle      string  `json:"vehicle"`
+	Jurisdiction string  `json:"jurisdiction"`
+	Year         int     `json:"year"`
+	Rate         float64 `json:"rate"` // per unit, in the expense currency
+	RateSource   string  `json:"rate_source,omitempty"`
+	Origin       string  `json:"origin,omitempty"`
+	Destination  string  `json:"destination,omitempty"`
+	RoundTrip    bool    `json:"round_trip"`
+}
+
+// PerDiem holds the travel details of a per-diem expense;
+// Amount = FullDays * FullDayRate + PartialDays * PartialDayRate
+type PerDiem struct {
+	Location       string  `json:"location"`
+	Jurisdiction   string  `json:"jurisdiction"`
+	Year           int     `json:"year"`
+	FullDays       int     `json:"full_days"`
+	PartialDays    int     `json:"partial_days"` // travel days, e.g. arrival and departure
+	FullDayRate    float64 `json:"full_day_rate"`
+	PartialDayRate float64 `json:"partial_day_rate"`
+	RateSource     string  `json:"rate_source,omitempty"`
+}
+
+// ReceiptFile describes a receipt stored in the content-addressed blob store
+type ReceiptFile struct {
+	Hash          string    `json:"hash"`
+	FileName      string    `json:"file_name"`
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	ThumbnailHash string    `json:"thumbnail_hash,omitempty"`
+	UploadedAt    time.Time `json:"uploaded_at"`
 }
 
 // InvoiceItem represents a line item on an invoice
 type InvoiceItem struct {
+	Section     string  `json:"section,omitempty"` // services, expenses
 	Description string  `json:"description"`
 	Quantity    float64 `json:"quantity"`
 	Rate        float64 `json:"rate"`
 	Amount      float64 `json:"amount"`
+	ExpenseID   string  `json:"expense_id,omitempty"`
+	TimeEntryID string  `json:"time_entry_id,omitempty"`
+	MilestoneID string  `json:"milestone_id,omitempty"`
+	Period      string  `json:"period,omitempty"`  // retainer month, e.g. 2025-03
+	Markup      float64 `json:"markup,omitempty"`  // percent applied to the expense amount
+	Receipt     string  `json:"receipt,omitempty"` // receipt URL of the rebilled expense
 }
 
+// Invoice item sections
+const (
+	InvoiceSectionRetainer = "retainer"
+	InvoiceSectionServices = "services"
+	InvoiceSectionExpenses = "expenses"
+)
+
 // Invoice represents an invoice
 type Invoice struct {
-	ID          string        `json:"id"`
-	UserID      string        `json:"user_id"`
-	ClientID    string        `json:"client_id"`
-	ProjectID   string        `json:"project_id,omitempty"`
-	Number      string        `json:"number"`
-	Title       string        `json:"title"`
-	Description string        `json:"description"`
-	Status      string        `json:"status"` // draft, sent, paid, overdue
-	Items       []InvoiceItem `json:"items"`
-	Amount      float64       `json:"amount"`
-	Currency    string        `json:"currency"`
-	TaxRate     float64       `json:"tax_rate"`
-	TaxAmount   float64       `json:"tax_amount"`
-	TotalAmount float64       `json:"total_amount"`
-	IssueDate   time.Time     `json:"issue_date"`
-	DueDate     time.Time     `json:"due_date"`
-	SentAt      *time.Time    `json:"sent_at,omitempty"`
-	PaidAt      *time.Time    `json:"paid_at,omitempty"`
-	TimeEntries []TimeEntry   `json:"time_entries,omitempty"`
-	Expenses    []Expense     `json:"expenses,omitempty"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
+	ID          string             `json:"id"`
+	UserID      string             `json:"user_id"`
+	WorkspaceID string             `json:"workspace_id,omitempty"`
+	ClientID    string             `json:"client_id"`
+	ProjectID   string             `json:"project_id,omitempty"`
+	Number      string             `json:"number"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Status      string             `json:"status"` // draft, sent, overdue, paid, cancelled, void
+	Items       []InvoiceItem      `json:"items"`
+	Amount      float64            `json:"amount"`
+	Currency    string             `json:"currency"`
+	TaxRate     float64            `json:"tax_rate"`
+	TaxAmount   float64            `json:"tax_amount"`
+	TotalAmount float64            `json:"total_amount"`
+	IssueDate   time.Time          `json:"issue_date"`
+	DueDate     time.Time          `json:"due_date"`
+	SentAt      *time.Time         `json:"sent_at,omitempty"`
+	SentTo      *InvoiceRecipients `json:"sent_to,omitempty"`
+	PaidAt      *time.Time         `json:"paid_at,omitempty"`
+	WriteOff    *WriteOff          `json:"write_off,omitempty"`
+	TimeEntries []TimeEntry        `json:"time_entries,omitempty"`
+	Expenses    []Expense          `json:"expenses,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// Invoice statuses. Drafts are cancelled and issued invoices are voided; both
+// release what the invoice billed.
+const (
+	InvoiceStatusDraft     = "draft"
+	InvoiceStatusSent      = "sent"
+	InvoiceStatusOverdue   = "overdue"
+	InvoiceStatusPaid      = "paid"
+	InvoiceStatusCancelled = "cancelled"
+	InvoiceStatusVoid      = "void"
+)
+
+// WriteOff records tracked hours an invoice did not charge, e.g. because the
+// project's not-to-exceed cap was reached
+type WriteOff struct {
+	Hours  float64 `json:"hours"`
+	Amount float64 `json:"amount"` // value of the hours at the hourly rate
+	Reason string  `json:"reason"`
 }
 
 // APIResponse represents a standard API response
@@ -0,0 +1,35 @@
+package types
+
+import "encoding/json"
+
+// Optional is a field of a JSON Merge Patch (RFC 7396) document. It tells
+// apart a key that is absent (leave unchanged), present with null (clear)
+// and present with a value (replace).
+type Optional[T any] struct {
+	Set   bool // key was present in the patch
+	Null  bool // key was present with a null value
+	Value T
+}
+
+// UnmarshalJSON is only called for keys present in the document
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// MarshalJSON writes null for cleared or unset fields
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Set || o.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+// HasValue reports whether the patch sets a non-null value
+func (o Optional[T]) HasValue() bool {
+	return o.Set && !o.Null
+}
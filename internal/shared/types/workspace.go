@@ -0,0 +1,128 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrForbidden is returned when the actor's workspace role does not allow an
+// operation
+var ErrForbidden = errors.New("forbidden")
+
+// Workspace roles. Owners and admins manage the workspace and its billing
+// setup, members do day-to-day work, accountants only read.
+const (
+	RoleOwner      = "owner"
+	RoleAdmin      = "admin"
+	RoleMember     = "member"
+	RoleAccountant = "accountant"
+)
+
+// IsRole reports whether role is one of the workspace roles
+func IsRole(role string) bool {
+	switch role {
+	case RoleOwner, RoleAdmin, RoleMember, RoleAccountant:
+		return true
+	}
+	return false
+}
+
+// Actor is a user acting on the data of one workspace with their role in it.
+// Every user has a personal workspace whose ID is their user ID, so records
+// stored before workspaces existed belong to it.
+type Actor struct {
+	UserID      string `json:"user_id"`
+	WorkspaceID string `json:"workspace_id"`
+	Role        string `json:"role"`
+}
+
+// PersonalActor is the user as owner of their personal workspace
+func PersonalActor(userID string) Actor {
+	return Actor{UserID: userID, WorkspaceID: userID, Role: RoleOwner}
+}
+
+// CanRead reports whether the actor may see the workspace's data
+func (a Actor) CanRead() bool {
+	return a.UserID != "" && a.WorkspaceID != "" && IsRole(a.Role)
+}
+
+// CanWrite reports whether the actor may create and change records
+func (a Actor) CanWrite() bool {
+	return a.CanRead() && a.Role != RoleAccountant
+}
+
+// CanManage reports whether the actor may delete and archive shared records,
+// bill clients and change workspace settings
+func (a Actor) CanManage() bool {
+	return a.CanRead() && (a.Role == RoleOwner || a.Role == RoleAdmin)
+}
+
+// CanEdit reports whether the actor may change a record created by ownerID.
+// Members may only change their own time and expenses.
+func (a Actor) CanEdit(ownerID string) bool {
+	return a.CanManage() || (a.CanWrite() && ownerID == a.UserID)
+}
+
+// RequireRead fails with ErrForbidden unless the actor may read
+func (a Actor) RequireRead() error {
+	if !a.CanRead() {
+		return fmt.Errorf("%w: not a member of this workspace", ErrForbidden)
+	}
+	return nil
+}
+
+// RequireWrite fails with ErrForbidden unless the actor may write
+func (a Actor) RequireWrite() error {
+	if !a.CanWrite() {
+		return fmt.Errorf("%w: the %s role is read-only", ErrForbidden, a.Role)
+	}
+	return nil
+}
+
+// RequireManage fails with ErrForbidden unless the actor is an owner or admin
+func (a Actor) RequireManage() error {
+	if !a.CanManage() {
+		return fmt.Errorf("%w: requires the owner or admin role", ErrForbidden)
+	}
+	return nil
+}
+
+// RequireEdit fails with ErrForbidden unless the actor may change a record
+// created by ownerID
+func (a Actor) RequireEdit(ownerID string) error {
+	if err := a.RequireWrite(); err != nil {
+		return err
+	}
+	if !a.CanEdit(ownerID) {
+		return fmt.Errorf("%w: only owners and admins may change another member's records", ErrForbidden)
+	}
+	return nil
+}
+
+// InWorkspace reports whether a record stored with the given workspace and
+// user IDs belongs to workspaceID. Records without a workspace ID predate
+// workspaces and belong to their user's personal workspace.
+func InWorkspace(recordWorkspaceID, recordUserID, workspaceID string) bool {
+	if recordWorkspaceID == "" {
+		return recordUserID == workspaceID
+	}
+	return recordWorkspaceID == workspaceID
+}
+
+// WorkspaceOf returns the workspace of a record stored with the given
+// workspace and user IDs
+func WorkspaceOf(recordWorkspaceID, recordUserID string) string {
+	if recordWorkspaceID == "" {
+		return recordUserID
+	}
+	return recordWorkspaceID
+}
+
+// SystemActor acts on a workspace's data for background work such as event
+// handlers and schedulers, which run outside any user's request
+func SystemActor(workspaceID string) Actor {
+	return Actor{UserID: SystemUserID, WorkspaceID: workspaceID, Role: RoleOwner}
+}
+
+// SystemUserID identifies the SystemActor
+const SystemUserID = "system"
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestActorRoleMatrix(t *testing.T) {
+	const self, other = "user-1", "user-2"
+	checks := []struct {
+		name  string
+		check func(Actor) error
+	}{
+		{"RequireRead", Actor.RequireRead},
+		{"RequireWrite", Actor.RequireWrite},
+		{"RequireManage", Actor.RequireManage},
+		{"RequireEdit own", func(a Actor) error { return a.RequireEdit(self) }},
+		{"RequireEdit other", func(a Actor) error { return a.RequireEdit(other) }},
+	}
+	// allowed lists the outcome of each check above, in order
+	roles := []struct {
+		role    string
+		allowed []bool
+	}{
+		{RoleOwner, []bool{true, true, true, true, true}},
+		{RoleAdmin, []bool{true, true, true, true, true}},
+		{RoleMember, []bool{true, true, false, true, false}},
+		{RoleAccountant, []bool{true, false, false, false, false}},
+		{"", []bool{false, false, false, false, false}},
+		{"superuser", []bool{false, false, false, false, false}},
+	}
+
+	for _, row := range roles {
+		actor := Actor{UserID: self, WorkspaceID: "workspace-1", Role: row.role}
+		for i, c := range checks {
+			err := c.check(actor)
+			if row.allowed[i] && err != nil {
+				t.Errorf("%q %s: %v", row.role, c.name, err)
+			}
+			if !row.allowed[i] && !errors.Is(err, ErrForbidden) {
+				t.Errorf("%q %s: err = %v, want ErrForbidden", row.role, c.name, err)
+			}
+		}
+	}
+}
+
+func TestActorWithoutWorkspaceCannotRead(t *testing.T) {
+	for _, actor := range []Actor{
+		{},
+		{UserID: "user-1", Role: RoleOwner},
+		{WorkspaceID: "workspace-1", Role: RoleOwner},
+	} {
+		if err := actor.RequireRead(); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%+v: err = %v, want ErrForbidden", actor, err)
+		}
+	}
+}
+
+func TestInWorkspace(t *testing.T) {
+	cases := []struct {
+		recordWorkspace, recordUser, workspace string
+		want                                   bool
+	}{
+		{"team", "user-1", "team", true},
+		{"team", "user-1", "user-1", false},
+		{"", "user-1", "user-1", true},
+		{"", "user-1", "team", false},
+	}
+	for _, tc := range cases {
+		if got := InWorkspace(tc.recordWorkspace, tc.recordUser, tc.workspace); got != tc.want {
+			t.Errorf("InWorkspace(%q, %q, %q) = %v, want %v", tc.recordWorkspace, tc.recordUser, tc.workspace, got, tc.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package web
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"datastar-go/internal/modules/auth"
 	"datastar-go/internal/modules/client"
@@ -43,6 +44,7 @@ func (h *Handlers) SetupRoutes(mux *http.ServeMux) {
 
 	// API routes for Datastar
 	mux.HandleFunc("/api/dashboard/stats", h.DashboardStats)
+	mux.HandleFunc("/api/dashboard/aging", h.DashboardAging)
 	
 	// Client API routes (frontend compatibility)
 	mux.HandleFunc("/api/clients", h.GetClients)
@@ -121,26 +123,26 @@ func (h *Handlers) DashboardStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := auth.GetUserID(r)
-	if userID == "" {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
 		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 	
 	// Get actual counts from services
-	clients, err := h.clientService.GetClients(userID)
+	clients, err := h.clientService.GetClients(actor)
 	clientsCount := 0
 	if err == nil {
 		clientsCount = len(clients)
 	}
 	
-	invoices, err := h.invoiceService.GetInvoices(userID)
+	invoices, err := h.invoiceService.GetInvoices(actor)
 	invoicesCount := 0
 	if err == nil {
 		invoicesCount = len(invoices)
 	}
 	
-	expenses, err := h.expenseService.GetExpenses(userID)
+	expenses, err := h.expenseService.GetExpenses(actor)
 	expensesCount := 0
 	if err == nil {
 		expensesCount = len(expenses)
@@ -148,7 +150,7 @@ func (h *Handlers) DashboardStats(w http.ResponseWriter, r *http.Request) {
 	
 	// Check for active timers
 	activeTimers := 0
-	if h.timeService.GetActiveTimer(userID) != nil {
+	if h.timeService.GetActiveTimer(actor) != nil {
 		activeTimers = 1
 	}
 
@@ -164,6 +166,29 @@ func (h *Handlers) DashboardStats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(stats)
 }
 
+// DashboardAging provides the accounts receivable aging card
+func (h *Handlers) DashboardAging(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := h.invoiceService.GetAgingReport(actor, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
 // GetClients returns clients data for frontend
 func (h *Handlers) GetClients(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -171,15 +196,15 @@ func (h *Handlers) GetClients(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := auth.GetUserID(r)
-	if userID == "" {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
 		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 	
-	clients, err := h.clientService.GetClients(userID)
+	clients, err := h.clientService.GetClients(actor)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -227,15 +252,15 @@ func (h *Handlers) GetInvoices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := auth.GetUserID(r)
-	if userID == "" {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
 		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 	
-	invoices, err := h.invoiceService.GetInvoices(userID)
+	invoices, err := h.invoiceService.GetInvoices(actor)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -282,15 +307,15 @@ func (h *Handlers) GetExpenses(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := auth.GetUserID(r)
-	if userID == "" {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
 		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 	
-	expenses, err := h.expenseService.GetExpenses(userID)
+	expenses, err := h.expenseService.GetExpenses(actor)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -337,8 +362,8 @@ func (h *Handlers) GetTimeEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := auth.GetUserID(r)
-	if userID == "" {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
 		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
@@ -360,18 +385,18 @@ func (h *Handlers) StartTimer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := auth.GetUserID(r)
-	if userID == "" {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
 		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 	
-	projectID := "default-project"
+	projectID := "" // assigned to a project later
 	description := "Work session"
 	
-	entry, err := h.timeService.StartTimer(userID, projectID, description)
+	entry, err := h.timeService.StartTimer(actor, projectID, description)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -390,15 +415,15 @@ func (h *Handlers) StopTimer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := auth.GetUserID(r)
-	if userID == "" {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
 		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 	
-	entry, err := h.timeService.StopTimer(userID)
+	entry, err := h.timeService.StopTimer(actor)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -417,15 +442,15 @@ func (h *Handlers) PauseTimer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := auth.GetUserID(r)
-	if userID == "" {
+	actor := auth.GetActor(r)
+	if actor.UserID == "" {
 		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 	
-	entry, err := h.timeService.PauseTimer(userID)
+	entry, err := h.timeService.PauseTimer(actor)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
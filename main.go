@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -17,6 +18,7 @@ import (
 	"datastar-go/internal/modules/invoice"
 	timemodule "datastar-go/internal/modules/time"
 	"datastar-go/internal/shared/database"
+	"datastar-go/internal/shared/ratelimit"
 	"datastar-go/internal/shared/types"
 	"datastar-go/internal/web"
 )
@@ -53,16 +55,33 @@ func main() {
 	// Initialize modules
 	log.Println("🏗️  Initializing modular monolith...")
 
-	// Authentication module
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = auth.GenerateSecretKey()
-		log.Println("⚠️  Using generated JWT secret. Set JWT_SECRET env var for production")
-	}
+	// Authentication module. Access tokens are signed with keys stored in the
+	// database; JWT_SECRET only verifies tokens issued before those existed.
 	authRepo := auth.NewRepository(db.DB())
 	// Create event adapter
 	eventAdapter := &EventBusAdapter{bus: eventBus}
-	authService := auth.NewService(authRepo, jwtSecret, eventAdapter)
+	authService := auth.NewService(authRepo, os.Getenv("JWT_SECRET"), eventAdapter)
+	keyConfig, err := signingKeyConfig()
+	if err != nil {
+		log.Fatal("Invalid signing key configuration:", err)
+	}
+	if err := authService.LoadSigningKeys(keyConfig); err != nil {
+		log.Fatal("Failed to load signing keys:", err)
+	}
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:8080"
+	}
+	authService.SetMailSender(auth.NewLogMailSender(), appURL)
+	providers, err := oidcProviders()
+	if err != nil {
+		log.Fatal("Invalid identity provider configuration:", err)
+	}
+	for _, provider := range providers {
+		if err := authService.AddOIDCProvider(provider); err != nil {
+			log.Fatal("Invalid identity provider configuration:", err)
+		}
+	}
 	authHandler := auth.NewHandler(authService)
 	authMiddleware := auth.NewMiddleware(authService)
 
@@ -71,15 +90,27 @@ func main() {
 	timeHandlers := timemodule.NewHandlers(timeService)
 
 	// Expense tracking module
-	expenseService := expense.NewService(eventBus, db.DB())
+	receiptStore, err := expense.NewBlobStore("./data/receipts")
+	if err != nil {
+		log.Fatal("Failed to initialize receipt store:", err)
+	}
+	expenseService := expense.NewService(eventBus, db.DB(), receiptStore)
 	expenseHandlers := expense.NewHandlers(expenseService)
 
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	expenseService.StartRecurringScheduler(schedulerCtx, time.Hour)
+	authService.StartKeyRotation(schedulerCtx)
+
 	// Client & project management module
-	clientService := client.NewService(eventBus, db.DB())
+	clientService := client.NewService(eventBus, db.DB(), timeService, expenseService)
+	timeService.SetProjectSource(clientService)
+	expenseService.SetProjectSource(clientService)
 	clientHandlers := client.NewHandlers(clientService)
 
 	// Invoice generation module
-	invoiceService := invoice.NewService(eventBus, db.DB())
+	invoiceService := invoice.NewService(eventBus, db.DB(), expenseService, clientService, timeService)
+	clientService.SetInvoiceSource(invoiceService)
 	invoiceHandlers := invoice.NewHandlers(invoiceService)
 
 	// Web handlers for Templ/Datastar frontend
@@ -88,13 +119,53 @@ func main() {
 	// Set up HTTP routes
 	mux := http.NewServeMux()
 
+	// Rate limits per client IP, by route group
+	credentialLimiter := ratelimit.New(ratelimit.Config{Requests: 10, Per: time.Minute, Burst: 10})
+	apiLimiter := ratelimit.New(ratelimit.Config{Requests: 600, Per: time.Minute, Burst: 120})
+
 	// Auth routes (no middleware)
-	mux.HandleFunc("/api/auth/register", authHandler.Register)
-	mux.HandleFunc("/api/auth/login", authHandler.Login)
-	mux.HandleFunc("/api/auth/logout", authHandler.Logout)
-	mux.HandleFunc("/api/auth/verify", authHandler.VerifyToken)
-	mux.HandleFunc("/api/auth/profile", authHandler.GetProfile)
-	mux.HandleFunc("/api/auth/refresh", authHandler.RefreshToken)
+	mux.HandleFunc("/api/auth/register", credentialLimiter.Wrap(authHandler.Register))
+	mux.HandleFunc("/api/auth/login", credentialLimiter.Wrap(authHandler.Login))
+	mux.HandleFunc("/api/auth/verify", apiLimiter.Wrap(authHandler.VerifyToken))
+	mux.HandleFunc("GET /.well-known/jwks.json", apiLimiter.Wrap(authHandler.JWKS))
+	mux.HandleFunc("/api/auth/refresh", apiLimiter.Wrap(authHandler.RefreshToken))
+	mux.HandleFunc("/api/auth/verify-email", credentialLimiter.Wrap(authHandler.VerifyEmail))
+	mux.HandleFunc("/api/auth/unlock", credentialLimiter.Wrap(authHandler.UnlockAccount))
+	mux.HandleFunc("POST /api/auth/password/forgot", credentialLimiter.Wrap(authHandler.ForgotPassword))
+	mux.HandleFunc("POST /api/auth/password/reset", credentialLimiter.Wrap(authHandler.ResetPassword))
+	mux.HandleFunc("POST /api/auth/login/2fa", credentialLimiter.Wrap(authHandler.CompleteTwoFactorLogin))
+	mux.HandleFunc("GET /api/auth/oidc/providers", apiLimiter.Wrap(authHandler.OIDCProviders))
+	mux.HandleFunc("GET /api/auth/oidc/{provider}/login", credentialLimiter.Wrap(authHandler.OIDCLogin))
+	mux.HandleFunc("GET /api/auth/oidc/{provider}/callback", credentialLimiter.Wrap(authHandler.OIDCCallback))
+
+	// Auth routes acting on the signed-in user
+	mux.HandleFunc("/api/auth/logout", authMiddleware.RequireWebAuth(authHandler.Logout))
+	mux.HandleFunc("/api/auth/profile", authMiddleware.RequireWebAuth(authHandler.GetProfile))
+	mux.HandleFunc("GET /api/auth/sessions", authMiddleware.RequireWebAuth(authHandler.ListSessions))
+	mux.HandleFunc("DELETE /api/auth/sessions", authMiddleware.RequireWebAuth(authHandler.RevokeSession))
+	mux.HandleFunc("POST /api/auth/verify-email/resend", authMiddleware.RequireWebAuth(authHandler.ResendVerification))
+	mux.HandleFunc("POST /api/auth/password/change", authMiddleware.RequireWebAuth(authHandler.ChangePassword))
+	mux.HandleFunc("GET /api/auth/2fa", authMiddleware.RequireWebAuth(authHandler.TwoFactorStatus))
+	mux.HandleFunc("POST /api/auth/2fa/enroll", authMiddleware.RequireWebAuth(authHandler.EnrollTOTP))
+	mux.HandleFunc("POST /api/auth/2fa/confirm", authMiddleware.RequireWebAuth(authHandler.ConfirmTOTP))
+	mux.HandleFunc("POST /api/auth/2fa/disable", authMiddleware.RequireWebAuth(authHandler.DisableTwoFactor))
+	mux.HandleFunc("POST /api/auth/2fa/recovery-codes", authMiddleware.RequireWebAuth(authHandler.RegenerateRecoveryCodes))
+	mux.HandleFunc("GET /api/auth/tokens", authMiddleware.RequireWebAuth(authHandler.ListAPITokens))
+	mux.HandleFunc("POST /api/auth/tokens", authMiddleware.RequireWebAuth(authHandler.CreateAPIToken))
+	mux.HandleFunc("DELETE /api/auth/tokens", authMiddleware.RequireWebAuth(authHandler.RevokeAPIToken))
+
+	// Workspace routes act on the workspace chosen by X-Workspace-ID or the
+	// workspace cookie
+	mux.HandleFunc("GET /api/workspaces", authMiddleware.RequireWebAuth(authHandler.ListWorkspaces))
+	mux.HandleFunc("POST /api/workspaces", authMiddleware.RequireWebAuth(authHandler.CreateWorkspace))
+	mux.HandleFunc("POST /api/workspaces/switch", authMiddleware.RequireWebAuth(authHandler.SwitchWorkspace))
+	mux.HandleFunc("GET /api/workspaces/members", authMiddleware.RequireWebAuth(authHandler.ListMembers))
+	mux.HandleFunc("PUT /api/workspaces/members", authMiddleware.RequireWebAuth(authHandler.UpdateMember))
+	mux.HandleFunc("DELETE /api/workspaces/members", authMiddleware.RequireWebAuth(authHandler.RemoveMember))
+	mux.HandleFunc("GET /api/workspaces/invitations", authMiddleware.RequireWebAuth(authHandler.ListInvitations))
+	mux.HandleFunc("POST /api/workspaces/invitations", authMiddleware.RequireWebAuth(authHandler.InviteMember))
+	mux.HandleFunc("DELETE /api/workspaces/invitations", authMiddleware.RequireWebAuth(authHandler.RevokeInvitation))
+	mux.HandleFunc("/api/workspaces/invitations/accept", authMiddleware.RequireWebAuth(authHandler.AcceptInvitation))
 
 	// Module routes (protected) - wrap existing mux with auth middleware
 	protectedMux := http.NewServeMux()
@@ -103,25 +174,29 @@ func main() {
 	clientHandlers.SetupRoutes(protectedMux)
 	invoiceHandlers.SetupRoutes(protectedMux)
 
-	// Wrap all non-auth API routes with authentication
-	mux.Handle("/api/", http.StripPrefix("/api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip auth for specific endpoints
-		if r.URL.Path == "/health" {
+	// Set up web routes 
+	webMux := http.NewServeMux()
+	webHandlers.SetupRoutes(webMux)
+
+	// Wrap all non-auth API routes with authentication. Handlers take the
+	// user from the auth context, never from the request.
+	mux.Handle("/api/", apiLimiter.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		// Skip auth for module health checks
+		if strings.HasSuffix(r.URL.Path, "/health") {
 			protectedMux.ServeHTTP(w, r)
 			return
 		}
-		authMiddleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
-			protectedMux.ServeHTTP(w, r)
-		})(w, r)
-	})))
+		// Frontend data endpoints authenticate with the session cookie
+		if _, pattern := protectedMux.Handler(r); pattern == "" {
+			authMiddleware.RequireWebAuth(webMux.ServeHTTP)(w, r)
+			return
+		}
+		authMiddleware.RequireAuth(protectedMux.ServeHTTP)(w, r)
+	}))
 
 	// System routes
 	mux.HandleFunc("/api/health", handleOverallHealth(db, eventBus))
 
-	// Set up web routes 
-	webMux := http.NewServeMux()
-	webHandlers.SetupRoutes(webMux)
-	
 	// Add login/register pages (no auth required)
 	mux.HandleFunc("/login", webHandlers.LoginPage)
 	mux.HandleFunc("/register", webHandlers.RegisterPage)
@@ -194,6 +269,7 @@ func main() {
 	// Wait for interrupt signal
 	<-c
 	log.Println("📤 Shutting down...")
+	stopScheduler()
 
 	// Publish shutdown event
 	shutdownEvent := types.NewEvent("system_shutdown", "main", map[string]any{
@@ -213,6 +289,55 @@ func main() {
 	log.Println("✅ Freelancer app stopped gracefully")
 }
 
+// signingKeyConfig reads the access token signing setup from JWT_ALGORITHM,
+// JWT_KEY_ROTATION and JWT_KEY_OVERLAP
+func signingKeyConfig() (auth.KeyConfig, error) {
+	config := auth.KeyConfig{Algorithm: os.Getenv("JWT_ALGORITHM")}
+
+	if value := os.Getenv("JWT_KEY_ROTATION"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("JWT_KEY_ROTATION: %w", err)
+		}
+		config.RotationInterval = interval
+	}
+	if value := os.Getenv("JWT_KEY_OVERLAP"); value != "" {
+		overlap, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("JWT_KEY_OVERLAP: %w", err)
+		}
+		config.Overlap = overlap
+	}
+	return config, nil
+}
+
+// oidcProviders reads the identity providers named in OIDC_PROVIDERS. Each
+// name is configured by OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET and optionally OIDC_<NAME>_SCOPES.
+func oidcProviders() ([]auth.OIDCProviderConfig, error) {
+	var providers []auth.OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := auth.OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if provider.Issuer == "" {
+			return nil, fmt.Errorf("%sISSUER is required for provider %s", prefix, name)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
 // handleOverallHealth returns overall system health
 func handleOverallHealth(db *database.BadgerDB, eventBus *events.EventBus) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -48,7 +48,8 @@ while [[ $# -gt 0 ]]; do
             echo ""
             echo "Environment Variables:"
             echo "  PORT              Server port (default: 8080)"
-            echo "  JWT_SECRET        JWT secret key (auto-generated if not set)"
+            echo "  JWT_ALGORITHM     Access token signing: HS256, EdDSA or RS256 (default: HS256)"
+            echo "  JWT_SECRET        Legacy secret, verifies tokens issued before stored signing keys"
             echo ""
             exit 0
             ;;
@@ -191,13 +192,8 @@ setup_environment() {
     export PORT="$PORT"
     log_success "Port set to: $PORT"
     
-    # Generate JWT secret if not provided
-    if [ -z "$JWT_SECRET" ]; then
-        log_warning "JWT_SECRET not set. A secret will be auto-generated."
-        log_warning "Set JWT_SECRET environment variable for production use."
-    else
-        log_success "JWT_SECRET is configured"
-    fi
+    # Signing keys are generated and rotated by the app and kept in the database
+    log_success "JWT signing: ${JWT_ALGORITHM:-HS256} (keys stored in ./data)"
     
     if [ "$PROD" = true ]; then
         export GIN_MODE="release"